@@ -0,0 +1,93 @@
+// Package blind hides applicant identities from reviewers in blind-review projects.
+package blind
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"backend/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	phonePattern = regexp.MustCompile(`(?:\+?\d{1,3}[\s.\-]?)?(?:\(\d{3}\)|\d{3})[\s.\-]?\d{3}[\s.\-]?\d{4}\b`)
+	// profile links usually spell out the name, e.g. linkedin.com/in/jane-doe
+	profilePattern = regexp.MustCompile(`(?i)(?:https?://)?(?:www\.)?(?:linkedin\.com|github\.com|instagram\.com|twitter\.com|x\.com)/\S+`)
+)
+
+// Pseudonym is the stable stand-in name for an applicant. It depends on the project too, so the
+// same person can't be followed across projects.
+func Pseudonym(projectID, applicantID primitive.ObjectID) string {
+	sum := sha256.Sum256(append(projectID[:], applicantID[:]...))
+	return fmt.Sprintf("Applicant %X", sum[:3])
+}
+
+// Anonymise rewrites applicant in place for a reviewer who must not learn who they are.
+// The name becomes the pseudonym, contact details and the headshot are dropped, and names, emails,
+// phone numbers and profile links are redacted from the extracted text and free-text answers.
+// The original resume and cover letter files carry the name too, so only their redacted text is kept.
+// Enrichment summaries are redacted the same way.
+func Anonymise(applicant *models.Applicant) {
+	pseudonym := Pseudonym(applicant.ProjectID, applicant.ID)
+	names := []string{applicant.FirstName, applicant.LastName}
+
+	applicant.ResumeText = Redact(applicant.ResumeText, names, pseudonym)
+	applicant.CoverLetterText = Redact(applicant.CoverLetterText, names, pseudonym)
+
+	responses := make([]models.Response, len(applicant.Responses))
+	for i, resp := range applicant.Responses {
+		if answer, ok := resp.Answer.(string); ok {
+			resp.Answer = Redact(answer, names, pseudonym)
+		}
+		responses[i] = resp
+	}
+	applicant.Responses = responses
+
+	enrichments := make(map[string]models.Enrichment, len(applicant.Enrichments))
+	for name, enrichment := range applicant.Enrichments {
+		enrichment.Summary = Redact(enrichment.Summary, names, pseudonym)
+		enrichments[name] = enrichment
+	}
+	if applicant.Enrichments != nil {
+		applicant.Enrichments = enrichments
+	}
+
+	applicant.FirstName, applicant.LastName = pseudonym, ""
+	applicant.Email, applicant.Phone = "", ""
+	applicant.Image, applicant.Resume, applicant.CoverLetter = nil, nil, nil
+	applicant.Anonymised = true
+}
+
+// Redact replaces every email, phone number and profile link in text with a placeholder and
+// every occurrence of names with pseudonym, ignoring case. A full name is replaced as one unit
+// so "Jane Doe" becomes the pseudonym once rather than twice.
+func Redact(text string, names []string, pseudonym string) string {
+	if text == "" {
+		return text
+	}
+
+	text = emailPattern.ReplaceAllString(text, "[email]")
+	text = profilePattern.ReplaceAllString(text, "[link]")
+	text = phonePattern.ReplaceAllString(text, "[phone]")
+
+	var parts []string
+	for _, name := range names {
+		for _, part := range strings.Fields(name) {
+			// initials and stray letters would redact half the alphabet
+			if len([]rune(part)) > 1 {
+				parts = append(parts, regexp.QuoteMeta(part))
+			}
+		}
+	}
+	if len(parts) == 0 {
+		return text
+	}
+
+	// consecutive name parts, in any order, collapse into one pseudonym
+	namePattern := regexp.MustCompile(`(?i)\b(?:` + strings.Join(parts, "|") + `)(?:[\s,]+(?:` + strings.Join(parts, "|") + `))*\b`)
+	return namePattern.ReplaceAllString(text, pseudonym)
+}
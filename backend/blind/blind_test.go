@@ -0,0 +1,108 @@
+package blind
+
+import (
+	"strings"
+	"testing"
+
+	"backend/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestRedact(t *testing.T) {
+	names := []string{"Jane", "Doe"}
+	const p = "Applicant ABC123"
+
+	tests := []struct {
+		name  string
+		text  string
+		names []string
+		want  string
+	}{
+		{"empty", "", names, ""},
+		{"no identifiers", "Led a team of five engineers.", names, "Led a team of five engineers."},
+		{"full name once", "Jane Doe built a compiler.", names, p + " built a compiler."},
+		{"reversed with comma", "Doe, Jane - Resume", names, p + " - Resume"},
+		{"case insensitive", "JANE DOE and jane", names, p + " and " + p},
+		{"not inside other words", "Janet Doering joined", names, "Janet Doering joined"},
+		{"possessive", "Jane's project", names, p + "'s project"},
+		{"email", "Reach me at jane.doe+cv@example.co.uk today", nil, "Reach me at [email] today"},
+		{"phone formats", "Call (555) 123-4567 or +1 555.123.4567", nil, "Call [phone] or [phone]"},
+		{"profile links", "See linkedin.com/in/jane-doe and https://github.com/janedoe.", nil, "See [link] and [link]"},
+		{"initials are not redacted", "J. Doe wrote A grade essays", []string{"J", "Doe"}, "J. " + p + " wrote A grade essays"},
+		{"regex characters in names", "Worked with O'Brien on it", []string{"O'Brien", "(Jr.)"}, "Worked with " + p + " on it"},
+		{"multi-word last name", "Maria de la Cruz presented", []string{"Maria", "de la Cruz"}, p + " presented"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Redact(tt.text, tt.names, p); got != tt.want {
+				t.Errorf("Redact(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPseudonym(t *testing.T) {
+	project, other := primitive.NewObjectID(), primitive.NewObjectID()
+	applicant := primitive.NewObjectID()
+
+	a := Pseudonym(project, applicant)
+	if a != Pseudonym(project, applicant) {
+		t.Error("Pseudonym is not stable")
+	}
+	if a == Pseudonym(other, applicant) {
+		t.Error("Pseudonym is the same across projects")
+	}
+	if !strings.HasPrefix(a, "Applicant ") || len(a) != len("Applicant ")+6 {
+		t.Errorf("Pseudonym = %q", a)
+	}
+}
+
+func TestAnonymise(t *testing.T) {
+	applicant := &models.Applicant{
+		ID:              primitive.NewObjectID(),
+		ProjectID:       primitive.NewObjectID(),
+		FirstName:       "Jane",
+		LastName:        "Doe",
+		Email:           "jane@example.com",
+		Phone:           "555-123-4567",
+		Image:           &models.FileInfo{FileID: "img"},
+		Resume:          &models.FileInfo{FileID: "resume"},
+		CoverLetter:     &models.FileInfo{FileID: "cover"},
+		ResumeText:      "Jane Doe\njane@example.com\nSoftware intern",
+		CoverLetterText: "Dear team, I am Jane.",
+		Responses: []models.Response{
+			{Question: "Why?", Answer: "Because Doe family tradition"},
+			{Question: "Age", Answer: 21},
+		},
+		Enrichments: map[string]models.Enrichment{"llm": {Summary: "Jane Doe is a strong candidate"}},
+	}
+	responses := applicant.Responses
+	pseudonym := Pseudonym(applicant.ProjectID, applicant.ID)
+
+	Anonymise(applicant)
+
+	if applicant.FirstName != pseudonym || applicant.LastName != "" {
+		t.Errorf("name = %q %q, want the pseudonym", applicant.FirstName, applicant.LastName)
+	}
+	if applicant.Email != "" || applicant.Phone != "" {
+		t.Error("contact details kept")
+	}
+	if applicant.Image != nil || applicant.Resume != nil || applicant.CoverLetter != nil {
+		t.Error("original files kept")
+	}
+	if !applicant.Anonymised {
+		t.Error("Anonymised not set")
+	}
+	for _, text := range []string{applicant.ResumeText, applicant.CoverLetterText, applicant.Responses[0].Answer.(string), applicant.Enrichments["llm"].Summary} {
+		if strings.Contains(strings.ToLower(text), "jane") || strings.Contains(strings.ToLower(text), "doe") {
+			t.Errorf("name left in %q", text)
+		}
+	}
+	if applicant.Responses[1].Answer != 21 {
+		t.Errorf("non-text answer changed to %v", applicant.Responses[1].Answer)
+	}
+	if responses[0].Answer != "Because Doe family tradition" {
+		t.Error("Anonymise modified the caller's responses slice")
+	}
+}
@@ -0,0 +1,28 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// apiError is the JSON error body of the REST endpoints. Fields maps request fields to what is
+// wrong with them when validation fails.
+type apiError struct {
+	Error  string            `json:"error"`
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeAPIError(w, status, apiError{Error: message})
+}
+
+func writeValidationError(w http.ResponseWriter, fields map[string]string) {
+	writeAPIError(w, http.StatusBadRequest, apiError{Error: "Validation failed", Fields: fields})
+}
+
+func writeAPIError(w http.ResponseWriter, status int, body apiError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
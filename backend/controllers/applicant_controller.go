@@ -1,33 +1,47 @@
 package controllers
 
 import (
-	"bytes"
 	"context"
-	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"mime"
 	"net/http"
+	"net/mail"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
 	"backend/db"
 	"backend/elo"
+	"backend/events"
+	"backend/middleware"
 	"backend/models"
+	"backend/ranking"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/gridfs"
 	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/go-chi/chi/v5"
 )
 
 type ApplicantController struct {
-	collection *mongo.Collection
+	collection  *mongo.Collection
+	projects    *mongo.Collection
+	comparisons *mongo.Collection
+	snapshots   *mongo.Collection
 }
 
 func NewApplicantController() *ApplicantController {
 	return &ApplicantController{
-		collection: db.GetCollection("applicants"),
+		collection:  db.GetCollection("applicants"),
+		projects:    db.GetCollection("projects"),
+		comparisons: db.GetCollection("comparisons"),
+		snapshots:   db.GetCollection("ranking_snapshots"),
 	}
 }
 
@@ -49,111 +63,625 @@ func abs(x int) int {
 	return x
 }
 
-func resetMatchHistory(ctx context.Context, collection *mongo.Collection) {
-	_, _ = collection.UpdateMany(ctx, bson.M{}, bson.M{"$set": bson.M{"matches_played": []primitive.ObjectID{}}})
-	log.Println("Reset all applicants' match history")
+func resetMatchHistory(ctx context.Context, collection *mongo.Collection, filter bson.M) {
+	_, _ = collection.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"matches_played": []primitive.ObjectID{}}})
+	log.Println("Reset applicants' match history")
+}
+
+// Default and maximum page sizes for listing applicants
+const (
+	defaultApplicantPageSize = 25
+	maxApplicantPageSize     = 100
+)
+
+// Fields applicants can be sorted by; prefix with "-" for descending
+var applicantSortFields = map[string]string{
+	"elo":       "elo",
+	"wins":      "wins",
+	"losses":    "losses",
+	"firstName": "firstName",
+	"lastName":  "lastName",
+	"major":     "major",
+	"year":      "year",
+	"timestamp": "timestamp",
 }
 
+type applicantPage struct {
+	Applicants []models.Applicant `json:"applicants"`
+	Page       int                `json:"page"`
+	PageSize   int                `json:"pageSize"`
+	Total      int64              `json:"total"`
+}
+
+// GetAll lists a project's applicants a page at a time. Query parameters: page (from 1), pageSize,
+// major and year (exact, case-insensitive), status and sort, e.g. sort=-elo (the default) or sort=lastName.
+// Extracted resume and cover letter text is left out of listings.
 func (ac *ApplicantController) GetAll(w http.ResponseWriter, r *http.Request) {
+	projectID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid Project ID")
+		return
+	}
+
+	query := r.URL.Query()
+	invalid := map[string]string{}
+	page, pageSize := 1, defaultApplicantPageSize
+	if v := query.Get("page"); v != "" {
+		if page, err = strconv.Atoi(v); err != nil || page < 1 {
+			invalid["page"] = "must be a positive integer"
+		}
+	}
+	if v := query.Get("pageSize"); v != "" {
+		if pageSize, err = strconv.Atoi(v); err != nil || pageSize < 1 || pageSize > maxApplicantPageSize {
+			invalid["pageSize"] = fmt.Sprintf("must be between 1 and %d", maxApplicantPageSize)
+		}
+	}
+
+	sortParam := query.Get("sort")
+	if sortParam == "" {
+		sortParam = "-elo"
+	}
+	direction := 1
+	if strings.HasPrefix(sortParam, "-") {
+		direction = -1
+	}
+	sortField, ok := applicantSortFields[strings.TrimPrefix(sortParam, "-")]
+	if !ok {
+		invalid["sort"] = "unknown sort field " + strings.TrimPrefix(sortParam, "-")
+	}
+	if status := query.Get("status"); status != "" && !models.ValidApplicantStatus(status) {
+		invalid["status"] = "must be one of active, withdrawn, disqualified or advanced"
+	}
+	if len(invalid) > 0 {
+		writeValidationError(w, invalid)
+		return
+	}
+
+	filter := bson.M{"project_id": projectID}
+	for _, field := range []string{"major", "year"} {
+		if v := strings.TrimSpace(query.Get(field)); v != "" {
+			filter[field] = primitive.Regex{Pattern: "^" + regexp.QuoteMeta(v) + "$", Options: "i"}
+		}
+	}
+	switch status := query.Get("status"); {
+	case status == models.ApplicantStatusActive:
+		activeApplicantFilter(filter)
+	case status != "":
+		filter["status"] = status
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	cursor, err := ac.collection.Find(ctx, bson.M{})
+	total, err := ac.collection.CountDocuments(ctx, filter)
 	if err != nil {
-		http.Error(w, "Failed to fetch applicants", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, "Failed to fetch applicants")
+		log.Println("MongoDB Count applicants error: ", err)
+		return
+	}
+
+	opts := options.Find().
+		// _id breaks ties so pages never overlap
+		SetSort(bson.D{{Key: sortField, Value: direction}, {Key: "_id", Value: 1}}).
+		SetSkip(int64((page - 1) * pageSize)).
+		SetLimit(int64(pageSize)).
+		SetProjection(bson.M{"resumeText": 0, "coverLetterText": 0})
+	cursor, err := ac.collection.Find(ctx, filter, opts)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to fetch applicants")
 		log.Println("MongoDB Find applicants error: ", err)
 		return
 	}
 	defer cursor.Close(ctx)
 
-	var applicants []models.Applicant
+	applicants := []models.Applicant{}
 	if err = cursor.All(ctx, &applicants); err != nil {
-		http.Error(w, "Error decoding applicants", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, "Error decoding applicants")
 		log.Println("Cursor decode error:", err)
 		return
 	}
+	ac.prepareApplicants(ctx, r, applicantPtrs(applicants)...)
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(applicants)
+	json.NewEncoder(w).Encode(applicantPage{
+		Applicants: applicants,
+		Page:       page,
+		PageSize:   pageSize,
+		Total:      total,
+	})
+}
+
+// findProjectApplicant loads the applicant in the URL, scoped to the project in the URL when there is one.
+// It writes the error response itself and returns nil when the applicant can't be loaded.
+func (ac *ApplicantController) findProjectApplicant(ctx context.Context, w http.ResponseWriter, r *http.Request) *models.Applicant {
+	applicantIDStr := chi.URLParam(r, "applicantId")
+	if applicantIDStr == "" {
+		// the original route took the id as a query parameter
+		applicantIDStr = r.URL.Query().Get("id")
+	}
+	if applicantIDStr == "" {
+		writeError(w, http.StatusBadRequest, "Applicant ID required")
+		return nil
+	}
+	applicantID, err := primitive.ObjectIDFromHex(applicantIDStr)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid Applicant ID")
+		return nil
+	}
+
+	filter := bson.M{"_id": applicantID}
+	if projectIDStr := chi.URLParam(r, "id"); projectIDStr != "" {
+		projectID, err := primitive.ObjectIDFromHex(projectIDStr)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid Project ID")
+			return nil
+		}
+		filter["project_id"] = projectID
+	}
+
+	var applicant models.Applicant
+	if err := ac.collection.FindOne(ctx, filter).Decode(&applicant); err != nil {
+		if err == mongo.ErrNoDocuments {
+			writeError(w, http.StatusNotFound, "Applicant not found")
+			return nil
+		}
+		writeError(w, http.StatusInternalServerError, "Failed to fetch applicant")
+		log.Println("MongoDB Find applicant error:", err)
+		return nil
+	}
+	return &applicant
 }
 
 func (ac *ApplicantController) GetById(w http.ResponseWriter, r *http.Request) {
-	// log.Println("Fetching applicant by ID")
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	// Get ID from URL path parameter
-	applicantIDStr := r.URL.Query().Get("id")
-	// log.Println("Applicant ID from path:", applicantIDStr)
+	applicant := ac.findProjectApplicant(ctx, w, r)
+	if applicant == nil {
+		return
+	}
+
+	ac.prepareApplicants(ctx, r, applicant)
 
-	if applicantIDStr == "" {
-		http.Error(w, "Applicant ID required", http.StatusBadRequest)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(applicant)
+}
+
+// applicantRequest is the JSON body of Create and Update. Update only changes the fields that are present.
+type applicantRequest struct {
+	FirstName *string            `json:"firstName"`
+	LastName  *string            `json:"lastName"`
+	Major     *string            `json:"major"`
+	Year      *string            `json:"year"`
+	Email     *string            `json:"email"`
+	Phone     *string            `json:"phone"`
+	Timestamp string             `json:"timestamp"`
+	Responses *[]models.Response `json:"responses"`
+	// files to detach on Update, any of resume, coverLetter and image
+	RemoveFiles []string `json:"removeFiles"`
+}
+
+// readApplicantRequest parses a JSON or multipart/form-data body. Multipart text parts fill the
+// applicant fields like a form submission, and file parts named resume, coverLetter or image are
+// streamed into storage. On error the response has been written and stored files discarded.
+func readApplicantRequest(ctx context.Context, w http.ResponseWriter, r *http.Request) (*applicantRequest, *intakeSubmission, bool) {
+	maxFile := uploadLimit("MAX_UPLOAD_FILE_BYTES", defaultMaxFileBytes)
+	maxRequest := uploadLimit("MAX_UPLOAD_REQUEST_BYTES", defaultMaxRequestBytes)
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequest)
+
+	sub := newIntakeSubmission("api", "", "")
+	request := &applicantRequest{}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid JSON input: "+err.Error())
+			return nil, nil, false
+		}
+		return request, sub, true
+	}
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid multipart body: "+err.Error())
+		return nil, nil, false
+	}
+	if status, err := readMultipartSubmission(ctx, reader, sub, maxFile); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			status, err = http.StatusRequestEntityTooLarge, fmt.Errorf("request exceeds %d bytes", maxRequest)
+		}
+		discardStoredFiles(ctx, sub)
+		writeError(w, status, err.Error())
+		return nil, nil, false
+	}
+
+	for field, value := range sub.Fields {
+		value := value
+		switch field {
+		case "firstName":
+			request.FirstName = &value
+		case "lastName":
+			request.LastName = &value
+		case "major":
+			request.Major = &value
+		case "year":
+			request.Year = &value
+		case "email":
+			request.Email = &value
+		case "phone":
+			request.Phone = &value
+		}
+	}
+	request.Timestamp = sub.Timestamp
+	if len(sub.Responses) > 0 {
+		request.Responses = &sub.Responses
+	}
+	return request, sub, true
+}
+
+// validate checks the fields that are present; creating additionally requires a name
+func (req *applicantRequest) validate(creating bool) map[string]string {
+	invalid := map[string]string{}
+
+	for field, value := range map[string]*string{
+		"firstName": req.FirstName,
+		"lastName":  req.LastName,
+		"major":     req.Major,
+		"year":      req.Year,
+	} {
+		switch {
+		case value == nil && creating && (field == "firstName" || field == "lastName"):
+			invalid[field] = "is required"
+		case value == nil:
+		case strings.TrimSpace(*value) == "" && (field == "firstName" || field == "lastName"):
+			invalid[field] = "must not be empty"
+		case len(*value) > 200:
+			invalid[field] = "must be at most 200 characters"
+		}
+	}
+
+	if req.Email != nil && *req.Email != "" {
+		if address, err := mail.ParseAddress(*req.Email); err != nil || address.Address != strings.TrimSpace(*req.Email) {
+			invalid["email"] = "is not a valid email address"
+		}
+	}
+	if req.Phone != nil && *req.Phone != "" {
+		if digits := len(normalisePhone(*req.Phone)); digits < 7 || digits > 15 {
+			invalid["phone"] = "is not a valid phone number"
+		}
+	}
+	if req.Timestamp != "" {
+		if _, err := time.Parse(time.RFC3339, req.Timestamp); err != nil {
+			invalid["timestamp"] = "must be an RFC 3339 time"
+		}
+	}
+	for _, field := range req.RemoveFiles {
+		if creating {
+			invalid["removeFiles"] = "only applies to updates"
+		} else if !isFileField(field) {
+			invalid["removeFiles"] = "unknown file " + field
+		}
+	}
+	return invalid
+}
+
+// Create adds an applicant to the project by hand, as JSON or multipart/form-data with files.
+// It goes through the same pipeline as form submissions, so the project's duplicate policy
+// applies and files are scanned, indexed and previewed.
+func (ac *ApplicantController) Create(w http.ResponseWriter, r *http.Request) {
+	projectID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid Project ID")
 		return
 	}
 
-	applicantID, err := primitive.ObjectIDFromHex(applicantIDStr)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var project models.Project
+	if err := ac.projects.FindOne(ctx, bson.M{"_id": projectID}).Decode(&project); err != nil {
+		if err == mongo.ErrNoDocuments {
+			writeError(w, http.StatusNotFound, "Project not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "Failed to fetch project")
+		log.Println("MongoDB Find project error:", err)
+		return
+	}
+
+	request, sub, ok := readApplicantRequest(ctx, w, r)
+	if !ok {
+		return
+	}
+	if invalid := request.validate(true); len(invalid) > 0 {
+		discardStoredFiles(ctx, sub)
+		writeValidationError(w, invalid)
+		return
+	}
+
+	for field, value := range map[string]*string{
+		"firstName": request.FirstName,
+		"lastName":  request.LastName,
+		"major":     request.Major,
+		"year":      request.Year,
+		"email":     request.Email,
+		"phone":     request.Phone,
+	} {
+		if value != nil {
+			sub.Fields[field] = strings.TrimSpace(*value)
+		}
+	}
+	if request.Responses != nil {
+		sub.Responses = *request.Responses
+	}
+	sub.Timestamp = request.Timestamp
+	if sub.Timestamp == "" {
+		sub.Timestamp = time.Now().Format(time.RFC3339)
+	}
+
+	result, err := ingest(ctx, ac.collection, &project, sub)
 	if err != nil {
-		// log.Println("Error converting applicant ID to ObjectID:", err)
-		http.Error(w, "Invalid Applicant ID", http.StatusBadRequest)
+		var duplicate *errDuplicateApplication
+		if errors.As(err, &duplicate) {
+			writeError(w, http.StatusConflict, err.Error())
+			return
+		}
+		var unsupported *errUnsupportedFileType
+		if errors.As(err, &unsupported) {
+			writeError(w, http.StatusUnsupportedMediaType, err.Error())
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "Failed to create applicant")
+		log.Println("Error creating applicant:", err)
 		return
 	}
 
-	var applicant models.Applicant
-	err = ac.collection.FindOne(ctx, bson.M{"_id": applicantID}).Decode(&applicant)
+	status := http.StatusCreated
+	if result.Replaced || result.Ignored {
+		status = http.StatusOK
+	}
+	ac.prepareApplicants(ctx, r, result.Applicant)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(result.Applicant)
+}
+
+// Update changes the fields present in a JSON or multipart/form-data body. File parts replace the
+// applicant's resume, coverLetter or image; removeFiles detaches them. Ratings and comparison
+// history can't be edited here.
+func (ac *ApplicantController) Update(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	applicant := ac.findProjectApplicant(ctx, w, r)
+	if applicant == nil {
+		return
+	}
+
+	request, sub, ok := readApplicantRequest(ctx, w, r)
+	if !ok {
+		return
+	}
+	invalid := request.validate(false)
+	for _, field := range request.RemoveFiles {
+		if _, ok := sub.Files[field]; ok {
+			invalid["removeFiles"] = field + " is both uploaded and removed"
+		}
+	}
+	if len(invalid) > 0 {
+		discardStoredFiles(ctx, sub)
+		writeValidationError(w, invalid)
+		return
+	}
+
+	set, unset := bson.M{}, bson.M{}
+	for field, value := range map[string]*string{
+		"firstName": request.FirstName,
+		"lastName":  request.LastName,
+		"major":     request.Major,
+		"year":      request.Year,
+		"email":     request.Email,
+		"phone":     request.Phone,
+	} {
+		if value != nil {
+			set[field] = strings.TrimSpace(*value)
+		}
+	}
+	if request.Timestamp != "" {
+		set["timestamp"] = request.Timestamp
+	}
+	if request.Responses != nil {
+		set["responses"] = *request.Responses
+	}
+
+	current := map[string]*models.FileInfo{
+		"resume":      applicant.Resume,
+		"coverLetter": applicant.CoverLetter,
+		"image":       applicant.Image,
+	}
+	textFields := map[string]string{"resume": "resumeText", "coverLetter": "coverLetterText"}
+	var replaced []*models.FileInfo
+
+	for field, file := range sub.Files {
+		fileInfo := file.Stored
+		scanStoredFile(ctx, fileInfo)
+		set[field] = fileInfo
+		if textField, ok := textFields[field]; ok {
+			set[textField] = extractFileText(ctx, fileInfo)
+		}
+		if current[field] != nil {
+			replaced = append(replaced, current[field])
+		}
+		if due := previewsDue(fileInfo); due != nil {
+			set["previewsDueAt"] = due
+		}
+	}
+	for _, field := range request.RemoveFiles {
+		unset[field] = ""
+		if textField, ok := textFields[field]; ok {
+			unset[textField] = ""
+		}
+		if current[field] != nil {
+			replaced = append(replaced, current[field])
+		}
+	}
+
+	if len(set) == 0 && len(unset) == 0 {
+		writeError(w, http.StatusBadRequest, "No fields to update")
+		return
+	}
+
+	update := bson.M{}
+	if len(set) > 0 {
+		update["$set"] = set
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	var updated models.Applicant
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := ac.collection.FindOneAndUpdate(ctx, bson.M{"_id": applicant.ID}, update, opts).Decode(&updated)
 	if err != nil {
+		discardStoredFiles(ctx, sub)
 		if err == mongo.ErrNoDocuments {
-			// log.Println("Applicant not found in database")
-			http.Error(w, "Applicant not found", http.StatusNotFound)
+			writeError(w, http.StatusNotFound, "Applicant not found")
 			return
 		}
-		// log.Println("Failed to fetch applicant from database:", err)
-		http.Error(w, "Failed to fetch applicant", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, "Failed to update applicant")
+		log.Println("MongoDB Update applicant error:", err)
 		return
 	}
 
+	for _, file := range replaced {
+		deleteStoredFile(ctx, file)
+	}
+	if set["previewsDueAt"] != nil {
+		wakePreviews()
+	}
+
+	ac.prepareApplicants(ctx, r, &updated)
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(applicant)
-	// log.Println("Applicant fetched successfully")
+	json.NewEncoder(w).Encode(updated)
 }
 
-func (ac *ApplicantController) Create(w http.ResponseWriter, r *http.Request) {
-	// TODO: Implement creating new applicant
-}
+// Delete removes an applicant and their files, and drops them from other applicants' comparison
+// history. Their comparisons are kept, marked applicantDeleted, since opponents keep the ratings
+// those games gave them and a replay has to account for every game an applicant played.
+func (ac *ApplicantController) Delete(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 
-func (ac *ApplicantController) Update(w http.ResponseWriter, r *http.Request) {
-	// TODO: Implement updating applicant
+	applicant := ac.findProjectApplicant(ctx, w, r)
+	if applicant == nil {
+		return
+	}
+
+	if _, err := ac.collection.DeleteOne(ctx, bson.M{"_id": applicant.ID}); err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to delete applicant")
+		log.Println("MongoDB Delete applicant error:", err)
+		return
+	}
+
+	_, err := ac.collection.UpdateMany(ctx,
+		bson.M{"project_id": applicant.ProjectID},
+		bson.M{"$pull": bson.M{"matches_played": applicant.ID, "possibleDuplicates": applicant.ID}})
+	if err != nil {
+		log.Println("MongoDB pull deleted applicant error:", err)
+	}
+	_, err = ac.comparisons.UpdateMany(ctx,
+		bson.M{"project_id": applicant.ProjectID, "$or": []bson.M{{"winnerId": applicant.ID}, {"loserId": applicant.ID}}},
+		bson.M{"$set": bson.M{"applicantDeleted": true}})
+	if err != nil {
+		log.Println("MongoDB mark deleted applicant's comparisons error:", err)
+	}
+	publishRankingChanged(applicant.ProjectID, applicant.Stage)
+
+	for _, file := range []*models.FileInfo{applicant.Resume, applicant.CoverLetter, applicant.Image} {
+		if file != nil {
+			deleteStoredFile(ctx, file)
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
 }
 
 func (ac *ApplicantController) GetTwoForComparison(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	opts := options.Find().SetSort(bson.D{{Key: "elo", Value: -1}})
-	cursor, err := ac.collection.Find(ctx, bson.M{}, opts)
+	// pairs come from the stage each project is reviewing, or only project_id's when given
+	var project *models.Project
+	if projectIDStr := r.URL.Query().Get("project_id"); projectIDStr != "" {
+		if project = ac.loadProject(ctx, w, projectIDStr); project == nil {
+			return
+		}
+		if project.Closed {
+			http.Error(w, "Project is closed for review", http.StatusConflict)
+			return
+		}
+	}
+	filter, err := ac.currentStageFilter(ctx, activeApplicantFilter(bson.M{}), project)
 	if err != nil {
+		http.Error(w, "Failed to fetch applicants", http.StatusInternalServerError)
+		log.Println("MongoDB Find projects error:", err)
+		return
+	}
+
+	applicant1, applicant2, err := ac.pickPair(ctx, filter)
+	switch {
+	case err == errNotEnoughApplicants:
+		http.Error(w, "Not enough applicants for comparison", http.StatusInternalServerError)
+		return
+	case err == errMatchHistoryReset:
+		http.Error(w, "All applicants have already played, match history reset", http.StatusConflict)
+		return
+	case err != nil:
 		http.Error(w, "Failed to fetch applicants", http.StatusInternalServerError)
 		log.Println("MongoDB Find applicants error:", err)
 		return
 	}
+
+	// Files are fetched separately from the file endpoint
+	ac.prepareApplicants(ctx, r, &applicant1, &applicant2)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode([]models.Applicant{applicant1, applicant2})
+}
+
+var (
+	errNotEnoughApplicants = errors.New("not enough applicants for comparison")
+	errMatchHistoryReset   = errors.New("all applicants have already played, match history reset")
+)
+
+// pickPair picks the two applicants matching filter who are closest in rating and haven't met yet,
+// and records that they have. When every pair has met, match history is reset and errMatchHistoryReset returned.
+func (ac *ApplicantController) pickPair(ctx context.Context, filter bson.M) (models.Applicant, models.Applicant, error) {
+	var applicant1, applicant2 models.Applicant
+
+	opts := options.Find().SetSort(bson.D{{Key: "elo", Value: -1}})
+	cursor, err := ac.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return applicant1, applicant2, err
+	}
 	defer cursor.Close(ctx)
 
 	var applicants []models.Applicant
 	if err = cursor.All(ctx, &applicants); err != nil {
-		http.Error(w, "Error decoding applicants", http.StatusInternalServerError)
-		log.Println("Cursor decode error:", err)
-		return
+		return applicant1, applicant2, err
 	}
 
 	if len(applicants) < 2 {
-		http.Error(w, "Not enough applicants for comparison", http.StatusInternalServerError)
-		return
+		return applicant1, applicant2, errNotEnoughApplicants
 	}
 
-	var applicant1, applicant2 models.Applicant
 	minEloDiff := int(^uint(0) >> 1)
 	for i := 0; i < len(applicants) - 1; i++ {
 		for j := i + 1; j < len(applicants); j++ {
-			if contains(applicants[i].MatchesPlayed, applicants[j].ID) {
+			if applicants[i].ProjectID != applicants[j].ProjectID || contains(applicants[i].MatchesPlayed, applicants[j].ID) {
 				continue
 			}
 
@@ -167,35 +695,17 @@ func (ac *ApplicantController) GetTwoForComparison(w http.ResponseWriter, r *htt
 	}
 
 	if applicant1.ID.IsZero() || applicant2.ID.IsZero() {
-		resetMatchHistory(ctx, ac.collection)
-		http.Error(w, "All applicants have already played, match history reset", http.StatusConflict)
-		return
+		resetMatchHistory(ctx, ac.collection, filter)
+		return applicant1, applicant2, errMatchHistoryReset
 	}
 
-	// Append file data to Applicant response
-	bucket, err := gridfs.NewBucket(db.Client.Database("akpsi-ucsb"))
-	if err != nil {
-		http.Error(w, "Error creating GridFS bucket: "+err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	applicant1.Image = fetchFile(bucket, applicant1.Image)
-	applicant1.CoverLetter = fetchFile(bucket, applicant1.CoverLetter)
-	applicant1.Resume = fetchFile(bucket, applicant1.Resume)
-
-	applicant2.Image = fetchFile(bucket, applicant2.Image)
-	applicant2.CoverLetter = fetchFile(bucket, applicant2.CoverLetter)
-	applicant2.Resume = fetchFile(bucket, applicant2.Resume)
-
-
 	applicant1.MatchesPlayed = append(applicant1.MatchesPlayed, applicant2.ID)
 	applicant2.MatchesPlayed = append(applicant2.MatchesPlayed, applicant1.ID)
 
 	_, _ = ac.collection.UpdateOne(ctx, bson.M{"_id": applicant1.ID}, bson.M{"$set": bson.M{"matches_played" : applicant1.MatchesPlayed}})
 	_, _ = ac.collection.UpdateOne(ctx, bson.M{"_id": applicant2.ID}, bson.M{"$set": bson.M{"matches_played" : applicant2.MatchesPlayed}})
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode([]models.Applicant{applicant1, applicant2})
+	return applicant1, applicant2, nil
 }
 
 func (ac *ApplicantController) UpdateElo(w http.ResponseWriter, r *http.Request) {
@@ -226,14 +736,62 @@ func (ac *ApplicantController) UpdateElo(w http.ResponseWriter, r *http.Request)
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	if _, err := ac.recordVote(ctx, winnerID, loserID, middleware.UserID(r.Context())); err != nil {
+		if verr, ok := err.(*voteError); ok {
+			http.Error(w, verr.message, verr.status)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+    w.WriteHeader(http.StatusOK)
+}
+
+// voteError is a vote refused for the reason in message, answered with status
+type voteError struct {
+	status  int
+	message string
+}
+
+func (e *voteError) Error() string {
+	return e.message
+}
+
+// recordVote applies one vote to the two applicants' ratings, logs it in the comparisons collection
+// and tells listeners. Votes between applicants who can't be compared come back as a *voteError.
+func (ac *ApplicantController) recordVote(ctx context.Context, winnerID, loserID primitive.ObjectID, reviewerID string) (*models.Comparison, error) {
 	var winner, loser models.Applicant
 	if err := ac.collection.FindOne(ctx, bson.M{"_id": winnerID}).Decode(&winner); err != nil {
-		http.Error(w, "Winner not found", http.StatusNotFound)
-		return
+		return nil, &voteError{http.StatusNotFound, "Winner not found"}
 	}
 	if err := ac.collection.FindOne(ctx, bson.M{"_id": loserID}).Decode(&loser); err != nil {
-		http.Error(w, "Loser not found", http.StatusNotFound)
-		return
+		return nil, &voteError{http.StatusNotFound, "Loser not found"}
+	}
+	if !winner.IsActive() || !loser.IsActive() {
+		return nil, &voteError{http.StatusConflict, "Only active applicants can be compared"}
+	}
+	if winner.ProjectID != loser.ProjectID || winner.Stage != loser.Stage {
+		return nil, &voteError{http.StatusConflict, "Applicants are not in the same project stage"}
+	}
+	var project models.Project
+	if err := ac.projects.FindOne(ctx, bson.M{"_id": winner.ProjectID}).Decode(&project); err != nil {
+		return nil, &voteError{http.StatusNotFound, "Project not found"}
+	}
+	if project.Closed {
+		return nil, &voteError{http.StatusConflict, "Project is closed for review"}
+	}
+
+	comparison := models.Comparison{
+		ID:              primitive.NewObjectID(),
+		ProjectID:       winner.ProjectID,
+		WinnerID:        winnerID,
+		LoserID:         loserID,
+		ReviewerID:      reviewerID,
+		Stage:           winner.Stage,
+		WinnerEloBefore: winner.Elo,
+		LoserEloBefore:  loser.Elo,
+		CreatedAt:       time.Now(),
 	}
 
 	winnerElo, loserElo := elo.CalculateElo(winner.Elo, loser.Elo, true)
@@ -248,69 +806,274 @@ func (ac *ApplicantController) UpdateElo(w http.ResponseWriter, r *http.Request)
 	updateLoser := bson.M{"$set": bson.M{"elo": loser.Elo}, "$inc": bson.M{"losses": 1}}
 	
 	if _, err := ac.collection.UpdateOne(ctx, bson.M{"_id": winnerID}, updateWinner); err != nil {
-		http.Error(w, "Failed to update winner", http.StatusInternalServerError)
-		return
+		return nil, fmt.Errorf("Failed to update winner")
 	}
 	if _, err := ac.collection.UpdateOne(ctx, bson.M{"_id": loserID}, updateLoser); err != nil {
-		http.Error(w, "Failed to update loser", http.StatusInternalServerError)
-		return
+		return nil, fmt.Errorf("Failed to update loser")
 	}	
 
-    w.WriteHeader(http.StatusOK)
-}
-
-// Helper function for file fetching
-func fetchFile(bucket *gridfs.Bucket, fileInfo *models.FileInfo) *models.FileInfo {
-	if fileInfo != nil {
-		fileID, err := primitive.ObjectIDFromHex(fileInfo.FileID)
-		if err != nil {
-			return nil
-		}
+	comparison.WinnerEloAfter, comparison.LoserEloAfter = winner.Elo, loser.Elo
+	if _, err := ac.comparisons.InsertOne(ctx, comparison); err != nil {
+		log.Println("MongoDB Insert comparison error:", err)
+	}
+	publish(events.VoteRecorded, comparison.ProjectID, comparison)
+	if ac.voteReordered(ctx, comparison) {
+		publishRankingChanged(comparison.ProjectID, comparison.Stage)
+	}
+	ac.autoClose(ctx, &project)
 
-		var buf bytes.Buffer
-		_, err = bucket.DownloadToStream(fileID, &buf)
-		if err != nil {
-			return nil
-		}
+	return &comparison, nil
+}
 
-		fileInfo.Data = base64.StdEncoding.EncodeToString(buf.Bytes())
+// voteReordered reports whether a vote moved its winner or loser past, onto or off anyone else's
+// rating, or past each other, changing the order of the stage's ranking. Errors count as a change.
+func (ac *ApplicantController) voteReordered(ctx context.Context, c models.Comparison) bool {
+	if c.WinnerEloBefore <= c.LoserEloBefore {
+		return true
 	}
-	return fileInfo
+	var passed []bson.M
+	if c.WinnerEloAfter != c.WinnerEloBefore {
+		passed = append(passed, bson.M{"elo": bson.M{"$gte": c.WinnerEloBefore, "$lte": c.WinnerEloAfter}})
+	}
+	if c.LoserEloAfter != c.LoserEloBefore {
+		passed = append(passed, bson.M{"elo": bson.M{"$gte": c.LoserEloAfter, "$lte": c.LoserEloBefore}})
+	}
+	if len(passed) == 0 {
+		return false
+	}
+	filter := activeApplicantFilter(bson.M{
+		"project_id": c.ProjectID,
+		"stage":      stageFilter(c.Stage),
+		"_id":        bson.M{"$nin": []primitive.ObjectID{c.WinnerID, c.LoserID}},
+		"$or":        passed,
+	})
+	count, err := ac.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		log.Println("MongoDB Count applicants error:", err)
+		return true
+	}
+	return count > 0
 }
 
+// GetRankings ranks a project's applicants in its current stage, or in an earlier one with ?stage=.
+// With ?at= it returns the last ranking snapshot taken at or before that time instead.
+// The project comes from the URL or, on the original route, the project_id query parameter.
 func (ac *ApplicantController) GetRankings(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
-	
-	projectIDStr := r.URL.Query().Get("project_id")
+
+	projectIDStr := chi.URLParam(r, "id")
+	if projectIDStr == "" {
+		projectIDStr = r.URL.Query().Get("project_id")
+	}
 	if projectIDStr == "" {
 		http.Error(w, "Project ID required", http.StatusBadRequest)
 		return
 	}
 
-	projectID, err := primitive.ObjectIDFromHex(projectIDStr)
+	project := ac.loadProject(ctx, w, projectIDStr)
+	if project == nil {
+		return
+	}
+	if at := r.URL.Query().Get("at"); at != "" {
+		ac.snapshotRankings(ctx, w, r, project, at)
+		return
+	}
+	stage, err := stageParam(r, project)
 	if err != nil {
-		http.Error(w, "Invalid Project ID", http.StatusBadRequest)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	log.Println("Project ID: ", projectID)
-	// Find all applicants for this project, sorted by Elo
-	opts := options.Find().SetSort(bson.D{{Key: "elo", Value: -1}})
-	cursor, err := ac.collection.Find(ctx, bson.M{"project_id": projectID}, opts)
+	var ranked, inactive []models.Applicant
+	if stage < project.CurrentStage {
+		ranked, inactive, err = ac.closedStageRankings(ctx, project.ID, stage)
+	} else {
+		var applicants []models.Applicant
+		applicants, err = ac.stageApplicants(ctx, project)
+		for _, applicant := range applicants {
+			if applicant.IsActive() {
+				ranked = append(ranked, applicant)
+			} else {
+				inactive = append(inactive, applicant)
+			}
+		}
+	}
 	if err != nil {
 		http.Error(w, "Failed to fetch rankings", http.StatusInternalServerError)
+		log.Println("MongoDB Find rankings error:", err)
 		return
 	}
+	ac.prepareApplicants(ctx, r, applicantPtrs(ranked)...)
+	ac.prepareApplicants(ctx, r, applicantPtrs(inactive)...)
+
+	// applicants who left the pool are listed apart from the ranking, still ordered by rating
+	response := struct {
+		Stage    int               `json:"stage"`
+		MinGames int               `json:"minGames"`
+		Rankings []rankedApplicant `json:"rankings"`
+		Inactive []rankedApplicant `json:"inactive"`
+	}{
+		Stage:    stage,
+		MinGames: ranking.MinGames,
+		Rankings: rankedApplicants(ranking.Rank(ranked), true),
+		Inactive: rankedApplicants(ranking.Rank(inactive), false),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// stageApplicants returns everyone in the project's current stage, best rated first
+func (ac *ApplicantController) stageApplicants(ctx context.Context, project *models.Project) ([]models.Applicant, error) {
+	filter, _ := ac.currentStageFilter(ctx, bson.M{}, project)
+	opts := options.Find().SetSort(bson.D{{Key: "elo", Value: -1}, {Key: "wins", Value: -1}})
+	cursor, err := ac.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
 	defer cursor.Close(ctx)
 
-	var rankings []models.Applicant
-	if err = cursor.All(ctx, &rankings); err != nil {
-		http.Error(w, "Error decoding rankings", http.StatusInternalServerError)
+	var applicants []models.Applicant
+	if err := cursor.All(ctx, &applicants); err != nil {
+		return nil, err
+	}
+	return applicants, nil
+}
+
+// Merge folds the applicant given as duplicateId into the applicant in the URL and deletes the duplicate.
+// Only project owners can merge, and only two applicants in the same stage. Other applicants' comparison
+// history and the comparison log are repointed at the merged record; games the two played against each
+// other are taken off its record and out of the log. All of it is written in one transaction.
+func (ac *ApplicantController) Merge(w http.ResponseWriter, r *http.Request) {
+	primaryID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid Applicant ID", http.StatusBadRequest)
+		return
+	}
+
+	var request struct {
+		DuplicateID string `json:"duplicateId"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request payload: %v", err), http.StatusBadRequest)
+		return
+	}
+	duplicateID, err := primitive.ObjectIDFromHex(request.DuplicateID)
+	if err != nil {
+		http.Error(w, "Invalid Duplicate ID format", http.StatusBadRequest)
+		return
+	}
+	if duplicateID == primaryID {
+		http.Error(w, "Cannot merge an applicant with itself", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var primary, duplicate models.Applicant
+	if err := ac.collection.FindOne(ctx, bson.M{"_id": primaryID}).Decode(&primary); err != nil {
+		http.Error(w, "Applicant not found", http.StatusNotFound)
+		return
+	}
+	if err := ac.collection.FindOne(ctx, bson.M{"_id": duplicateID}).Decode(&duplicate); err != nil {
+		http.Error(w, "Duplicate applicant not found", http.StatusNotFound)
+		return
+	}
+	if primary.ProjectID != duplicate.ProjectID {
+		http.Error(w, "Applicants belong to different projects", http.StatusBadRequest)
+		return
+	}
+
+	project := ac.loadProject(ctx, w, primary.ProjectID.Hex())
+	if project == nil {
+		return
+	}
+	if len(project.OwnerIDs) > 0 && !project.IsOwner(middleware.UserID(r.Context())) {
+		http.Error(w, "Only project owners can merge applicants", http.StatusForbidden)
+		return
+	}
+	// ratings from different stages don't add up
+	if primary.Stage != duplicate.Stage {
+		http.Error(w, "Applicants are in different stages", http.StatusConflict)
+		return
+	}
+
+	// a game between the two is one win and one loss for the same person once they're merged
+	between := func() bson.M {
+		return bson.M{"project_id": primary.ProjectID, "$or": []bson.M{
+			{"winnerId": primaryID, "loserId": duplicateID},
+			{"winnerId": duplicateID, "loserId": primaryID},
+		}}
+	}
+	filter := between()
+	filter["stage"] = stageFilter(primary.Stage)
+	played, err := ac.comparisons.CountDocuments(ctx, filter)
+	if err != nil {
+		http.Error(w, "Failed to fetch comparison history", http.StatusInternalServerError)
+		log.Println("MongoDB Count comparisons error:", err)
 		return
 	}
 
+	merged, superseded := mergeApplicants(&primary, &duplicate)
+	merged.Wins = max(merged.Wins-int(played), 0)
+	merged.Losses = max(merged.Losses-int(played), 0)
+
+	session, err := ac.collection.Database().Client().StartSession()
+	if err != nil {
+		http.Error(w, "Failed to merge applicants", http.StatusInternalServerError)
+		log.Println("MongoDB start session error:", err)
+		return
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		if _, err := ac.collection.ReplaceOne(sc, bson.M{"_id": primaryID}, merged); err != nil {
+			return nil, err
+		}
+		if _, err := ac.comparisons.DeleteMany(sc, between()); err != nil {
+			return nil, err
+		}
+		// the merged record now owns the duplicate's games, so the comparison log must say so or a later
+		// replay finds the merged wins and losses unaccounted for
+		for _, side := range []string{"winnerId", "loserId"} {
+			_, err := ac.comparisons.UpdateMany(sc,
+				bson.M{"project_id": primary.ProjectID, side: duplicateID},
+				bson.M{"$set": bson.M{side: primaryID}})
+			if err != nil {
+				return nil, err
+			}
+		}
+		if _, err := ac.collection.DeleteOne(sc, bson.M{"_id": duplicateID}); err != nil {
+			return nil, err
+		}
+
+		// anyone who was compared against the duplicate has now been compared against the merged applicant
+		_, err := ac.collection.UpdateMany(sc,
+			bson.M{"matches_played": duplicateID, "_id": bson.M{"$ne": primaryID}},
+			bson.M{"$addToSet": bson.M{"matches_played": primaryID}})
+		if err != nil {
+			return nil, err
+		}
+		_, err = ac.collection.UpdateMany(sc,
+			bson.M{"project_id": primary.ProjectID},
+			bson.M{"$pull": bson.M{"matches_played": duplicateID, "possibleDuplicates": duplicateID}})
+		return nil, err
+	})
+	if err != nil {
+		http.Error(w, "Failed to merge applicants", http.StatusInternalServerError)
+		log.Println("MongoDB merge applicants error:", err)
+		return
+	}
+	publishRankingChanged(primary.ProjectID, primary.Stage)
+
+	for _, file := range superseded {
+		deleteStoredFile(ctx, file)
+	}
+
+	ac.prepareApplicants(ctx, r, merged)
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(rankings)
+	json.NewEncoder(w).Encode(merged)
 }
-
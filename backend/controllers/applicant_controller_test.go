@@ -0,0 +1,547 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/events"
+	"backend/middleware"
+	"backend/models"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// withURLParams attaches chi route parameters to r
+func withURLParams(r *http.Request, params map[string]string) *http.Request {
+	rctx := chi.NewRouteContext()
+	for key, value := range params {
+		rctx.URLParams.Add(key, value)
+	}
+	return r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+}
+
+func testApplicantController(mt *mtest.T) *ApplicantController {
+	return &ApplicantController{
+		collection:  mt.Coll,
+		projects:    mt.DB.Collection("projects"),
+		comparisons: mt.DB.Collection("comparisons"),
+		snapshots:   mt.DB.Collection("ranking_snapshots"),
+	}
+}
+
+// applyComparisonWrites applies the $set updates and deletes the handler sent to the comparisons
+// collection, matching documents on every field of the filter and on any branch of a top-level $or
+func applyComparisonWrites(mt *mtest.T, comparisons []models.Comparison) []models.Comparison {
+	matches := func(c models.Comparison, filter bson.Raw) bool {
+		doc, err := bson.Marshal(c)
+		if err != nil {
+			mt.Fatal(err)
+		}
+		or, err := filter.LookupErr("$or")
+		if err != nil {
+			return matchesAll(doc, filter)
+		}
+		branches, _ := or.Array().Values()
+		for _, branch := range branches {
+			if matchesAll(doc, branch.Document()) && matchesAll(doc, filter) {
+				return true
+			}
+		}
+		return false
+	}
+
+	out := append([]models.Comparison{}, comparisons...)
+	for _, event := range mt.GetAllStartedEvents() {
+		switch {
+		case event.CommandName == "delete" && event.Command.Lookup("delete").StringValue() == "comparisons":
+			filter := event.Command.Lookup("deletes", "0", "q").Document()
+			kept := out[:0]
+			for _, c := range out {
+				if !matches(c, filter) {
+					kept = append(kept, c)
+				}
+			}
+			out = kept
+		case event.CommandName == "update" && event.Command.Lookup("update").StringValue() == "comparisons":
+			update := event.Command.Lookup("updates", "0").Document()
+			set, _ := update.Lookup("u", "$set").Document().Elements()
+			for i := range out {
+				if !matches(out[i], update.Lookup("q").Document()) {
+					continue
+				}
+				for _, element := range set {
+					switch element.Key() {
+					case "winnerId":
+						out[i].WinnerID = element.Value().ObjectID()
+					case "loserId":
+						out[i].LoserID = element.Value().ObjectID()
+					}
+				}
+			}
+		}
+	}
+	return out
+}
+
+// matchesAll reports whether doc has every field of filter but $or with the same value
+func matchesAll(doc bson.Raw, filter bson.Raw) bool {
+	elements, _ := filter.Elements()
+	for _, element := range elements {
+		if element.Key() != "$or" && !doc.Lookup(element.Key()).Equal(element.Value()) {
+			return false
+		}
+	}
+	return true
+}
+
+// Merging hands the duplicate's games to the primary. The comparison log has to follow, or
+// withdrawing anyone with a replay afterwards fails with errIncompleteComparisonLog.
+func TestMergeThenWithdrawReplays(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	project := models.Project{ID: primitive.NewObjectID(), Name: "Recruiting"}
+	primary := models.Applicant{ID: primitive.NewObjectID(), ProjectID: project.ID, FirstName: "Ada", Elo: 1000, Wins: 1, Losses: 1, Status: models.ApplicantStatusActive}
+	duplicate := models.Applicant{ID: primitive.NewObjectID(), ProjectID: project.ID, FirstName: "Ada", Elo: 1000, Wins: 1, Losses: 1, Status: models.ApplicantStatusActive}
+	other := models.Applicant{ID: primitive.NewObjectID(), ProjectID: project.ID, FirstName: "Grace", Elo: 1000, Wins: 1, Losses: 1, Status: models.ApplicantStatusActive}
+	at := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	comparison := func(winner, loser primitive.ObjectID, minute int) models.Comparison {
+		return models.Comparison{ID: primitive.NewObjectID(), ProjectID: project.ID, WinnerID: winner, LoserID: loser, CreatedAt: at.Add(time.Duration(minute) * time.Minute)}
+	}
+	logged := []models.Comparison{
+		comparison(duplicate.ID, other.ID, 1),
+		comparison(other.ID, primary.ID, 2),
+		comparison(primary.ID, duplicate.ID, 3),
+	}
+
+	var merged models.Applicant
+	var repointed []models.Comparison
+	mt.Run("merge", func(mt *mtest.T) {
+		ac := testApplicantController(mt)
+		mt.AddMockResponses(
+			cursorOf(mt, "db.applicants", primary),
+			cursorOf(mt, "db.applicants", duplicate),
+			cursorOf(mt, "db.projects", project),
+			mtest.CreateCursorResponse(0, "db.comparisons", mtest.FirstBatch, bson.D{{Key: "n", Value: 1}}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 3}, bson.E{Key: "nModified", Value: 3}),
+			mtest.CreateSuccessResponse(),
+			cursorOf(mt, "db.projects", project),
+		)
+
+		r := httptest.NewRequest(http.MethodPost, "/api/applicants/"+primary.ID.Hex()+"/merge", strings.NewReader(`{"duplicateId":"`+duplicate.ID.Hex()+`"}`))
+		w := httptest.NewRecorder()
+		ac.Merge(w, withURLParams(r, map[string]string{"id": primary.ID.Hex()}))
+		if w.Code != http.StatusOK {
+			mt.Fatalf("merge status = %d: %s", w.Code, w.Body)
+		}
+		if err := json.NewDecoder(w.Body).Decode(&merged); err != nil {
+			mt.Fatal(err)
+		}
+		// the game between the two was a win and a loss for the same person
+		if merged.Wins != 1 || merged.Losses != 1 {
+			mt.Fatalf("merged record is %d-%d, want 1-1", merged.Wins, merged.Losses)
+		}
+
+		repointed = applyComparisonWrites(mt, logged)
+		games := 0
+		for _, c := range repointed {
+			if c.WinnerID == duplicate.ID || c.LoserID == duplicate.ID {
+				mt.Fatalf("comparison %s still points at the merged duplicate", c.ID.Hex())
+			}
+			if c.WinnerID == c.LoserID {
+				mt.Fatalf("comparison %s became a game against themselves", c.ID.Hex())
+			}
+			if c.WinnerID == primary.ID || c.LoserID == primary.ID {
+				games++
+			}
+		}
+		if games != merged.Wins+merged.Losses {
+			mt.Errorf("log has %d games for the merged applicant, record has %d", games, merged.Wins+merged.Losses)
+		}
+
+		var writes, inTransaction int
+		for _, event := range mt.GetAllStartedEvents() {
+			switch event.CommandName {
+			case "update", "delete":
+				writes++
+				if _, err := event.Command.LookupErr("txnNumber"); err == nil {
+					inTransaction++
+				}
+			}
+		}
+		if writes == 0 || inTransaction != writes || commandNames(mt)[len(commandNames(mt))-2] != "commitTransaction" {
+			mt.Errorf("%d of %d writes in a committed transaction: %v", inTransaction, writes, commandNames(mt))
+		}
+	})
+
+	withdraw := func(mt *mtest.T, comparisons []models.Comparison) *httptest.ResponseRecorder {
+		ac := testApplicantController(mt)
+		withdrawn := other
+		withdrawn.Status = models.ApplicantStatusWithdrawn
+		mt.AddMockResponses(
+			cursorOf(mt, "db.applicants", other),
+			cursorOf(mt, "db.projects", project),
+			cursorOf(mt, "db.applicants", merged, other),
+			cursorOf(mt, "db.comparisons", toAny(comparisons)...),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 2}, bson.E{Key: "nModified", Value: 2}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+			mtest.CreateSuccessResponse(bson.E{Key: "value", Value: toDoc(mt, withdrawn)}),
+			cursorOf(mt, "db.projects", project),
+		)
+
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"status":"withdrawn","replay":true}`))
+		w := httptest.NewRecorder()
+		ac.SetStatus(w, withURLParams(r, map[string]string{"id": project.ID.Hex(), "applicantId": other.ID.Hex()}))
+		return w
+	}
+
+	mt.Run("withdraw and replay after merge", func(mt *mtest.T) {
+		if w := withdraw(mt, repointed); w.Code != http.StatusOK {
+			mt.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+		}
+	})
+}
+
+func TestMergeRefusals(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	project := models.Project{ID: primitive.NewObjectID(), Name: "Recruiting", OwnerIDs: []string{"user_alice"}}
+	primary := models.Applicant{ID: primitive.NewObjectID(), ProjectID: project.ID, FirstName: "Ada", Stage: 1}
+	sameStage := models.Applicant{ID: primitive.NewObjectID(), ProjectID: project.ID, FirstName: "Ada", Stage: 1}
+	earlierStage := models.Applicant{ID: primitive.NewObjectID(), ProjectID: project.ID, FirstName: "Ada", Stage: 0}
+
+	tests := []struct {
+		name       string
+		userID     string
+		duplicate  models.Applicant
+		wantStatus int
+	}{
+		{"reviewer", "user_bob", sameStage, http.StatusForbidden},
+		{"not signed in", "", sameStage, http.StatusForbidden},
+		{"different stages", "user_alice", earlierStage, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			ac := testApplicantController(mt)
+			mt.AddMockResponses(
+				cursorOf(mt, "db.applicants", primary),
+				cursorOf(mt, "db.applicants", tt.duplicate),
+				cursorOf(mt, "db.projects", project),
+			)
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"duplicateId":"`+tt.duplicate.ID.Hex()+`"}`))
+			if tt.userID != "" {
+				r = r.WithContext(middleware.WithUserID(r.Context(), tt.userID))
+			}
+			w := httptest.NewRecorder()
+			ac.Merge(w, withURLParams(r, map[string]string{"id": primary.ID.Hex()}))
+
+			if w.Code != tt.wantStatus {
+				mt.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if names := commandNames(mt); len(names) != 3 {
+				mt.Errorf("commands = %v, want nothing written", names)
+			}
+		})
+	}
+}
+
+func toAny[T any](values []T) []interface{} {
+	out := make([]interface{}, len(values))
+	for i, v := range values {
+		out[i] = v
+	}
+	return out
+}
+
+func TestGetAllValidatesQuery(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	projectID := primitive.NewObjectID()
+
+	tests := []struct {
+		query     string
+		wantField string
+	}{
+		{"page=0", "page"},
+		{"page=two", "page"},
+		{"pageSize=101", "pageSize"},
+		{"sort=-email", "sort"},
+		{"status=archived", "status"},
+		{"status=Withdrawn", "status"},
+	}
+	for _, tt := range tests {
+		mt.Run(tt.query, func(mt *mtest.T) {
+			ac := testApplicantController(mt)
+			r := httptest.NewRequest(http.MethodGet, "/api/projects/"+projectID.Hex()+"/applicants?"+tt.query, nil)
+			w := httptest.NewRecorder()
+			ac.GetAll(w, withURLParams(r, map[string]string{"id": projectID.Hex()}))
+
+			var body apiError
+			json.NewDecoder(w.Body).Decode(&body)
+			if w.Code != http.StatusBadRequest || body.Fields[tt.wantField] == "" {
+				mt.Fatalf("status = %d, fields = %v, want 400 naming %s", w.Code, body.Fields, tt.wantField)
+			}
+			if names := commandNames(mt); len(names) > 0 {
+				mt.Errorf("queried the database with an invalid query: %v", names)
+			}
+		})
+	}
+}
+
+func TestGetAllFiltersStatus(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	projectID := primitive.NewObjectID()
+
+	tests := []struct {
+		status string
+		want   interface{}
+	}{
+		{"withdrawn", "withdrawn"},
+		{"active", bson.M{"$in": []interface{}{nil, "", models.ApplicantStatusActive}}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		mt.Run("status="+tt.status, func(mt *mtest.T) {
+			ac := testApplicantController(mt)
+			mt.AddMockResponses(
+				mtest.CreateCursorResponse(0, "db.applicants", mtest.FirstBatch, bson.D{{Key: "n", Value: 0}}),
+				mtest.CreateCursorResponse(0, "db.applicants", mtest.FirstBatch),
+			)
+			r := httptest.NewRequest(http.MethodGet, "/api/projects/"+projectID.Hex()+"/applicants?status="+tt.status, nil)
+			w := httptest.NewRecorder()
+			ac.GetAll(w, withURLParams(r, map[string]string{"id": projectID.Hex()}))
+			if w.Code != http.StatusOK {
+				mt.Fatalf("status = %d: %s", w.Code, w.Body)
+			}
+
+			var find bson.Raw
+			for _, event := range mt.GetAllStartedEvents() {
+				if event.CommandName == "find" {
+					find = event.Command
+				}
+			}
+			if find == nil {
+				mt.Fatal("applicants were never listed")
+			}
+			got, err := find.LookupErr("filter", "status")
+			switch {
+			case tt.want == nil && err == nil:
+				mt.Errorf("filtered on status %v without a status parameter", got)
+			case tt.want != nil && !got.Equal(bsonValue(mt, tt.want)):
+				mt.Errorf("status filter = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// bsonValue marshals v the way the driver sends it in a command
+func bsonValue(t testing.TB, v interface{}) bson.RawValue {
+	doc, err := bson.Marshal(bson.M{"v": v})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bson.Raw(doc).Lookup("v")
+}
+
+func TestApplicantCRUD(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	project := models.Project{ID: primitive.NewObjectID(), Name: "Recruiting"}
+	applicant := models.Applicant{ID: primitive.NewObjectID(), ProjectID: project.ID, FirstName: "Ada", LastName: "Lovelace", Stage: 1, Elo: 1000}
+	params := map[string]string{"id": project.ID.Hex(), "applicantId": applicant.ID.Hex()}
+	jsonRequest := func(method, body string) *http.Request {
+		r := httptest.NewRequest(method, "/", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		return withURLParams(r, params)
+	}
+
+	mt.Run("get", func(mt *mtest.T) {
+		ac := testApplicantController(mt)
+		mt.AddMockResponses(cursorOf(mt, "db.applicants", applicant), cursorOf(mt, "db.projects", project))
+		w := httptest.NewRecorder()
+		ac.GetById(w, jsonRequest(http.MethodGet, ""))
+
+		var got models.Applicant
+		if err := json.NewDecoder(w.Body).Decode(&got); err != nil || w.Code != http.StatusOK || got.ID != applicant.ID {
+			mt.Fatalf("status = %d, applicant = %v (%v)", w.Code, got.ID, err)
+		}
+		filter := mt.GetStartedEvent().Command.Lookup("filter").Document()
+		if filter.Lookup("project_id").ObjectID() != project.ID {
+			mt.Errorf("applicant looked up outside the project in the URL: %v", filter)
+		}
+	})
+
+	mt.Run("get missing", func(mt *mtest.T) {
+		ac := testApplicantController(mt)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "db.applicants", mtest.FirstBatch))
+		w := httptest.NewRecorder()
+		ac.GetById(w, jsonRequest(http.MethodGet, ""))
+		if w.Code != http.StatusNotFound {
+			mt.Fatalf("status = %d, want 404", w.Code)
+		}
+	})
+
+	mt.Run("get with a bad id", func(mt *mtest.T) {
+		ac := testApplicantController(mt)
+		r := withURLParams(httptest.NewRequest(http.MethodGet, "/", nil), map[string]string{"id": project.ID.Hex(), "applicantId": "nope"})
+		w := httptest.NewRecorder()
+		ac.GetById(w, r)
+		if w.Code != http.StatusBadRequest {
+			mt.Fatalf("status = %d, want 400", w.Code)
+		}
+	})
+
+	createTests := []struct {
+		name       string
+		body       string
+		wantFields []string
+	}{
+		{"without a name", `{"major":"Maths"}`, []string{"firstName", "lastName"}},
+		{"blank name", `{"firstName":" ","lastName":"Lovelace"}`, []string{"firstName"}},
+		{"bad contact details", `{"firstName":"Ada","lastName":"Lovelace","email":"ada@","phone":"12"}`, []string{"email", "phone"}},
+		{"bad timestamp", `{"firstName":"Ada","lastName":"Lovelace","timestamp":"yesterday"}`, []string{"timestamp"}},
+		{"removing files", `{"firstName":"Ada","lastName":"Lovelace","removeFiles":["resume"]}`, []string{"removeFiles"}},
+	}
+	for _, tt := range createTests {
+		mt.Run("create "+tt.name, func(mt *mtest.T) {
+			ac := testApplicantController(mt)
+			mt.AddMockResponses(cursorOf(mt, "db.projects", project))
+			w := httptest.NewRecorder()
+			ac.Create(w, jsonRequest(http.MethodPost, tt.body))
+
+			var body apiError
+			json.NewDecoder(w.Body).Decode(&body)
+			if w.Code != http.StatusBadRequest || len(body.Fields) != len(tt.wantFields) {
+				mt.Fatalf("status = %d, fields = %v, want 400 naming %v", w.Code, body.Fields, tt.wantFields)
+			}
+			for _, field := range tt.wantFields {
+				if body.Fields[field] == "" {
+					mt.Errorf("fields = %v, want %s", body.Fields, field)
+				}
+			}
+		})
+	}
+
+	mt.Run("create in a missing project", func(mt *mtest.T) {
+		ac := testApplicantController(mt)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "db.projects", mtest.FirstBatch))
+		w := httptest.NewRecorder()
+		ac.Create(w, jsonRequest(http.MethodPost, `{"firstName":"Ada","lastName":"Lovelace"}`))
+		if w.Code != http.StatusNotFound {
+			mt.Fatalf("status = %d, want 404", w.Code)
+		}
+	})
+
+	updateTests := []struct {
+		name      string
+		body      string
+		wantField string
+	}{
+		{"blank name", `{"lastName":""}`, "lastName"},
+		{"unknown file", `{"removeFiles":["portfolio"]}`, "removeFiles"},
+		{"long major", `{"major":"` + strings.Repeat("m", 201) + `"}`, "major"},
+	}
+	for _, tt := range updateTests {
+		mt.Run("update "+tt.name, func(mt *mtest.T) {
+			ac := testApplicantController(mt)
+			mt.AddMockResponses(cursorOf(mt, "db.applicants", applicant))
+			w := httptest.NewRecorder()
+			ac.Update(w, jsonRequest(http.MethodPatch, tt.body))
+
+			var body apiError
+			json.NewDecoder(w.Body).Decode(&body)
+			if w.Code != http.StatusBadRequest || body.Fields[tt.wantField] == "" {
+				mt.Fatalf("status = %d, fields = %v, want 400 naming %s", w.Code, body.Fields, tt.wantField)
+			}
+		})
+	}
+
+	mt.Run("update nothing", func(mt *mtest.T) {
+		ac := testApplicantController(mt)
+		mt.AddMockResponses(cursorOf(mt, "db.applicants", applicant))
+		w := httptest.NewRecorder()
+		ac.Update(w, jsonRequest(http.MethodPatch, `{}`))
+		if w.Code != http.StatusBadRequest {
+			mt.Fatalf("status = %d, want 400", w.Code)
+		}
+	})
+
+	mt.Run("update", func(mt *mtest.T) {
+		ac := testApplicantController(mt)
+		updated := applicant
+		updated.Major = "Mathematics"
+		mt.AddMockResponses(
+			cursorOf(mt, "db.applicants", applicant),
+			mtest.CreateSuccessResponse(bson.E{Key: "value", Value: toDoc(mt, updated)}),
+			cursorOf(mt, "db.projects", project),
+		)
+		w := httptest.NewRecorder()
+		ac.Update(w, jsonRequest(http.MethodPatch, `{"major":"  Mathematics ","elo":3000}`))
+		if w.Code != http.StatusOK {
+			mt.Fatalf("status = %d: %s", w.Code, w.Body)
+		}
+
+		var set bson.Raw
+		for _, event := range mt.GetAllStartedEvents() {
+			if event.CommandName == "findAndModify" {
+				set = event.Command.Lookup("update", "$set").Document()
+			}
+		}
+		if major := set.Lookup("major").StringValue(); major != "Mathematics" {
+			mt.Errorf("major set to %q, want it trimmed", major)
+		}
+		if _, err := set.LookupErr("elo"); err == nil {
+			mt.Error("update changed the applicant's rating")
+		}
+	})
+
+	mt.Run("delete", func(mt *mtest.T) {
+		ac := testApplicantController(mt)
+		mt.AddMockResponses(
+			cursorOf(mt, "db.applicants", applicant),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 2}, bson.E{Key: "nModified", Value: 2}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 3}, bson.E{Key: "nModified", Value: 3}),
+		)
+		published, unsubscribe := events.Subscribe(project.ID, 4)
+		defer unsubscribe()
+
+		w := httptest.NewRecorder()
+		ac.Delete(w, jsonRequest(http.MethodDelete, ""))
+		if w.Code != http.StatusNoContent {
+			mt.Fatalf("status = %d: %s", w.Code, w.Body)
+		}
+
+		var marked bool
+		for _, event := range mt.GetAllStartedEvents() {
+			if event.CommandName != "update" || event.Command.Lookup("update").StringValue() != "comparisons" {
+				continue
+			}
+			update := event.Command.Lookup("updates", "0").Document()
+			marked = update.Lookup("u", "$set", "applicantDeleted").Boolean() &&
+				strings.Contains(update.Lookup("q", "$or").String(), applicant.ID.Hex())
+		}
+		if !marked {
+			mt.Errorf("the deleted applicant's comparisons weren't marked: %v", commandNames(mt))
+		}
+
+		select {
+		case event := <-published:
+			if event.Type != events.RankingChanged || event.Data.(map[string]int)["stage"] != applicant.Stage {
+				mt.Errorf("published %s %v, want ranking-changed for stage %d", event.Type, event.Data, applicant.Stage)
+			}
+		default:
+			mt.Error("no ranking-changed event after deleting a ranked applicant")
+		}
+	})
+}
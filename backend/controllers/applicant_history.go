@@ -0,0 +1,204 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"backend/models"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// historyEntry is one comparison from the point of view of the applicant whose history it is
+type historyEntry struct {
+	ComparisonID primitive.ObjectID `json:"comparisonId"`
+	Stage        int                `json:"stage"`
+	Opponent     applicantName      `json:"opponent"`
+	// "win" or "loss"
+	Outcome           string    `json:"outcome"`
+	ReviewerID        string    `json:"reviewerId,omitempty"`
+	EloBefore         int       `json:"eloBefore"`
+	EloAfter          int       `json:"eloAfter"`
+	OpponentEloBefore int       `json:"opponentEloBefore"`
+	OpponentEloAfter  int       `json:"opponentEloAfter"`
+	CreatedAt         time.Time `json:"createdAt"`
+	// taken out of the ratings by a replay
+	Voided bool `json:"voided,omitempty"`
+}
+
+// applicantName identifies an applicant in a history; opponents deleted since have no name
+type applicantName struct {
+	ID         primitive.ObjectID `json:"id"`
+	FirstName  string             `json:"firstName"`
+	LastName   string             `json:"lastName"`
+	Anonymised bool               `json:"anonymised,omitempty"`
+}
+
+// ratingPoint is the applicant's rating after a comparison, or at the start of a stage
+type ratingPoint struct {
+	At    time.Time `json:"at"`
+	Stage int       `json:"stage"`
+	Elo   int       `json:"elo"`
+}
+
+// History lists every comparison an applicant took part in, oldest first, with the opponent, the
+// outcome, the reviewer and both ratings either side of the vote, plus the applicant's rating over
+// time for charting. Ratings restart at each stage, so each point carries its stage. ?stage= keeps
+// one stage. Voided comparisons are listed but left out of the ratings series; games against
+// themselves, left by earlier merges, are left out altogether.
+func (ac *ApplicantController) History(w http.ResponseWriter, r *http.Request) {
+	applicantID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid Applicant ID")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var applicant models.Applicant
+	if err := ac.collection.FindOne(ctx, bson.M{"_id": applicantID}).Decode(&applicant); err != nil {
+		if err == mongo.ErrNoDocuments {
+			writeError(w, http.StatusNotFound, "Applicant not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "Failed to fetch applicant")
+		log.Println("MongoDB Find applicant error:", err)
+		return
+	}
+
+	filter := bson.M{"$or": []bson.M{{"winnerId": applicantID}, {"loserId": applicantID}}}
+	if v := r.URL.Query().Get("stage"); v != "" {
+		stage, err := strconv.Atoi(v)
+		if err != nil || stage < 0 {
+			writeValidationError(w, map[string]string{"stage": "must be a stage number"})
+			return
+		}
+		filter["stage"] = stageFilter(stage)
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := ac.comparisons.Find(ctx, filter, opts)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to fetch comparisons")
+		log.Println("MongoDB Find comparisons error:", err)
+		return
+	}
+	var comparisons []models.Comparison
+	if err := cursor.All(ctx, &comparisons); err != nil {
+		writeError(w, http.StatusInternalServerError, "Error decoding comparisons")
+		return
+	}
+
+	opponents, err := ac.historyOpponents(ctx, applicantID, comparisons)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to fetch opponents")
+		log.Println("MongoDB Find opponents error:", err)
+		return
+	}
+	// opponents and the applicant go through the same blind-review rules as any other applicant response
+	people := []*models.Applicant{&applicant}
+	for _, opponent := range opponents {
+		people = append(people, opponent)
+	}
+	ac.prepareApplicants(ctx, r, people...)
+
+	entries := make([]historyEntry, 0, len(comparisons))
+	ratings := []ratingPoint{}
+	for _, c := range comparisons {
+		// merges once repointed games between the two duplicates into games against themselves
+		if c.WinnerID == c.LoserID {
+			continue
+		}
+		entry := historyEntry{
+			ComparisonID: c.ID,
+			Stage:        c.Stage,
+			ReviewerID:   c.ReviewerID,
+			CreatedAt:    c.CreatedAt,
+			Voided:       c.Voided,
+		}
+		if c.WinnerID == applicantID {
+			entry.Outcome = "win"
+			entry.Opponent.ID = c.LoserID
+			entry.EloBefore, entry.EloAfter = c.WinnerEloBefore, c.WinnerEloAfter
+			entry.OpponentEloBefore, entry.OpponentEloAfter = c.LoserEloBefore, c.LoserEloAfter
+		} else {
+			entry.Outcome = "loss"
+			entry.Opponent.ID = c.WinnerID
+			entry.EloBefore, entry.EloAfter = c.LoserEloBefore, c.LoserEloAfter
+			entry.OpponentEloBefore, entry.OpponentEloAfter = c.WinnerEloBefore, c.WinnerEloAfter
+		}
+		if opponent := opponents[entry.Opponent.ID]; opponent != nil {
+			entry.Opponent.FirstName, entry.Opponent.LastName = opponent.FirstName, opponent.LastName
+			entry.Opponent.Anonymised = opponent.Anonymised
+		}
+		entries = append(entries, entry)
+
+		if c.Voided {
+			continue
+		}
+		// each stage's line starts from the rating the applicant went into their first vote with
+		if len(ratings) == 0 || ratings[len(ratings)-1].Stage != c.Stage {
+			ratings = append(ratings, ratingPoint{At: c.CreatedAt, Stage: c.Stage, Elo: entry.EloBefore})
+		}
+		ratings = append(ratings, ratingPoint{At: c.CreatedAt, Stage: c.Stage, Elo: entry.EloAfter})
+	}
+
+	response := struct {
+		Applicant   applicantName  `json:"applicant"`
+		Elo         int            `json:"elo"`
+		Wins        int            `json:"wins"`
+		Losses      int            `json:"losses"`
+		Comparisons []historyEntry `json:"comparisons"`
+		Ratings     []ratingPoint  `json:"ratings"`
+	}{
+		Applicant:   applicantName{ID: applicant.ID, FirstName: applicant.FirstName, LastName: applicant.LastName, Anonymised: applicant.Anonymised},
+		Elo:         applicant.Elo,
+		Wins:        applicant.Wins,
+		Losses:      applicant.Losses,
+		Comparisons: entries,
+		Ratings:     ratings,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// historyOpponents loads everyone applicantID was compared with, keyed by id
+func (ac *ApplicantController) historyOpponents(ctx context.Context, applicantID primitive.ObjectID, comparisons []models.Comparison) (map[primitive.ObjectID]*models.Applicant, error) {
+	ids := []primitive.ObjectID{}
+	seen := map[primitive.ObjectID]bool{applicantID: true}
+	for _, c := range comparisons {
+		for _, id := range []primitive.ObjectID{c.WinnerID, c.LoserID} {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	opponents := map[primitive.ObjectID]*models.Applicant{}
+	if len(ids) == 0 {
+		return opponents, nil
+	}
+	opts := options.Find().SetProjection(bson.M{"firstName": 1, "lastName": 1, "project_id": 1})
+	cursor, err := ac.collection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var applicants []models.Applicant
+	if err := cursor.All(ctx, &applicants); err != nil {
+		return nil, err
+	}
+	for i := range applicants {
+		opponents[applicants[i].ID] = &applicants[i]
+	}
+	return opponents, nil
+}
@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"backend/blind"
+	"backend/middleware"
+	"backend/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestHistory(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	project := models.Project{ID: primitive.NewObjectID(), Name: "Fall", OwnerIDs: []string{"user_alice"}, BlindReview: true}
+	ada := models.Applicant{ID: primitive.NewObjectID(), ProjectID: project.ID, FirstName: "Ada", LastName: "Lovelace", Elo: 1016, Wins: 1, Losses: 0}
+	grace := models.Applicant{ID: primitive.NewObjectID(), ProjectID: project.ID, FirstName: "Grace", LastName: "Hopper"}
+	alan := models.Applicant{ID: primitive.NewObjectID(), ProjectID: project.ID, FirstName: "Alan", LastName: "Turing"}
+	at := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	comparisons := []interface{}{
+		// left behind by a merge from before merges dropped the pair's own games
+		models.Comparison{ID: primitive.NewObjectID(), ProjectID: project.ID, WinnerID: ada.ID, LoserID: ada.ID, WinnerEloBefore: 1000, WinnerEloAfter: 1016, CreatedAt: at},
+		models.Comparison{ID: primitive.NewObjectID(), ProjectID: project.ID, WinnerID: ada.ID, LoserID: grace.ID, WinnerEloBefore: 1000, WinnerEloAfter: 1016, LoserEloBefore: 1000, LoserEloAfter: 984, CreatedAt: at.Add(time.Minute)},
+		models.Comparison{ID: primitive.NewObjectID(), ProjectID: project.ID, WinnerID: alan.ID, LoserID: ada.ID, WinnerEloBefore: 1000, WinnerEloAfter: 1015, LoserEloBefore: 1016, LoserEloAfter: 1001, CreatedAt: at.Add(2 * time.Minute), Voided: true},
+	}
+
+	type history struct {
+		Applicant   applicantName  `json:"applicant"`
+		Comparisons []historyEntry `json:"comparisons"`
+		Ratings     []ratingPoint  `json:"ratings"`
+	}
+	get := func(mt *mtest.T, userID string) history {
+		ac := testApplicantController(mt)
+		mt.AddMockResponses(
+			cursorOf(mt, "db.applicants", ada),
+			cursorOf(mt, "db.comparisons", comparisons...),
+			cursorOf(mt, "db.applicants", grace, alan),
+			cursorOf(mt, "db.projects", project),
+		)
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r = r.WithContext(middleware.WithUserID(r.Context(), userID))
+		w := httptest.NewRecorder()
+		ac.History(w, withURLParams(r, map[string]string{"id": ada.ID.Hex()}))
+		if w.Code != http.StatusOK {
+			mt.Fatalf("status = %d: %s", w.Code, w.Body)
+		}
+		var h history
+		if err := json.NewDecoder(w.Body).Decode(&h); err != nil {
+			mt.Fatal(err)
+		}
+		return h
+	}
+
+	mt.Run("owner", func(mt *mtest.T) {
+		h := get(mt, "user_alice")
+		if len(h.Comparisons) != 2 {
+			mt.Fatalf("%d comparisons listed, want the game against themselves left out", len(h.Comparisons))
+		}
+		win, loss := h.Comparisons[0], h.Comparisons[1]
+		if win.Outcome != "win" || win.Opponent.ID != grace.ID || win.Opponent.FirstName != "Grace" || win.EloBefore != 1000 || win.EloAfter != 1016 {
+			mt.Errorf("first entry = %+v, want a win over Grace from 1000 to 1016", win)
+		}
+		if loss.Outcome != "loss" || loss.Opponent.FirstName != "Alan" || !loss.Voided || loss.OpponentEloAfter != 1015 {
+			mt.Errorf("second entry = %+v, want a voided loss to Alan", loss)
+		}
+		// the voided loss is listed but doesn't move the rating
+		if len(h.Ratings) != 2 || h.Ratings[0].Elo != 1000 || h.Ratings[1].Elo != 1016 {
+			mt.Errorf("ratings = %+v, want 1000 then 1016", h.Ratings)
+		}
+	})
+
+	mt.Run("reviewer under blind review", func(mt *mtest.T) {
+		h := get(mt, "user_bob")
+		if h.Applicant.FirstName != blind.Pseudonym(project.ID, ada.ID) || h.Applicant.LastName != "" || !h.Applicant.Anonymised {
+			mt.Errorf("applicant = %+v, want Ada's pseudonym", h.Applicant)
+		}
+		wantOpponents := []primitive.ObjectID{grace.ID, alan.ID}
+		for i, entry := range h.Comparisons {
+			o := entry.Opponent
+			if o.ID != wantOpponents[i] || o.FirstName != blind.Pseudonym(project.ID, o.ID) || o.LastName != "" || !o.Anonymised {
+				mt.Errorf("opponent %d = %+v, want their pseudonym", i, o)
+			}
+		}
+	})
+}
@@ -0,0 +1,171 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"backend/models"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+	snippetRadius      = 80
+)
+
+type applicantSearchResult struct {
+	models.Applicant `bson:",inline"`
+	Score            float64 `json:"score" bson:"score"`
+	Snippet          string  `json:"snippet,omitempty" bson:"-"`
+}
+
+// Search runs a full-text query over a project's applicants: names, major, answers and the
+// text extracted from resumes and cover letters. Results are ordered by relevance and carry a
+// short snippet around the first matching term; the extracted text itself is not returned.
+// The query uses MongoDB $text syntax, so "quoted phrases" and -exclusions work.
+func (ac *ApplicantController) Search(w http.ResponseWriter, r *http.Request) {
+	projectID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid Project ID", http.StatusBadRequest)
+		return
+	}
+
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		http.Error(w, "Search query required", http.StatusBadRequest)
+		return
+	}
+
+	limit := defaultSearchLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxSearchLimit {
+			http.Error(w, fmt.Sprintf("limit must be between 1 and %d", maxSearchLimit), http.StatusBadRequest)
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// names are indexed, so a search would tell a blind reviewer who is behind a pseudonym
+	if blinded, err := ac.hidesIdentities(ctx, r, projectID); blinded {
+		if err != nil {
+			log.Println("MongoDB Find project error:", err)
+		}
+		http.Error(w, "Search is limited to project owners in blind review", http.StatusForbidden)
+		return
+	}
+
+	score := bson.M{"$meta": "textScore"}
+	opts := options.Find().
+		SetProjection(bson.M{"score": score}).
+		SetSort(bson.D{{Key: "score", Value: score}}).
+		SetLimit(int64(limit))
+	cursor, err := ac.collection.Find(ctx, bson.M{
+		"project_id": projectID,
+		"$text":      bson.M{"$search": query},
+	}, opts)
+	if err != nil {
+		http.Error(w, "Failed to search applicants", http.StatusInternalServerError)
+		log.Println("MongoDB text search error:", err)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	results := []applicantSearchResult{}
+	if err := cursor.All(ctx, &results); err != nil {
+		http.Error(w, "Error decoding search results", http.StatusInternalServerError)
+		return
+	}
+
+	terms := searchTerms(query)
+	applicants := make([]*models.Applicant, len(results))
+	for i := range results {
+		result := &results[i]
+		result.Snippet = searchSnippet(terms, &result.Applicant)
+		result.ResumeText, result.CoverLetterText = "", ""
+		applicants[i] = &result.Applicant
+	}
+	ac.prepareApplicants(ctx, r, applicants...)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
+
+// searchTerms lowercases the positive words and phrases of a $text query
+func searchTerms(query string) []string {
+	var terms []string
+	for i, part := range strings.Split(query, `"`) {
+		if i%2 == 1 {
+			// inside quotes, the phrase is matched as a whole
+			if phrase := strings.ToLower(strings.TrimSpace(part)); phrase != "" {
+				terms = append(terms, phrase)
+			}
+			continue
+		}
+		for _, word := range strings.Fields(part) {
+			if strings.HasPrefix(word, "-") {
+				continue
+			}
+			if word = strings.ToLower(strings.TrimFunc(word, unicode.IsPunct)); word != "" {
+				terms = append(terms, word)
+			}
+		}
+	}
+	return terms
+}
+
+// searchSnippet returns the text around the first term found in the applicant's resume,
+// cover letter or answers. MongoDB stems words, so a hit on the stem alone may find nothing here.
+func searchSnippet(terms []string, applicant *models.Applicant) string {
+	sources := []string{applicant.ResumeText, applicant.CoverLetterText}
+	for _, resp := range applicant.Responses {
+		if answer, ok := resp.Answer.(string); ok {
+			sources = append(sources, answer)
+		}
+	}
+
+	for _, text := range sources {
+		lower := strings.ToLower(text)
+		if len(lower) != len(text) {
+			// some runes change width when lowercased, so offsets into lower don't line up with text
+			text = lower
+		}
+		for _, term := range terms {
+			i := strings.Index(lower, term)
+			if i < 0 {
+				continue
+			}
+			start, end := max(i-snippetRadius, 0), min(i+len(term)+snippetRadius, len(text))
+			// widen to whole words so the snippet never cuts through a word or a rune
+			for start > 0 && text[start-1] != ' ' {
+				start--
+			}
+			for end < len(text) && text[end] != ' ' {
+				end++
+			}
+			snippet := strings.TrimSpace(text[start:end])
+			if start > 0 {
+				snippet = "…" + snippet
+			}
+			if end < len(text) {
+				snippet += "…"
+			}
+			return snippet
+		}
+	}
+	return ""
+}
@@ -0,0 +1,124 @@
+package controllers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"backend/models"
+)
+
+func TestSearchTerms(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"Python Rust", []string{"python", "rust"}},
+		{`"machine learning" golang`, []string{"machine learning", "golang"}},
+		{"python -java", []string{"python"}},
+		{"c++, go!", []string{"c++", "go"}},
+		{`"  " -`, nil},
+		{`"unterminated phrase`, []string{"unterminated phrase"}},
+	}
+	for _, tt := range tests {
+		if got := searchTerms(tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("searchTerms(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestSearchSnippet(t *testing.T) {
+	filler := strings.Repeat("lorem ipsum ", 20)
+
+	tests := []struct {
+		name      string
+		terms     []string
+		applicant models.Applicant
+		want      string
+	}{
+		{
+			name:      "no match",
+			terms:     []string{"haskell"},
+			applicant: models.Applicant{ResumeText: "Go and Rust"},
+			want:      "",
+		},
+		{
+			name:      "whole short text",
+			terms:     []string{"rust"},
+			applicant: models.Applicant{ResumeText: "Go and Rust"},
+			want:      "Go and Rust",
+		},
+		{
+			name:      "match at the start",
+			terms:     []string{"kubernetes"},
+			applicant: models.Applicant{ResumeText: "Kubernetes operator " + filler},
+			want:      "Kubernetes operator " + strings.TrimSpace(strings.Repeat("lorem ipsum ", 6)) + "…",
+		},
+		{
+			name:      "match at the end",
+			terms:     []string{"kubernetes"},
+			applicant: models.Applicant{ResumeText: filler + "Kubernetes"},
+			want:      "…" + strings.Repeat("lorem ipsum ", 7) + "Kubernetes",
+		},
+		{
+			name:      "cover letter when the resume has no match",
+			terms:     []string{"compilers"},
+			applicant: models.Applicant{ResumeText: "nothing here", CoverLetterText: "I love compilers"},
+			want:      "I love compilers",
+		},
+		{
+			name:  "answers after the documents",
+			terms: []string{"robotics"},
+			applicant: models.Applicant{Responses: []models.Response{
+				{Question: "Age", Answer: 20.0},
+				{Question: "Why", Answer: "Robotics club"},
+			}},
+			want: "Robotics club",
+		},
+		{
+			name:      "first term found wins",
+			terms:     []string{"absent", "go"},
+			applicant: models.Applicant{ResumeText: "Go developer"},
+			want:      "Go developer",
+		},
+		{
+			name:      "phrase",
+			terms:     []string{"machine learning"},
+			applicant: models.Applicant{ResumeText: "Machine Learning intern"},
+			want:      "Machine Learning intern",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := searchSnippet(tt.terms, &tt.applicant); got != tt.want {
+				t.Errorf("searchSnippet() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchSnippetBoundaries(t *testing.T) {
+	// the radius lands inside long multi-byte words on both sides
+	word := strings.Repeat("ü", 60)
+	text := word + " " + word + " target " + word + " " + word
+
+	snippet := searchSnippet([]string{"target"}, &models.Applicant{ResumeText: text})
+	if !utf8.ValidString(snippet) {
+		t.Fatalf("snippet %q is not valid UTF-8", snippet)
+	}
+	if !strings.HasPrefix(snippet, "…") || !strings.HasSuffix(snippet, "…") {
+		t.Errorf("snippet %q, want ellipses on both sides", snippet)
+	}
+	for _, w := range strings.Fields(strings.Trim(snippet, "…")) {
+		if w != word && w != "target" {
+			t.Errorf("snippet cut through a word: %q", w)
+		}
+	}
+
+	// lowercasing İ changes its width, so offsets come from the lowercased text instead
+	snippet = searchSnippet([]string{"istanbul"}, &models.Applicant{ResumeText: "İİİ Istanbul office"})
+	if !utf8.ValidString(snippet) || !strings.Contains(snippet, "istanbul") {
+		t.Errorf("snippet = %q, want a valid snippet around the match", snippet)
+	}
+}
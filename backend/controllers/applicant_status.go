@@ -0,0 +1,181 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"backend/elo"
+	"backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// activeApplicantFilter narrows filter to applicants still in the pairing pool.
+// Applicants stored before statuses existed have no status and count as active.
+func activeApplicantFilter(filter bson.M) bson.M {
+	filter["status"] = bson.M{"$in": []interface{}{nil, "", models.ApplicantStatusActive}}
+	return filter
+}
+
+// SetStatus moves an applicant between active, withdrawn, disqualified and advanced.
+// Body: {"status": "withdrawn", "replay": true}. With replay, the project's ratings are
+// recomputed from the comparison log as if the applicant's comparisons had never happened.
+func (ac *ApplicantController) SetStatus(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Status string `json:"status"`
+		Replay bool   `json:"replay"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid JSON input: "+err.Error())
+		return
+	}
+	if !models.ValidApplicantStatus(request.Status) {
+		writeValidationError(w, map[string]string{"status": "must be one of active, withdrawn, disqualified or advanced"})
+		return
+	}
+	if request.Replay && request.Status != models.ApplicantStatusWithdrawn && request.Status != models.ApplicantStatusDisqualified {
+		writeValidationError(w, map[string]string{"replay": "only applies when withdrawing or disqualifying"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	applicant := ac.findProjectApplicant(ctx, w, r)
+	if applicant == nil {
+		return
+	}
+
+	if request.Replay {
+		project := ac.loadProject(ctx, w, applicant.ProjectID.Hex())
+		if project == nil {
+			return
+		}
+		if applicant.Stage != project.CurrentStage {
+			writeError(w, http.StatusConflict, "Ratings can only be replayed in the stage being reviewed")
+			return
+		}
+		if err := ac.replayWithout(ctx, project, applicant.ID); err != nil {
+			if err == errIncompleteComparisonLog {
+				writeError(w, http.StatusConflict, err.Error())
+				return
+			}
+			writeError(w, http.StatusInternalServerError, "Failed to replay ratings")
+			log.Println("Rating replay error:", err)
+			return
+		}
+	}
+
+	var updated models.Applicant
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := ac.collection.FindOneAndUpdate(ctx, bson.M{"_id": applicant.ID}, bson.M{"$set": bson.M{"status": request.Status}}, opts).Decode(&updated)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to update applicant status")
+		log.Println("MongoDB Update applicant status error:", err)
+		return
+	}
+
+	if applicant.IsActive() != updated.IsActive() || request.Replay {
+		publishRankingChanged(updated.ProjectID, updated.Stage)
+	}
+	ac.prepareApplicants(ctx, r, &updated)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+var errIncompleteComparisonLog = fmt.Errorf("ratings include comparisons from before the comparison log, they can't be replayed")
+
+// replayWithout voids every comparison involving excluded and recomputes the ratings, wins and
+// losses of the remaining applicants in the project's current stage by replaying the stage's other
+// comparisons in order from the starting rating. Applicants who were withdrawn or disqualified earlier keep their final
+// ratings. Votes cast while the replay runs may be overwritten.
+func (ac *ApplicantController) replayWithout(ctx context.Context, project *models.Project, excluded primitive.ObjectID) error {
+	projectID, stage := project.ID, stageFilter(project.CurrentStage)
+	cursor, err := ac.collection.Find(ctx, bson.M{"project_id": projectID, "stage": stage})
+	if err != nil {
+		return err
+	}
+	var applicants []models.Applicant
+	if err := cursor.All(ctx, &applicants); err != nil {
+		return err
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err = ac.comparisons.Find(ctx, bson.M{"project_id": projectID, "stage": stage, "voided": bson.M{"$ne": true}}, opts)
+	if err != nil {
+		return err
+	}
+	var comparisons []models.Comparison
+	if err := cursor.All(ctx, &comparisons); err != nil {
+		return err
+	}
+
+	// every game an applicant has played must be in the log, or the replay would lose it
+	logged := map[primitive.ObjectID]int{}
+	for _, c := range comparisons {
+		logged[c.WinnerID]++
+		logged[c.LoserID]++
+	}
+	for _, a := range applicants {
+		if replayed(a, excluded) && a.Wins+a.Losses > logged[a.ID] {
+			return errIncompleteComparisonLog
+		}
+	}
+
+	type record struct{ elo, wins, losses int }
+	ratings := map[primitive.ObjectID]*record{}
+	for _, a := range applicants {
+		ratings[a.ID] = &record{elo: initialElo}
+	}
+
+	for _, c := range comparisons {
+		// a merged applicant's games against its own duplicate count for nothing
+		if c.WinnerID == excluded || c.LoserID == excluded || c.WinnerID == c.LoserID {
+			continue
+		}
+		winner, loser := ratings[c.WinnerID], ratings[c.LoserID]
+		if winner == nil || loser == nil {
+			// one side was deleted, its games went with it
+			continue
+		}
+		winner.elo, loser.elo = elo.CalculateElo(winner.elo, loser.elo, true)
+		winner.wins++
+		loser.losses++
+	}
+
+	_, err = ac.comparisons.UpdateMany(ctx,
+		bson.M{"project_id": projectID, "stage": stage, "$or": []bson.M{{"winnerId": excluded}, {"loserId": excluded}}},
+		bson.M{"$set": bson.M{"voided": true}})
+	if err != nil {
+		return err
+	}
+
+	var writes []mongo.WriteModel
+	for _, a := range applicants {
+		if !replayed(a, excluded) {
+			continue
+		}
+		rec := ratings[a.ID]
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": a.ID}).
+			SetUpdate(bson.M{"$set": bson.M{"elo": rec.elo, "wins": rec.wins, "losses": rec.losses}}))
+	}
+	if len(writes) == 0 {
+		return nil
+	}
+	_, err = ac.collection.BulkWrite(ctx, writes)
+	return err
+}
+
+// replayed reports whether a replay rewrites a's rating. Applicants who already left keep theirs.
+func replayed(a models.Applicant, excluded primitive.ObjectID) bool {
+	return a.ID != excluded && a.Status != models.ApplicantStatusWithdrawn && a.Status != models.ApplicantStatusDisqualified
+}
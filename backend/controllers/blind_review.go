@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"backend/blind"
+	"backend/middleware"
+	"backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// prepareApplicants readies applicants for a response. In blind-review projects everyone but the
+// project's owners gets anonymised applicants; then the remaining files get signed links.
+// If a project can't be loaded its applicants are anonymised rather than risk showing identities.
+func (ac *ApplicantController) prepareApplicants(ctx context.Context, r *http.Request, applicants ...*models.Applicant) {
+	userID := middleware.UserID(r.Context())
+	projects := map[primitive.ObjectID]*models.Project{}
+	failed := map[primitive.ObjectID]bool{}
+
+	for _, applicant := range applicants {
+		project, ok := projects[applicant.ProjectID]
+		if !ok && !failed[applicant.ProjectID] {
+			var p models.Project
+			err := ac.projects.FindOne(ctx, bson.M{"_id": applicant.ProjectID}).Decode(&p)
+			switch {
+			case err == nil:
+				project = &p
+			case err == mongo.ErrNoDocuments:
+				// applicants outside any project have no blind setting
+			default:
+				log.Println("MongoDB Find project error:", err)
+				failed[applicant.ProjectID] = true
+			}
+			projects[applicant.ProjectID] = project
+		}
+
+		if failed[applicant.ProjectID] || (project != nil && project.BlindReview && !project.IsOwner(userID)) {
+			blind.Anonymise(applicant)
+		}
+		withFileURLs(r, applicant)
+	}
+}
+
+// hidesIdentities reports whether the caller sees anonymised applicants in projectID
+func (ac *ApplicantController) hidesIdentities(ctx context.Context, r *http.Request, projectID primitive.ObjectID) (bool, error) {
+	var project models.Project
+	err := ac.projects.FindOne(ctx, bson.M{"_id": projectID}).Decode(&project)
+	if err == mongo.ErrNoDocuments {
+		return false, nil
+	}
+	if err != nil {
+		return true, err
+	}
+	return project.BlindReview && !project.IsOwner(middleware.UserID(r.Context())), nil
+}
+
+func applicantPtrs(applicants []models.Applicant) []*models.Applicant {
+	ptrs := make([]*models.Applicant, len(applicants))
+	for i := range applicants {
+		ptrs[i] = &applicants[i]
+	}
+	return ptrs
+}
@@ -0,0 +1,236 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"backend/elo"
+	"backend/models"
+	"backend/ranking"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	defaultConvergenceWindow = 20
+	defaultConvergenceTopK   = 10
+	defaultConvergencePoints = 10
+	maxConvergencePoints     = 100
+)
+
+// convergencePoint compares the ranking after Comparisons votes with the one a window earlier
+type convergencePoint struct {
+	Comparisons int     `json:"comparisons"`
+	KendallTau  float64 `json:"kendallTau"`
+	Spearman    float64 `json:"spearman"`
+	TopKChurn   float64 `json:"topKChurn"`
+}
+
+type convergenceReport struct {
+	Stage       int `json:"stage"`
+	Comparisons int `json:"comparisons"`
+	Window      int `json:"window"`
+	TopK        int `json:"topK"`
+	// one point per window, oldest first
+	History []convergencePoint `json:"history"`
+	// votes since the set of the top K last changed; stable once that is a full window
+	StableFor int               `json:"stableFor"`
+	Stable    bool              `json:"stable"`
+	Closed    bool              `json:"closed"`
+	AutoClose *models.AutoClose `json:"autoClose,omitempty"`
+}
+
+// Convergence reports how much the ranking of the project's current stage still moves: Kendall's tau,
+// Spearman's rho and the churn in the top K between rankings ?window= votes apart, over the last
+// ?points= windows, and how many votes the top K has held. ?k= and ?window= default to the project's
+// auto-close settings. The ranking is rebuilt from the comparison log, so votes from before the log
+// existed are left out.
+func (ac *ApplicantController) Convergence(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	project := ac.loadProject(ctx, w, chi.URLParam(r, "id"))
+	if project == nil {
+		return
+	}
+
+	window, topK := defaultConvergenceWindow, defaultConvergenceTopK
+	if project.AutoClose != nil {
+		window, topK = project.AutoClose.Window, project.AutoClose.TopK
+	}
+	fields := map[string]string{}
+	window = positiveParam(r, "window", window, fields)
+	topK = positiveParam(r, "k", topK, fields)
+	points := positiveParam(r, "points", defaultConvergencePoints, fields)
+	if points > maxConvergencePoints {
+		fields["points"] = "must be at most " + strconv.Itoa(maxConvergencePoints)
+	}
+	if len(fields) > 0 {
+		writeValidationError(w, fields)
+		return
+	}
+
+	report, err := ac.convergence(ctx, project, window, topK, points)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to compute convergence")
+		log.Println("Convergence error:", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+func positiveParam(r *http.Request, name string, fallback int, fields map[string]string) int {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		fields[name] = "must be a positive number"
+	}
+	return n
+}
+
+// convergence replays the current stage's comparison log, snapshotting the ranking of its active
+// applicants every window votes back from the latest and tracking when the top k last changed
+func (ac *ApplicantController) convergence(ctx context.Context, project *models.Project, window, topK, points int) (*convergenceReport, error) {
+	stage := stageFilter(project.CurrentStage)
+	cursor, err := ac.collection.Find(ctx, bson.M{"project_id": project.ID, "stage": stage},
+		options.Find().SetProjection(bson.M{"_id": 1, "status": 1}))
+	if err != nil {
+		return nil, err
+	}
+	var applicants []models.Applicant
+	if err := cursor.All(ctx, &applicants); err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err = ac.comparisons.Find(ctx, bson.M{"project_id": project.ID, "stage": stage, "voided": bson.M{"$ne": true}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var comparisons []models.Comparison
+	if err := cursor.All(ctx, &comparisons); err != nil {
+		return nil, err
+	}
+
+	// everyone's games move the ratings, only the active are ranked
+	ratings := map[primitive.ObjectID]int{}
+	active := map[primitive.ObjectID]bool{}
+	for _, a := range applicants {
+		ratings[a.ID] = initialElo
+		active[a.ID] = a.IsActive()
+	}
+	ranked := func() map[primitive.ObjectID]int {
+		current := map[primitive.ObjectID]int{}
+		for id, rating := range ratings {
+			if active[id] {
+				current[id] = rating
+			}
+		}
+		return current
+	}
+
+	total := len(comparisons)
+	snapshotAt := map[int]bool{}
+	for i := 0; i <= points && total-i*window >= 0; i++ {
+		snapshotAt[total-i*window] = true
+	}
+	var snapshots []map[primitive.ObjectID]int
+	var snapshotVotes []int
+	top := func() []primitive.ObjectID {
+		order := ranking.Order(ranked())
+		return order[:min(topK, len(order))]
+	}
+
+	previousTop := top()
+	lastChange := 0
+	if snapshotAt[0] {
+		snapshots, snapshotVotes = append(snapshots, ranked()), append(snapshotVotes, 0)
+	}
+	for i, c := range comparisons {
+		winner, wok := ratings[c.WinnerID]
+		loser, lok := ratings[c.LoserID]
+		if wok && lok {
+			ratings[c.WinnerID], ratings[c.LoserID] = elo.CalculateElo(winner, loser, true)
+		}
+		if current := top(); ranking.TopKChurn(previousTop, current, topK) > 0 || len(current) != len(previousTop) {
+			previousTop, lastChange = current, i+1
+		}
+		if snapshotAt[i+1] {
+			snapshots, snapshotVotes = append(snapshots, ranked()), append(snapshotVotes, i+1)
+		}
+	}
+
+	report := &convergenceReport{
+		Stage:       project.CurrentStage,
+		Comparisons: total,
+		Window:      window,
+		TopK:        topK,
+		History:     []convergencePoint{},
+		StableFor:   total - lastChange,
+		Stable:      total >= window && total-lastChange >= window,
+		Closed:      project.Closed,
+		AutoClose:   project.AutoClose,
+	}
+	for i := 1; i < len(snapshots); i++ {
+		before, after := snapshots[i-1], snapshots[i]
+		x, y := make([]int, 0, len(after)), make([]int, 0, len(after))
+		for id, rating := range after {
+			x, y = append(x, before[id]), append(y, rating)
+		}
+		report.History = append(report.History, convergencePoint{
+			Comparisons: snapshotVotes[i],
+			KendallTau:  round3(ranking.KendallTau(x, y)),
+			Spearman:    round3(ranking.Spearman(x, y)),
+			TopKChurn:   round3(ranking.TopKChurn(ranking.Order(before), ranking.Order(after), topK)),
+		})
+	}
+	return report, nil
+}
+
+// autoClose closes the project once its top K has held for the configured window of votes
+func (ac *ApplicantController) autoClose(ctx context.Context, project *models.Project) {
+	if project.AutoClose == nil || project.Closed {
+		return
+	}
+	settings := project.AutoClose
+	report, err := ac.convergence(ctx, project, settings.Window, settings.TopK, 0)
+	if err != nil {
+		log.Println("Auto-close convergence error:", err)
+		return
+	}
+	if !report.Stable {
+		return
+	}
+
+	now := time.Now()
+	result, err := ac.projects.UpdateOne(ctx,
+		bson.M{"_id": project.ID, "currentStage": project.CurrentStage, "closed": bson.M{"$ne": true}},
+		bson.M{"$set": bson.M{"closed": true, "closedAt": now}})
+	if err != nil {
+		log.Println("MongoDB auto-close project error:", err)
+		return
+	}
+	if result.ModifiedCount == 0 {
+		// closed or cut meanwhile
+		return
+	}
+	project.Closed, project.ClosedAt = true, &now
+	publishProjectStatus(project, "auto-closed")
+	log.Printf("Closed project %s: top %d unchanged for %d votes", project.ID.Hex(), settings.TopK, report.StableFor)
+}
+
+func round3(v float64) float64 {
+	return math.Round(v*1000) / 1000
+}
@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"backend/models"
+	"backend/preview"
+	"backend/scan"
+	"backend/storage"
+)
+
+// attachDerivedFiles generates the display copies of a stored file and links them from it:
+// a thumbnail for images and a first-page preview for PDFs. Failures are logged and the
+// original is served instead. The preview workers call it, so intake never waits on it.
+func attachDerivedFiles(ctx context.Context, file *models.FileInfo) {
+	if !needsPreview(file) {
+		return
+	}
+
+	thumbnail := strings.HasPrefix(file.MimeType, "image/")
+	render := func(data []byte) ([]byte, string, error) {
+		rendered, err := preview.PDFFirstPage(ctx, data)
+		return rendered, preview.PreviewMimeType, err
+	}
+	if thumbnail {
+		render = preview.Thumbnail
+	}
+
+	data, err := readStoredFile(ctx, file)
+	if err != nil {
+		log.Printf("Error generating preview of %s: %v", file.FileID, err)
+		return
+	}
+	derived, mimeType, err := render(data)
+	if err != nil {
+		if !errors.Is(err, preview.ErrUnsupported) && !errors.Is(err, preview.ErrUnavailable) {
+			log.Printf("Error generating preview of %s: %v", file.FileID, err)
+		}
+		return
+	}
+
+	suffix := "preview.png"
+	switch {
+	case thumbnail && mimeType == preview.ThumbnailAlphaMimeType:
+		suffix = "thumb.png"
+	case thumbnail:
+		suffix = "thumb.jpg"
+	}
+	derivedInfo, err := storeDerivedFile(ctx, file, suffix, mimeType, derived)
+	if err != nil {
+		log.Printf("Error storing preview of %s: %v", file.FileID, err)
+		return
+	}
+	if thumbnail {
+		file.Thumbnail = derivedInfo
+	} else {
+		file.Preview = derivedInfo
+	}
+}
+
+// needsPreview reports whether file is an image or PDF that has no display copy yet and passed scanning
+func needsPreview(file *models.FileInfo) bool {
+	if file == nil || file.ScanStatus == scan.StatusInfected || file.Thumbnail != nil || file.Preview != nil {
+		return false
+	}
+	return strings.HasPrefix(file.MimeType, "image/") || file.MimeType == "application/pdf"
+}
+
+// storeDerivedFile stores a derived copy of file next to it, in the same backend
+func storeDerivedFile(ctx context.Context, file *models.FileInfo, suffix, mimeType string, data []byte) (*models.FileInfo, error) {
+	store, err := storage.Get(file.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	base := strings.TrimSuffix(file.FileName, filepath.Ext(file.FileName))
+	uniqueName := fmt.Sprintf("%d_%s.%s", time.Now().Unix(), base, suffix)
+	fileID, err := store.Put(ctx, uniqueName, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.FileInfo{
+		FileID:     fileID,
+		FileName:   base + "." + suffix,
+		MimeType:   mimeType,
+		UniqueName: uniqueName,
+		UploadedAt: time.Now(),
+		Storage:    store.Name(),
+	}, nil
+}
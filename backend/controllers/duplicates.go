@@ -0,0 +1,240 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Names at least this similar (1 - edit distance / length) are flagged as possible duplicates
+const fuzzyNameThreshold = 0.85
+
+// duplicateMatch is the result of checking a submission against a project's existing applicants.
+// Exact is a definite match (same email, or same name and phone) and is subject to the project's
+// duplicate policy; Possible holds fuzzy name matches that are only flagged for a reviewer to merge.
+type duplicateMatch struct {
+	Exact    *models.Applicant
+	Possible []primitive.ObjectID
+}
+
+type errDuplicateApplication struct {
+	ExistingID primitive.ObjectID
+}
+
+func (e *errDuplicateApplication) Error() string {
+	return fmt.Sprintf("duplicate application, existing applicant %s", e.ExistingID.Hex())
+}
+
+// emailCollation compares emails case-insensitively; the applicant_email index is built with it.
+// Emails are stored trimmed, so this matches exactly what normaliseEmail does.
+var emailCollation = &options.Collation{Locale: "en", Strength: 2}
+
+// findDuplicates matches sub against the project's applicants. The email is looked up through the
+// applicant_email index; names are compared on a projection of just the name and phone fields, and
+// only an exact match is loaded in full.
+func findDuplicates(ctx context.Context, collection *mongo.Collection, projectID primitive.ObjectID, sub *intakeSubmission) (*duplicateMatch, error) {
+	email := normaliseEmail(sub.Fields["email"])
+	name := normaliseName(sub.Fields["firstName"] + " " + sub.Fields["lastName"])
+	phone := normalisePhone(sub.Fields["phone"])
+
+	match := &duplicateMatch{}
+	if email != "" {
+		var existing models.Applicant
+		err := collection.FindOne(ctx, bson.M{"project_id": projectID, "email": email}, options.FindOne().SetCollation(emailCollation)).Decode(&existing)
+		if err == nil {
+			match.Exact = &existing
+			return match, nil
+		}
+		if err != mongo.ErrNoDocuments {
+			return nil, err
+		}
+	}
+	if name == "" {
+		return match, nil
+	}
+
+	opts := options.Find().SetProjection(bson.M{"firstName": 1, "lastName": 1, "phone": 1})
+	cursor, err := collection.Find(ctx, bson.M{"project_id": projectID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var candidates []models.Applicant
+	if err := cursor.All(ctx, &candidates); err != nil {
+		return nil, err
+	}
+
+	for _, candidate := range candidates {
+		existingName := normaliseName(candidate.FirstName + " " + candidate.LastName)
+		if phone != "" && name == existingName && phone == normalisePhone(candidate.Phone) {
+			var existing models.Applicant
+			if err := collection.FindOne(ctx, bson.M{"_id": candidate.ID}).Decode(&existing); err != nil {
+				return nil, err
+			}
+			match.Exact = &existing
+			match.Possible = nil
+			return match, nil
+		}
+		if existingName != "" && nameSimilarity(name, existingName) >= fuzzyNameThreshold {
+			match.Possible = append(match.Possible, candidate.ID)
+		}
+	}
+	return match, nil
+}
+
+func normaliseEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// normaliseName lowercases and keeps only letters, with single spaces between words
+func normaliseName(name string) string {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	return strings.Join(words, " ")
+}
+
+// normalisePhone keeps the digits and drops a leading US country code
+func normalisePhone(phone string) string {
+	var digits strings.Builder
+	for _, r := range phone {
+		if unicode.IsDigit(r) {
+			digits.WriteRune(r)
+		}
+	}
+	d := digits.String()
+	if len(d) == 11 && strings.HasPrefix(d, "1") {
+		d = d[1:]
+	}
+	return d
+}
+
+func nameSimilarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+// mergeApplicants folds duplicate into primary and returns the files that are no longer referenced.
+// Primary's answers win, blanks are filled from duplicate, and the newer upload of each file is kept.
+// Results are combined: wins and losses add up, the rating is averaged by games played and
+// opponents are unioned, dropping any comparison the two records had against each other.
+func mergeApplicants(primary, duplicate *models.Applicant) (*models.Applicant, []*models.FileInfo) {
+	merged := *primary
+
+	fill := func(dst *string, src string) {
+		if *dst == "" {
+			*dst = src
+		}
+	}
+	fill(&merged.FirstName, duplicate.FirstName)
+	fill(&merged.LastName, duplicate.LastName)
+	fill(&merged.Major, duplicate.Major)
+	fill(&merged.Year, duplicate.Year)
+	fill(&merged.Email, duplicate.Email)
+	fill(&merged.Phone, duplicate.Phone)
+	fill(&merged.Source, duplicate.Source)
+	if submittedAfter(duplicate.Timestamp, primary.Timestamp) {
+		merged.Timestamp = duplicate.Timestamp
+	}
+
+	answered := map[string]bool{}
+	for _, resp := range merged.Responses {
+		answered[resp.Question] = true
+	}
+	for _, resp := range duplicate.Responses {
+		if !answered[resp.Question] {
+			merged.Responses = append(merged.Responses, resp)
+		}
+	}
+
+	var superseded []*models.FileInfo
+	newer := func(a, b *models.FileInfo) *models.FileInfo {
+		switch {
+		case a == nil:
+			return b
+		case b == nil:
+			return a
+		case b.UploadedAt.After(a.UploadedAt):
+			superseded = append(superseded, a)
+			return b
+		default:
+			superseded = append(superseded, b)
+			return a
+		}
+	}
+	merged.Resume = newer(primary.Resume, duplicate.Resume)
+	merged.CoverLetter = newer(primary.CoverLetter, duplicate.CoverLetter)
+	merged.Image = newer(primary.Image, duplicate.Image)
+	if merged.Resume == duplicate.Resume {
+		merged.ResumeText = duplicate.ResumeText
+	}
+	if merged.CoverLetter == duplicate.CoverLetter {
+		merged.CoverLetterText = duplicate.CoverLetterText
+	}
+
+	primaryGames := primary.Wins + primary.Losses
+	duplicateGames := duplicate.Wins + duplicate.Losses
+	if primaryGames+duplicateGames > 0 {
+		merged.Elo = (primary.Elo*primaryGames + duplicate.Elo*duplicateGames) / (primaryGames + duplicateGames)
+	}
+	merged.Wins = primary.Wins + duplicate.Wins
+	merged.Losses = primary.Losses + duplicate.Losses
+
+	merged.MatchesPlayed = unionIDs(primary.MatchesPlayed, duplicate.MatchesPlayed, primary.ID, duplicate.ID)
+	merged.PossibleDuplicates = unionIDs(primary.PossibleDuplicates, duplicate.PossibleDuplicates, primary.ID, duplicate.ID)
+
+	return &merged, superseded
+}
+
+func unionIDs(a, b []primitive.ObjectID, exclude ...primitive.ObjectID) []primitive.ObjectID {
+	seen := map[primitive.ObjectID]bool{}
+	for _, id := range exclude {
+		seen[id] = true
+	}
+	result := []primitive.ObjectID{}
+	for _, list := range [][]primitive.ObjectID{a, b} {
+		for _, id := range list {
+			if !seen[id] {
+				seen[id] = true
+				result = append(result, id)
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,171 @@
+package controllers
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"", "abc", 3},
+		{"abc", "", 3},
+		{"abc", "abc", 0},
+		{"kitten", "sitting", 3},
+		{"flaw", "lawn", 2},
+		{"jon", "john", 1},
+		{"ab", "ba", 2},
+		{"zoë", "zoe", 1},
+		{"josé", "jose", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+"/"+tt.b, func(t *testing.T) {
+			if got := levenshtein([]rune(tt.a), []rune(tt.b)); got != tt.want {
+				t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+			}
+			if got := levenshtein([]rune(tt.b), []rune(tt.a)); got != tt.want {
+				t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.b, tt.a, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNameSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b    string
+		want    float64
+		similar bool
+	}{
+		{"", "", 1, true},
+		{"ada lovelace", "ada lovelace", 1, true},
+		{"jon smith", "john smith", 0.9, true},
+		{"katherine johnson", "catherine johnson", 1 - 1.0/17, true},
+		{"ada lovelace", "grace hopper", 1 - 11.0/12, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+"/"+tt.b, func(t *testing.T) {
+			got := nameSimilarity(tt.a, tt.b)
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("nameSimilarity(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+			if similar := got >= fuzzyNameThreshold; similar != tt.similar {
+				t.Errorf("nameSimilarity(%q, %q) = %v, flagged as possible duplicate = %v", tt.a, tt.b, got, similar)
+			}
+		})
+	}
+}
+
+func TestNormalise(t *testing.T) {
+	tests := []struct {
+		name, fn, in, want string
+	}{
+		{"email case and space", "email", "  Ada@Example.COM ", "ada@example.com"},
+		{"name punctuation", "name", "  O'Brien-Smith,  Mary ", "o brien smith mary"},
+		{"name unicode", "name", "Zoë  Ångström", "zoë ångström"},
+		{"phone formatting", "phone", "(555) 123-4567", "5551234567"},
+		{"phone country code", "phone", "+1 555 123 4567", "5551234567"},
+		{"phone other country", "phone", "+44 20 7946 0958", "442079460958"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			switch tt.fn {
+			case "email":
+				got = normaliseEmail(tt.in)
+			case "name":
+				got = normaliseName(tt.in)
+			case "phone":
+				got = normalisePhone(tt.in)
+			}
+			if got != tt.want {
+				t.Errorf("normalise %s(%q) = %q, want %q", tt.fn, tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindDuplicates(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	projectID := primitive.NewObjectID()
+	ada := models.Applicant{ID: primitive.NewObjectID(), ProjectID: projectID, FirstName: "Ada", LastName: "Lovelace", Email: "Ada@Example.com", Phone: "(555) 010-9999"}
+	adda := models.Applicant{ID: primitive.NewObjectID(), FirstName: "Adda", LastName: "Lovelace"}
+	grace := models.Applicant{ID: primitive.NewObjectID(), FirstName: "Grace", LastName: "Hopper"}
+
+	submission := func(fields map[string]string) *intakeSubmission {
+		sub := newIntakeSubmission("test", "f1", "")
+		for k, v := range fields {
+			sub.Fields[k] = v
+		}
+		return sub
+	}
+
+	mt.Run("email through the collated index", func(mt *mtest.T) {
+		mt.AddMockResponses(cursorOf(mt, "db.applicants", ada))
+		match, err := findDuplicates(context.Background(), mt.Coll, projectID, submission(map[string]string{"email": " ADA@example.com", "firstName": "Ada"}))
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if match.Exact == nil || match.Exact.ID != ada.ID {
+			mt.Fatalf("exact = %+v, want ada", match.Exact)
+		}
+
+		events := mt.GetAllStartedEvents()
+		if len(events) != 1 {
+			mt.Fatalf("commands = %v, want a single lookup", commandNames(mt))
+		}
+		cmd := events[0].Command
+		if email := cmd.Lookup("filter", "email").StringValue(); email != "ada@example.com" {
+			mt.Errorf("filter email = %q", email)
+		}
+		if strength := cmd.Lookup("collation", "strength").Int32(); strength != 2 {
+			mt.Errorf("collation strength = %d, want 2 to match the applicant_email index", strength)
+		}
+	})
+
+	mt.Run("same name and phone", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			cursorOf(mt, "db.applicants"),
+			cursorOf(mt, "db.applicants", adda, bson.M{"_id": ada.ID, "firstName": "Ada", "lastName": "Lovelace", "phone": ada.Phone}),
+			cursorOf(mt, "db.applicants", ada),
+		)
+		match, err := findDuplicates(context.Background(), mt.Coll, projectID, submission(map[string]string{
+			"email": "ada@elsewhere.org", "firstName": "ada", "lastName": "LOVELACE", "phone": "+1 555 010 9999",
+		}))
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if match.Exact == nil || match.Exact.Email != ada.Email {
+			mt.Fatalf("exact = %+v, want ada loaded in full", match.Exact)
+		}
+
+		scan := mt.GetAllStartedEvents()[1].Command
+		projection := scan.Lookup("projection").Document()
+		if elements, _ := projection.Elements(); len(elements) != 3 || projection.Lookup("phone").IsZero() {
+			mt.Errorf("name scan projection = %v, want only name and phone", projection)
+		}
+	})
+
+	mt.Run("similar names are possible duplicates", func(mt *mtest.T) {
+		mt.AddMockResponses(cursorOf(mt, "db.applicants", adda, grace))
+		match, err := findDuplicates(context.Background(), mt.Coll, projectID, submission(map[string]string{"firstName": "Ada", "lastName": "Lovelace"}))
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if match.Exact != nil {
+			mt.Fatalf("exact = %+v, want none", match.Exact)
+		}
+		if len(match.Possible) != 1 || match.Possible[0] != adda.ID {
+			mt.Fatalf("possible = %v, want only adda", match.Possible)
+		}
+	})
+}
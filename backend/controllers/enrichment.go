@@ -0,0 +1,188 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"backend/db"
+	"backend/enrich"
+	"backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// applications enriched at once; model calls are slow and often rate limited
+	enrichmentWorkers = 2
+	// how often idle workers look for applications waiting to be enriched
+	enrichmentPollInterval = 5 * time.Second
+	// time to run every enricher on one application; a claimed application is tried again after
+	// this if its worker never finishes it
+	enrichmentTimeout = 5 * time.Minute
+)
+
+// enrichmentWake tells an idle worker there is work before its next poll
+var enrichmentWake = make(chan struct{}, 1)
+
+// queueEnrichment marks the applicant's enrichments pending and due now. The workers find it in the
+// collection, so nothing queued is lost however far behind they are or if the server restarts.
+// Intake calls it for every new or resubmitted application; it returns without waiting for the result.
+func queueEnrichment(ctx context.Context, collection *mongo.Collection, applicantID primitive.ObjectID) error {
+	enrichers := enrich.Enrichers()
+	if len(enrichers) == 0 {
+		return nil
+	}
+	now := time.Now()
+	set := bson.M{"enrichmentDueAt": now}
+	for _, e := range enrichers {
+		set["enrichments."+e.Name()] = models.Enrichment{Status: models.EnrichmentPending, UpdatedAt: now}
+	}
+	if _, err := collection.UpdateOne(ctx, bson.M{"_id": applicantID}, bson.M{"$set": set}); err != nil {
+		return err
+	}
+
+	select {
+	case enrichmentWake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// RunEnrichment starts the enrichment workers, which pick up applications as they are queued and
+// any a restart left pending. It does nothing when no enricher is configured.
+func RunEnrichment() {
+	if len(enrich.Enrichers()) == 0 {
+		return
+	}
+	collection := db.GetCollection("applicants")
+	for i := 0; i < enrichmentWorkers; i++ {
+		go func() {
+			for {
+				if !enrichNext(collection) {
+					select {
+					case <-enrichmentWake:
+					case <-time.After(enrichmentPollInterval):
+					}
+				}
+			}
+		}()
+	}
+}
+
+// enrichNext claims the application due soonest and enriches it. It reports whether there was one.
+func enrichNext(collection *mongo.Collection) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), enrichmentTimeout)
+	defer cancel()
+
+	// claiming pushes the due time past the timeout, so a worker that dies mid-way only delays it
+	now := time.Now()
+	var applicant models.Applicant
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "enrichmentDueAt", Value: 1}}).
+		SetReturnDocument(options.After)
+	err := collection.FindOneAndUpdate(ctx,
+		bson.M{"enrichmentDueAt": bson.M{"$lte": now}},
+		bson.M{"$set": bson.M{"enrichmentDueAt": now.Add(enrichmentTimeout)}},
+		opts).Decode(&applicant)
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			log.Println("MongoDB claim enrichment error:", err)
+		}
+		return false
+	}
+
+	enrichApplicant(ctx, collection, &applicant)
+	return true
+}
+
+// enrichApplicant runs every enricher on a claimed application and stores what each made of it
+func enrichApplicant(ctx context.Context, collection *mongo.Collection, applicant *models.Applicant) {
+	applicantID := applicant.ID
+	for _, e := range enrich.Enrichers() {
+		result, err := e.Enrich(ctx, applicant)
+		result.Status = models.EnrichmentDone
+		if err != nil {
+			result.Status, result.Error = models.EnrichmentFailed, err.Error()
+			log.Printf("Enricher %s failed on applicant %s: %v", e.Name(), applicantID.Hex(), err)
+		}
+		result.UpdatedAt = time.Now()
+		if _, err := collection.UpdateOne(ctx, bson.M{"_id": applicantID}, bson.M{"$set": bson.M{"enrichments." + e.Name(): result}}); err != nil {
+			log.Println("MongoDB Update enrichment error:", err)
+		}
+	}
+
+	// a resubmission while the enrichers ran queued it again with a new due time, which stays
+	filter := bson.M{"_id": applicantID, "enrichmentDueAt": applicant.EnrichmentDueAt}
+	if _, err := collection.UpdateOne(ctx, filter, bson.M{"$unset": bson.M{"enrichmentDueAt": ""}}); err != nil {
+		log.Println("MongoDB Update enrichment error:", err)
+	}
+}
+
+// backgroundCheck is an applicant's enrichments and the enrichers that produce them
+type backgroundCheck struct {
+	ApplicantID primitive.ObjectID           `json:"applicantId"`
+	Enrichers   []string                     `json:"enrichers"`
+	Enrichments map[string]models.Enrichment `json:"enrichments"`
+}
+
+func newBackgroundCheck(applicant *models.Applicant) backgroundCheck {
+	check := backgroundCheck{ApplicantID: applicant.ID, Enrichers: []string{}, Enrichments: applicant.Enrichments}
+	for _, e := range enrich.Enrichers() {
+		check.Enrichers = append(check.Enrichers, e.Name())
+	}
+	if check.Enrichments == nil {
+		check.Enrichments = map[string]models.Enrichment{}
+	}
+	return check
+}
+
+// BackgroundCheck returns what the enrichment pipeline made of an application, such as the summary of
+// its resume and answers. Blind-review projects get it redacted like the rest of the application.
+func (ac *ApplicantController) BackgroundCheck(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	applicant := ac.findProjectApplicant(ctx, w, r)
+	if applicant == nil {
+		return
+	}
+	ac.prepareApplicants(ctx, r, applicant)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newBackgroundCheck(applicant))
+}
+
+// RunBackgroundCheck queues an application for enrichment again, e.g. after a model outage or a change of model
+func (ac *ApplicantController) RunBackgroundCheck(w http.ResponseWriter, r *http.Request) {
+	if len(enrich.Enrichers()) == 0 {
+		writeError(w, http.StatusServiceUnavailable, "Enrichment is not configured, set ENRICH_LLM_URL")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	applicant := ac.findProjectApplicant(ctx, w, r)
+	if applicant == nil {
+		return
+	}
+	if err := queueEnrichment(ctx, ac.collection, applicant.ID); err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to queue enrichment")
+		log.Println("MongoDB Update enrichment error:", err)
+		return
+	}
+	if err := ac.collection.FindOne(ctx, bson.M{"_id": applicant.ID}).Decode(applicant); err != nil {
+		log.Println("MongoDB Find applicant error:", err)
+	}
+	ac.prepareApplicants(ctx, r, applicant)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(newBackgroundCheck(applicant))
+}
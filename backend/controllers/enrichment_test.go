@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"backend/enrich"
+	"backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// setupSummariser configures the summary enricher against a stub model for the test
+func setupSummariser(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"model":"stub","choices":[{"message":{"role":"assistant","content":"A summary."}}]}`))
+	}))
+	t.Cleanup(server.Close)
+	// runs after the environment is restored, turning enrichment back off
+	t.Cleanup(enrich.Setup)
+	t.Setenv("ENRICH_LLM_URL", server.URL)
+	enrich.Setup()
+}
+
+func TestQueueEnrichmentNeverDrops(t *testing.T) {
+	setupSummariser(t)
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("more than the workers take", func(mt *mtest.T) {
+		// no worker is listening, so only the first wake-up fits
+		const queued = 3
+		ids := make([]primitive.ObjectID, queued)
+		for i := range ids {
+			ids[i] = primitive.NewObjectID()
+			mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}))
+			if err := queueEnrichment(context.Background(), mt.Coll, ids[i]); err != nil {
+				mt.Fatalf("queueEnrichment() error = %v", err)
+			}
+		}
+		select {
+		case <-enrichmentWake:
+		default:
+		}
+
+		// every application is left due in the collection for a worker's next poll
+		updates := 0
+		for _, e := range mt.GetAllStartedEvents() {
+			if e.CommandName != "update" {
+				continue
+			}
+			set := e.Command.Lookup("updates", "0", "u", "$set").Document()
+			if _, err := set.LookupErr("enrichmentDueAt"); err != nil {
+				mt.Errorf("update %d doesn't set enrichmentDueAt: %v", updates, set)
+			}
+			if status := set.Lookup("enrichments.summary", "status").StringValue(); status != models.EnrichmentPending {
+				mt.Errorf("summary status = %q, want pending", status)
+			}
+			if id := e.Command.Lookup("updates", "0", "q", "_id").ObjectID(); id != ids[updates] {
+				mt.Errorf("update %d is for %s, want %s", updates, id.Hex(), ids[updates].Hex())
+			}
+			updates++
+		}
+		if updates != queued {
+			mt.Fatalf("%d applications marked due, want %d", updates, queued)
+		}
+	})
+}
+
+func TestEnrichNext(t *testing.T) {
+	setupSummariser(t)
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("claims and clears", func(mt *mtest.T) {
+		claimedUntil := time.Now().Add(enrichmentTimeout).Truncate(time.Millisecond)
+		applicant := models.Applicant{ID: primitive.NewObjectID(), FirstName: "Ada", ResumeText: "Analyst.", EnrichmentDueAt: &claimedUntil}
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(bson.E{Key: "value", Value: toDoc(mt, applicant)}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+		)
+		if !enrichNext(mt.Coll) {
+			mt.Fatal("enrichNext() found nothing to do")
+		}
+
+		var commands []string
+		var claim, result, clear bson.Raw
+		for _, e := range mt.GetAllStartedEvents() {
+			commands = append(commands, e.CommandName)
+			switch {
+			case e.CommandName == "findAndModify":
+				claim = e.Command
+			case e.CommandName == "update" && result == nil:
+				result = e.Command
+			case e.CommandName == "update":
+				clear = e.Command
+			}
+		}
+		if claim == nil || result == nil || clear == nil {
+			mt.Fatalf("commands = %v, want a claim and two updates", commands)
+		}
+		if _, err := claim.LookupErr("query", "enrichmentDueAt", "$lte"); err != nil {
+			mt.Errorf("claim query = %v, want applications already due", claim.Lookup("query"))
+		}
+		summary := result.Lookup("updates", "0", "u", "$set", "enrichments.summary").Document()
+		if summary.Lookup("status").StringValue() != models.EnrichmentDone || summary.Lookup("summary").StringValue() != "A summary." {
+			mt.Errorf("stored enrichment = %v", summary)
+		}
+		// the due time is only cleared if nobody queued the application again meanwhile
+		q := clear.Lookup("updates", "0", "q")
+		if due := q.Document().Lookup("enrichmentDueAt").Time(); !due.Equal(claimedUntil) {
+			mt.Errorf("clear filter enrichmentDueAt = %v, want the claimed %v", due, claimedUntil)
+		}
+		if _, err := clear.LookupErr("updates", "0", "u", "$unset", "enrichmentDueAt"); err != nil {
+			mt.Errorf("clear update = %v", clear.Lookup("updates", "0", "u"))
+		}
+	})
+
+	mt.Run("nothing due", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "value", Value: nil}))
+		if enrichNext(mt.Coll) {
+			mt.Fatal("enrichNext() = true with nothing due")
+		}
+	})
+}
@@ -0,0 +1,203 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"time"
+
+	"backend/db"
+	"backend/fileurl"
+	"backend/middleware"
+	"backend/models"
+	"backend/scan"
+	"backend/storage"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type FileController struct {
+	collection *mongo.Collection
+}
+
+func NewFileController() *FileController {
+	return &FileController{
+		collection: db.GetCollection("applicants"),
+	}
+}
+
+// storedFiles lists every file an applicant references, including thumbnails and previews
+func storedFiles(applicant *models.Applicant) []*models.FileInfo {
+	var files []*models.FileInfo
+	for _, file := range []*models.FileInfo{applicant.Resume, applicant.CoverLetter, applicant.Image} {
+		if file == nil {
+			continue
+		}
+		files = append(files, file)
+		for _, derived := range []*models.FileInfo{file.Thumbnail, file.Preview} {
+			if derived != nil {
+				files = append(files, derived)
+			}
+		}
+	}
+	return files
+}
+
+// withFileURLs points each of the applicant's files at the file endpoint so responses carry
+// links instead of file contents. The links are signed for the requesting reviewer and the
+// applicant's project and expire after fileurl.DefaultTTL unless configured otherwise.
+func withFileURLs(r *http.Request, applicant *models.Applicant) {
+	signFileURLs(applicant, middleware.UserID(r.Context()))
+}
+
+// signFileURLs gives applicant's files links that reviewerID can open
+func signFileURLs(applicant *models.Applicant, reviewerID string) {
+	now := time.Now()
+	for _, file := range storedFiles(applicant) {
+		file.URL = fileurl.Sign("/api/files/"+file.FileID, file.FileID, applicant.ProjectID.Hex(), reviewerID, now)
+	}
+}
+
+// findFileOwner returns the applicant that references fileID along with that file's info
+func findFileOwner(ctx context.Context, collection *mongo.Collection, fileID string) (*models.Applicant, *models.FileInfo, error) {
+	var applicant models.Applicant
+	err := collection.FindOne(ctx, bson.M{"$or": []bson.M{
+		{"resume.fileId": fileID},
+		{"coverLetter.fileId": fileID},
+		{"image.fileId": fileID},
+		{"image.thumbnail.fileId": fileID},
+		{"resume.preview.fileId": fileID},
+		{"coverLetter.preview.fileId": fileID},
+	}}).Decode(&applicant)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, file := range storedFiles(&applicant) {
+		if file.FileID == fileID {
+			return &applicant, file, nil
+		}
+	}
+	return nil, nil, mongo.ErrNoDocuments
+}
+
+// Serve streams a file from the blob store. Only signed URLs handed out by withFileURLs are accepted:
+// the signature must be valid and unexpired, the file must belong to the signed project, and the
+// signed-in caller must be the reviewer the URL was issued to. Files the scanner flagged are refused.
+// http.ServeContent takes care of Content-Length, Range / If-Range requests and If-None-Match
+// against the ETag. Files are immutable once stored, so the file id itself is a strong ETag.
+// Only PDFs and raster images are shown inline; anything else is a sandboxed download, so an
+// uploaded HTML or SVG file can never run script on our origin.
+func (fc *FileController) Serve(w http.ResponseWriter, r *http.Request) {
+	fileIDStr := chi.URLParam(r, "fileId")
+	if fileIDStr == "" {
+		http.Error(w, "File ID required", http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	grant, err := fileurl.Verify(fileIDStr, r.URL.Query(), now)
+	if err != nil {
+		log.Printf("Rejected file request for %s from %s: %v", fileIDStr, r.RemoteAddr, err)
+		http.Error(w, "Forbidden: "+err.Error(), http.StatusForbidden)
+		return
+	}
+	if grant.ReviewerID == "" || middleware.UserID(r.Context()) != grant.ReviewerID {
+		http.Error(w, "Forbidden: url was issued to another reviewer", http.StatusForbidden)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	owner, fileInfo, err := findFileOwner(ctx, fc.collection, fileIDStr)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			http.Error(w, "File not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to fetch file", http.StatusInternalServerError)
+		log.Println("MongoDB Find file owner error:", err)
+		return
+	}
+	if owner.ProjectID.Hex() != grant.ProjectID {
+		http.Error(w, "Forbidden: file is not part of this project", http.StatusForbidden)
+		return
+	}
+
+	if fileInfo.ScanStatus == scan.StatusInfected {
+		log.Printf("Refused to serve infected file %s (%s) to %s", fileIDStr, fileInfo.ScanSignature, r.RemoteAddr)
+		http.Error(w, "File failed malware scan", http.StatusForbidden)
+		return
+	}
+
+	store, err := storage.Get(fileInfo.Storage)
+	if err != nil {
+		http.Error(w, "Failed to open file", http.StatusInternalServerError)
+		log.Println(err)
+		return
+	}
+
+	// the read outlives the lookup timeout, so it is bound to the request instead
+	content, err := store.Open(r.Context(), fileInfo.FileID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			http.Error(w, "File not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to open file", http.StatusInternalServerError)
+		log.Println("Storage open file error:", err)
+		return
+	}
+	defer content.Close()
+
+	disposition := "inline"
+	if !inlineTypes[fileInfo.MimeType] {
+		disposition = "attachment"
+		w.Header().Set("Content-Security-Policy", "sandbox")
+	} else if r.URL.Query().Get("download") != "" {
+		disposition = "attachment"
+	}
+
+	contentType := fileInfo.MimeType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": fileInfo.FileName}))
+	w.Header().Set("ETag", `"`+fileIDStr+`"`)
+	// resumes and headshots are personal data, keep them out of shared caches, and no longer than the URL is valid
+	w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", cacheMaxAge(grant.Expires, now)))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	http.ServeContent(w, r, fileInfo.FileName, fileInfo.UploadedAt, content)
+}
+
+// Content types browsers may render inline; none of them can run script
+var inlineTypes = map[string]bool{
+	"application/pdf": true,
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/gif":       true,
+	"image/webp":      true,
+}
+
+// Files are cached for at most this long
+const maxFileCacheAge = time.Hour
+
+// cacheMaxAge is how many seconds a file may be cached: the signed URL's remaining lifetime, capped at maxFileCacheAge
+func cacheMaxAge(expires, now time.Time) int {
+	remaining := expires.Sub(now)
+	if remaining > maxFileCacheAge {
+		remaining = maxFileCacheAge
+	}
+	if remaining < 0 {
+		return 0
+	}
+	return int(remaining / time.Second)
+}
@@ -0,0 +1,263 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/fileurl"
+	"backend/middleware"
+	"backend/models"
+	"backend/storage"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// serveFile requests a signed file URL as userID ("" for no signed-in user)
+func serveFile(fc *FileController, fileID, signedURL, userID string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, signedURL, nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("fileId", fileID)
+	ctx := context.WithValue(r.Context(), chi.RouteCtxKey, rctx)
+	if userID != "" {
+		ctx = middleware.WithUserID(ctx, userID)
+	}
+	w := httptest.NewRecorder()
+	fc.Serve(w, r.WithContext(ctx))
+	return w
+}
+
+func TestServeScopesURLsToReviewer(t *testing.T) {
+	fileurl.Init("test-key", time.Minute)
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("reviewers", func(mt *mtest.T) {
+		setupLocalStorage(t, mt)
+		store := storage.Default()
+		fileID, err := store.Put(context.Background(), "resume.txt", strings.NewReader("resume"))
+		if err != nil {
+			mt.Fatal(err)
+		}
+		projectID := primitive.NewObjectID()
+		owner := bson.D{
+			{Key: "_id", Value: primitive.NewObjectID()},
+			{Key: "project_id", Value: projectID},
+			{Key: "resume", Value: bson.D{
+				{Key: "fileId", Value: fileID}, {Key: "fileName", Value: "resume.txt"},
+				{Key: "mimeType", Value: "text/plain"}, {Key: "storage", Value: storage.Local},
+			}},
+		}
+		fc := &FileController{collection: mt.Coll}
+		signed := fileurl.Sign("/api/files/"+fileID, fileID, projectID.Hex(), "user_alice", time.Now())
+
+		tests := []struct {
+			name   string
+			url    string
+			userID string
+			status int
+		}{
+			{"issued reviewer", signed, "user_alice", http.StatusOK},
+			{"another reviewer", signed, "user_bob", http.StatusForbidden},
+			{"not signed in", signed, "", http.StatusForbidden},
+			{"reviewer swapped in the query", strings.Replace(signed, "r=user_alice", "r=user_bob", 1), "user_bob", http.StatusForbidden},
+			{"issued to nobody", fileurl.Sign("/api/files/"+fileID, fileID, projectID.Hex(), "", time.Now()), "user_alice", http.StatusForbidden},
+			{"unsigned", "/api/files/" + fileID, "user_alice", http.StatusForbidden},
+		}
+		for _, tt := range tests {
+			mt.Run(tt.name, func(mt *mtest.T) {
+				fc.collection = mt.Coll
+				if tt.status == http.StatusOK {
+					mt.AddMockResponses(mtest.CreateCursorResponse(0, "db.applicants", mtest.FirstBatch, owner))
+				}
+				w := serveFile(fc, fileID, tt.url, tt.userID)
+				if w.Code != tt.status {
+					mt.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+				}
+				if tt.status == http.StatusOK {
+					if body, _ := io.ReadAll(w.Body); string(body) != "resume" {
+						mt.Fatalf("body = %q", body)
+					}
+				}
+			})
+		}
+	})
+}
+
+func TestSignFileURLs(t *testing.T) {
+	fileurl.Init("test-key", time.Minute)
+	thumbnail := &models.FileInfo{FileID: "thumb"}
+	applicant := &models.Applicant{
+		ProjectID: primitive.NewObjectID(),
+		Resume:    &models.FileInfo{FileID: "resume"},
+		Image:     &models.FileInfo{FileID: "image", Thumbnail: thumbnail},
+	}
+	signFileURLs(applicant, "user_alice")
+
+	for _, file := range []*models.FileInfo{applicant.Resume, applicant.Image, thumbnail} {
+		u, err := url.Parse(file.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.Path != "/api/files/"+file.FileID {
+			t.Errorf("url path = %q", u.Path)
+		}
+		grant, err := fileurl.Verify(file.FileID, u.Query(), time.Now())
+		if err != nil {
+			t.Fatalf("Verify(%s) error = %v", file.FileID, err)
+		}
+		if grant.ReviewerID != "user_alice" || grant.ProjectID != applicant.ProjectID.Hex() {
+			t.Errorf("grant = %+v", grant)
+		}
+	}
+}
+
+// storedFileOwner stores content in the local store and returns the applicant document referencing it as its resume
+func storedFileOwner(mt *mtest.T, projectID primitive.ObjectID, fileName, mimeType, content string) (string, bson.D) {
+	fileID, err := storage.Default().Put(context.Background(), fileName, strings.NewReader(content))
+	if err != nil {
+		mt.Fatal(err)
+	}
+	return fileID, bson.D{
+		{Key: "_id", Value: primitive.NewObjectID()},
+		{Key: "project_id", Value: projectID},
+		{Key: "resume", Value: bson.D{
+			{Key: "fileId", Value: fileID}, {Key: "fileName", Value: fileName},
+			{Key: "mimeType", Value: mimeType}, {Key: "storage", Value: storage.Local},
+		}},
+	}
+}
+
+func TestServeOnlyRendersSafeTypesInline(t *testing.T) {
+	fileurl.Init("test-key", time.Minute)
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	projectID := primitive.NewObjectID()
+
+	tests := []struct {
+		fileName, mimeType string
+		query              string
+		wantDisposition    string
+		wantSandbox        bool
+	}{
+		{"resume.pdf", "application/pdf", "", "inline", false},
+		{"headshot.png", "image/png", "", "inline", false},
+		{"headshot.png", "image/png", "&download=1", "attachment", false},
+		{"resume.html", "text/html", "", "attachment", true},
+		{"logo.svg", "image/svg+xml", "", "attachment", true},
+		{"notes.txt", "text/plain", "", "attachment", true},
+		{"unknown", "", "", "attachment", true},
+	}
+	for _, tt := range tests {
+		mt.Run(tt.fileName+tt.query, func(mt *mtest.T) {
+			setupLocalStorage(t, mt)
+			fileID, owner := storedFileOwner(mt, projectID, tt.fileName, tt.mimeType, "<script>alert(1)</script>")
+			mt.AddMockResponses(mtest.CreateCursorResponse(0, "db.applicants", mtest.FirstBatch, owner))
+
+			signed := fileurl.Sign("/api/files/"+fileID, fileID, projectID.Hex(), "user_alice", time.Now())
+			w := serveFile(&FileController{collection: mt.Coll}, fileID, signed+tt.query, "user_alice")
+			if w.Code != http.StatusOK {
+				mt.Fatalf("status = %d: %s", w.Code, w.Body)
+			}
+			if got := w.Header().Get("Content-Disposition"); !strings.HasPrefix(got, tt.wantDisposition+";") {
+				mt.Errorf("Content-Disposition = %q, want %s", got, tt.wantDisposition)
+			}
+			if sandboxed := w.Header().Get("Content-Security-Policy") == "sandbox"; sandboxed != tt.wantSandbox {
+				mt.Errorf("sandboxed = %t, want %t", sandboxed, tt.wantSandbox)
+			}
+			if w.Header().Get("X-Content-Type-Options") != "nosniff" {
+				mt.Error("nosniff not set")
+			}
+		})
+	}
+}
+
+func TestServeRangesAndETag(t *testing.T) {
+	fileurl.Init("test-key", time.Minute)
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	projectID := primitive.NewObjectID()
+	content := "%PDF-1.7 0123456789abcdef"
+
+	request := func(mt *mtest.T, fileID string, header http.Header) *httptest.ResponseRecorder {
+		signed := fileurl.Sign("/api/files/"+fileID, fileID, projectID.Hex(), "user_alice", time.Now())
+		r := httptest.NewRequest(http.MethodGet, signed, nil)
+		r.Header = header
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("fileId", fileID)
+		ctx := middleware.WithUserID(context.WithValue(r.Context(), chi.RouteCtxKey, rctx), "user_alice")
+		w := httptest.NewRecorder()
+		(&FileController{collection: mt.Coll}).Serve(w, r.WithContext(ctx))
+		return w
+	}
+
+	mt.Run("range", func(mt *mtest.T) {
+		setupLocalStorage(t, mt)
+		fileID, owner := storedFileOwner(mt, projectID, "resume.pdf", "application/pdf", content)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "db.applicants", mtest.FirstBatch, owner))
+
+		w := request(mt, fileID, http.Header{"Range": {"bytes=9-14"}})
+		if w.Code != http.StatusPartialContent {
+			mt.Fatalf("status = %d, want 206", w.Code)
+		}
+		if body := w.Body.String(); body != "012345" {
+			mt.Errorf("body = %q, want the requested bytes", body)
+		}
+		if got, want := w.Header().Get("Content-Range"), fmt.Sprintf("bytes 9-14/%d", len(content)); got != want {
+			mt.Errorf("Content-Range = %q, want %q", got, want)
+		}
+		if got := w.Header().Get("ETag"); got != `"`+fileID+`"` {
+			mt.Errorf("ETag = %q, want the quoted file id", got)
+		}
+	})
+
+	mt.Run("if-none-match", func(mt *mtest.T) {
+		setupLocalStorage(t, mt)
+		fileID, owner := storedFileOwner(mt, projectID, "resume.pdf", "application/pdf", content)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "db.applicants", mtest.FirstBatch, owner))
+
+		w := request(mt, fileID, http.Header{"If-None-Match": {`"` + fileID + `"`}})
+		if w.Code != http.StatusNotModified {
+			mt.Fatalf("status = %d, want 304", w.Code)
+		}
+		if w.Body.Len() != 0 {
+			mt.Errorf("304 carried a %d byte body", w.Body.Len())
+		}
+	})
+
+	mt.Run("stale if-range", func(mt *mtest.T) {
+		setupLocalStorage(t, mt)
+		fileID, owner := storedFileOwner(mt, projectID, "resume.pdf", "application/pdf", content)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "db.applicants", mtest.FirstBatch, owner))
+
+		w := request(mt, fileID, http.Header{"Range": {"bytes=0-3"}, "If-Range": {`"some-other-file"`}})
+		if w.Code != http.StatusOK || w.Body.String() != content {
+			mt.Fatalf("status = %d body = %q, want the whole file", w.Code, w.Body)
+		}
+	})
+}
+
+func TestCacheMaxAge(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	tests := []struct {
+		expires time.Time
+		want    int
+	}{
+		{now.Add(24 * time.Hour), 3600},
+		{now.Add(time.Hour), 3600},
+		{now.Add(90 * time.Second), 90},
+		{now.Add(500 * time.Millisecond), 0},
+		{now.Add(-time.Minute), 0},
+	}
+	for _, tt := range tests {
+		if got := cacheMaxAge(tt.expires, now); got != tt.want {
+			t.Errorf("cacheMaxAge(now+%v) = %d, want %d", tt.expires.Sub(now), got, tt.want)
+		}
+	}
+}
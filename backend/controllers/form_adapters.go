@@ -0,0 +1,131 @@
+package controllers
+
+import (
+	"encoding/base64"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"backend/models"
+)
+
+// normaliseTypeform maps a Typeform form_response onto an intake submission. Answers are matched
+// to applicant fields by the field ref when it is meaningful, otherwise by the question title.
+func normaliseTypeform(formId string, resp models.TypeformResponse) *intakeSubmission {
+	sub := newIntakeSubmission("typeform", formId, resp.SubmittedAt)
+
+	titles := make(map[string]string, len(resp.Definition.Fields))
+	for _, field := range resp.Definition.Fields {
+		titles[field.ID] = field.Title
+	}
+
+	for _, answer := range resp.Answers {
+		question := titles[answer.Field.ID]
+		if canonicalField(answer.Field.Ref) != "" || question == "" {
+			question = answer.Field.Ref
+		}
+
+		if answer.Type == "file_url" {
+			if field := canonicalField(question); isFileField(field) {
+				sub.Files[field] = &intakeFile{
+					FileName: path.Base(answer.FileURL),
+					URL:      answer.FileURL,
+				}
+				continue
+			}
+		}
+
+		sub.addAnswer(question, typeformAnswerValue(answer))
+	}
+	return sub
+}
+
+func typeformAnswerValue(answer models.TypeformAnswer) string {
+	switch answer.Type {
+	case "text":
+		return answer.Text
+	case "email":
+		return answer.Email
+	case "phone_number":
+		return answer.PhoneNumber
+	case "url":
+		return answer.URL
+	case "file_url":
+		return answer.FileURL
+	case "date":
+		return answer.Date
+	case "number":
+		if answer.Number != nil {
+			return strconv.FormatFloat(*answer.Number, 'f', -1, 64)
+		}
+	case "boolean":
+		if answer.Boolean != nil {
+			return strconv.FormatBool(*answer.Boolean)
+		}
+	case "choice":
+		if answer.Choice != nil {
+			if answer.Choice.Label != "" {
+				return answer.Choice.Label
+			}
+			return answer.Choice.Other
+		}
+	case "choices":
+		if answer.Choices != nil {
+			labels := answer.Choices.Labels
+			if answer.Choices.Other != "" {
+				labels = append(labels, answer.Choices.Other)
+			}
+			return strings.Join(labels, ", ")
+		}
+	}
+	return ""
+}
+
+// normaliseGoogleForms maps the Apps Script item responses onto an intake submission.
+// FILE_UPLOAD items only carry Drive file ids, so the script attaches the file bytes alongside.
+func normaliseGoogleForms(formId string, payload models.GoogleFormsSubmission) (*intakeSubmission, error) {
+	sub := newIntakeSubmission("google-forms", formId, payload.Timestamp)
+
+	for _, item := range payload.ItemResponses {
+		if item.ItemType == "FILE_UPLOAD" {
+			field := canonicalField(item.Title)
+			if !isFileField(field) {
+				sub.Responses = append(sub.Responses, models.Response{Question: item.Title, Answer: item.Response})
+				continue
+			}
+			if len(item.Files) == 0 {
+				return nil, fmt.Errorf("file upload %q has no attached file data", item.Title)
+			}
+
+			file := item.Files[0]
+			data, err := base64.StdEncoding.DecodeString(file.Data)
+			if err != nil {
+				return nil, fmt.Errorf("error decoding file data for %q: %v", item.Title, err)
+			}
+			sub.Files[field] = &intakeFile{
+				FileName:    file.FileName,
+				MimeType:    file.MimeType,
+				DriveFileID: file.DriveFileID,
+				Data:        data,
+			}
+			continue
+		}
+
+		switch answer := item.Response.(type) {
+		case string:
+			sub.addAnswer(item.Title, answer)
+		case []interface{}:
+			values := make([]string, 0, len(answer))
+			for _, v := range answer {
+				values = append(values, fmt.Sprint(v))
+			}
+			sub.addAnswer(item.Title, strings.Join(values, ", "))
+		}
+	}
+
+	if sub.Fields["email"] == "" && payload.RespondentEmail != "" {
+		sub.Fields["email"] = payload.RespondentEmail
+	}
+	return sub, nil
+}
@@ -1,183 +1,385 @@
 package controllers
 
 import (
+	"bytes"
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log"
 	"net/http"
 	"time"
 
 	"backend/db"
 	"backend/models"
+	"backend/webhook"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/gridfs"
 )
 
 type FormResponseController struct {
-	collection *mongo.Collection
+	collection  *mongo.Collection
+	projects    *mongo.Collection
+	quarantined *mongo.Collection
+	replays     *replayStore
+	quarantines *quarantineLimiter
 }
 
 func NewFormResponseController() *FormResponseController {
 	return &FormResponseController{
-		collection: db.GetCollection("applicants"),
+		collection:  db.GetCollection("applicants"),
+		projects:    db.GetCollection("projects"),
+		quarantined: db.GetCollection("quarantine"),
+		replays:     &replayStore{collection: db.GetCollection("webhook_replays")},
+		quarantines: newQuarantineLimiter(),
 	}
 }
 
+var errUnknownForm = errors.New("unknown form")
+
+// resolveProject finds the project a form submission belongs to, either by its ObjectID or by an
+// external form id (Typeform or Google Forms id) registered on the project
+func (fc *FormResponseController) resolveProject(r *http.Request, formId string) (*models.Project, error) {
+	if formId == "" {
+		log.Printf("Rejected form webhook from %s: missing formId", r.RemoteAddr)
+		return nil, errUnknownForm
+	}
+
+	filter := bson.M{"externalFormIds": formId}
+	if projectID, err := primitive.ObjectIDFromHex(formId); err == nil {
+		filter = bson.M{"$or": []bson.M{{"_id": projectID}, {"externalFormIds": formId}}}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var project models.Project
+	if err := fc.projects.FindOne(ctx, filter).Decode(&project); err != nil {
+		log.Printf("Rejected form webhook from %s: no project for form %q: %v", r.RemoteAddr, formId, err)
+		return nil, errUnknownForm
+	}
+	if project.WebhookSecret == "" {
+		log.Printf("Rejected form webhook from %s: project %s has no webhook secret", r.RemoteAddr, formId)
+		return nil, fmt.Errorf("webhook secret not configured for project")
+	}
+	return &project, nil
+}
+
+// rejectWebhook answers a submission that could not be authenticated. Submissions for forms that
+// no project claims are kept in quarantine so they can be assigned by hand, a limited number per sender.
+func (fc *FormResponseController) rejectWebhook(w http.ResponseWriter, r *http.Request, source, formId string, body []byte, err error) {
+	if errors.Is(err, errReplayCheck) {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	if !errors.Is(err, errUnknownForm) {
+		http.Error(w, "Unauthorized webhook: "+err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	if !fc.quarantines.allow(senderHost(r), time.Now()) {
+		log.Printf("Rejected form webhook from %s: quarantine rate limit reached", r.RemoteAddr)
+		http.Error(w, fmt.Sprintf("No project registered for form %q", formId), http.StatusTooManyRequests)
+		return
+	}
+
+	id, qErr := fc.quarantine(r, source, formId, body, fmt.Sprintf("no project registered for form %q", formId))
+	if qErr != nil {
+		log.Println("Error quarantining submission:", qErr)
+		http.Error(w, fmt.Sprintf("No project registered for form %q", formId), http.StatusNotFound)
+		return
+	}
+	http.Error(w, fmt.Sprintf("No project registered for form %q, submission quarantined as %s", formId, id.Hex()), http.StatusNotFound)
+}
+
+// verifySignature resolves the project named by formId, checks the X-Swiperank-Signature header
+// against its secret and claims the delivery's replay key, which it returns. Rejected requests are
+// logged with the reason and the caller should answer them with rejectWebhook.
+func (fc *FormResponseController) verifySignature(r *http.Request, formId string, body []byte) (*models.Project, string, error) {
+	project, err := fc.resolveProject(r, formId)
+	if err != nil {
+		return nil, "", err
+	}
+
+	header := r.Header.Get(webhook.SignatureHeader)
+	now := time.Now()
+	replayKey, err := webhook.VerifyReplayKey(project.WebhookSecret, header, body, now, webhook.DefaultTolerance)
+	if err != nil {
+		log.Printf("Rejected form webhook from %s for project %s: %v", r.RemoteAddr, formId, err)
+		return nil, "", err
+	}
+	// the signed timestamp can drift either way, so a replay could verify for twice the tolerance
+	if err := fc.replays.claim(replayKey, now.Add(2*webhook.DefaultTolerance)); err != nil {
+		log.Printf("Rejected form webhook from %s for project %s: %v", r.RemoteAddr, formId, err)
+		return nil, "", err
+	}
+	return project, replayKey, nil
+}
+
+// Typeform doesn't sign a timestamp, so a captured delivery verifies forever; its event id is
+// remembered for this long instead
+const typeformReplayWindow = 365 * 24 * time.Hour
+
+// verifyTypeformSignature checks Typeform's own Typeform-Signature header and claims the event id,
+// returning the replay key. Typeform does not sign a timestamp, so replays are caught by event id.
+func (fc *FormResponseController) verifyTypeformSignature(r *http.Request, formId, eventID string, body []byte) (*models.Project, string, error) {
+	project, err := fc.resolveProject(r, formId)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err := webhook.VerifyTypeform(project.WebhookSecret, r.Header.Get(webhook.TypeformSignatureHeader), body); err != nil {
+		log.Printf("Rejected Typeform webhook from %s for project %s: %v", r.RemoteAddr, formId, err)
+		return nil, "", err
+	}
+	if eventID == "" {
+		log.Printf("Rejected Typeform webhook from %s for project %s: missing event_id", r.RemoteAddr, formId)
+		return nil, "", errors.New("missing event_id")
+	}
+	replayKey := "typeform:" + eventID
+	if err := fc.replays.claim(replayKey, time.Now().Add(typeformReplayWindow)); err != nil {
+		log.Printf("Rejected Typeform webhook from %s for project %s: %v", r.RemoteAddr, formId, err)
+		return nil, "", err
+	}
+	return project, replayKey, nil
+}
+
+// readWebhookBody reads a JSON webhook body of at most MAX_UPLOAD_REQUEST_BYTES, answering the
+// request itself when it can't
+func readWebhookBody(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	maxRequest := uploadLimit("MAX_UPLOAD_REQUEST_BYTES", defaultMaxRequestBytes)
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequest))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, fmt.Sprintf("Request exceeds %d bytes", maxRequest), http.StatusRequestEntityTooLarge)
+			return nil, false
+		}
+		http.Error(w, "Error reading request body: "+err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	return body, true
+}
+
 func (fc *FormResponseController) HandleFormResponse(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
+	// the raw body is needed to verify the signature before anything is trusted
+	body, ok := readWebhookBody(w, r)
+	if !ok {
+		return
+	}
+
 	var requestData struct {
-		FormId string              `json:"formId"`
+		FormId string `json:"formId"`
 		models.FormResponses
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
+	if err := json.NewDecoder(bytes.NewReader(body)).Decode(&requestData); err != nil {
+		http.Error(w, "Error parsing request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	project, replayKey, err := fc.verifySignature(r, requestData.FormId, body)
+	if err != nil {
+		fc.rejectWebhook(w, r, "form", requestData.FormId, body, err)
+		return
+	}
+
+	sub, err := normaliseFormResponses(requestData.FormId, requestData.FormResponses)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	fc.ingestClaimed(w, project, sub, replayKey)
+}
+
+// HandleTypeform accepts Typeform's native form_response webhook. The project is taken from the
+// formId query parameter on the webhook URL, falling back to the Typeform form id.
+func (fc *FormResponseController) HandleTypeform(w http.ResponseWriter, r *http.Request) {
+	body, ok := readWebhookBody(w, r)
+	if !ok {
+		return
+	}
+
+	var payload models.TypeformWebhook
+	if err := json.Unmarshal(body, &payload); err != nil {
 		http.Error(w, "Error parsing request body: "+err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	projectID, err := primitive.ObjectIDFromHex(requestData.FormId)
+	formId := r.URL.Query().Get("formId")
+	if formId == "" {
+		formId = payload.FormResponse.FormID
+	}
+
+	project, replayKey, err := fc.verifyTypeformSignature(r, formId, payload.EventID, body)
 	if err != nil {
-		projectID = primitive.NewObjectID()
+		fc.rejectWebhook(w, r, "typeform", formId, body, err)
+		return
+	}
+
+	if payload.EventType != "form_response" {
+		// Typeform expects a 2xx for events we don't care about, otherwise it keeps retrying
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	fc.ingestClaimed(w, project, normaliseTypeform(formId, payload.FormResponse), replayKey)
+}
+
+// HandleGoogleForms accepts the payload posted by the Google Forms Apps Script trigger
+func (fc *FormResponseController) HandleGoogleForms(w http.ResponseWriter, r *http.Request) {
+	body, ok := readWebhookBody(w, r)
+	if !ok {
+		return
+	}
+
+	var payload models.GoogleFormsSubmission
+	if err := json.Unmarshal(body, &payload); err != nil {
+		http.Error(w, "Error parsing request body: "+err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	applicant := models.Applicant{
-		ID:            primitive.NewObjectID(),
-		ProjectID:     projectID,
-		Elo:          1000,
-		Wins:         0,
-		Losses:       0,
-		MatchesPlayed: []primitive.ObjectID{},
-		Timestamp:    requestData.Timestamp,
+	formId := r.URL.Query().Get("formId")
+	if formId == "" {
+		formId = payload.FormID
 	}
 
-	bucket, err := gridfs.NewBucket(db.Client.Database("akpsi-ucsb"))
+	project, replayKey, err := fc.verifySignature(r, formId, body)
 	if err != nil {
-		http.Error(w, "Error creating GridFS bucket: "+err.Error(), http.StatusInternalServerError)
+		fc.rejectWebhook(w, r, "google-forms", formId, body, err)
 		return
 	}
 
-	for _, resp := range requestData.Responses {
-		if err := processFormResponse(&applicant, resp, bucket); err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
-			return
-		}
+	sub, err := normaliseGoogleForms(formId, payload)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	fc.ingestClaimed(w, project, sub, replayKey)
+}
+
+// ingestClaimed ingests a submission whose delivery claimed replayKey. When ingest fails the key is
+// released, so the sender's retry of the same delivery isn't mistaken for a replay.
+func (fc *FormResponseController) ingestClaimed(w http.ResponseWriter, project *models.Project, sub *intakeSubmission, replayKey string) {
+	if !fc.respondIngest(w, project, sub) {
+		fc.replays.release(replayKey)
+	}
+}
+
+// respondIngest runs the shared ingestion pipeline and writes the response every adapter returns.
+// It reports whether the submission was ingested.
+func (fc *FormResponseController) respondIngest(w http.ResponseWriter, project *models.Project, sub *intakeSubmission) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	result, err := fc.collection.InsertOne(ctx, applicant)
+	result, err := ingest(ctx, fc.collection, project, sub)
 	if err != nil {
-		http.Error(w, "Error inserting document: "+err.Error(), http.StatusInternalServerError)
-		return
+		var duplicate *errDuplicateApplication
+		if errors.As(err, &duplicate) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return false
+		}
+		var unsupported *errUnsupportedFileType
+		if errors.As(err, &unsupported) {
+			http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+			return false
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return false
+	}
+
+	status, message := http.StatusCreated, "Form response received successfully"
+	switch {
+	case result.Replaced:
+		status, message = http.StatusOK, "Form response replaced existing application"
+	case result.Ignored:
+		status, message = http.StatusOK, "Form response is older than existing application, ignored"
+	}
+	if !result.Ignored {
+		sendIntakeReceipt(project, result.Applicant)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
+	w.WriteHeader(status)
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"message": "Form response received successfully",
-		"id":     result.InsertedID,
+		"message": message,
+		"id":      result.Applicant.ID,
 	})
 
-	prettyJSON, _ := json.MarshalIndent(applicant, "", "    ")
-	fmt.Printf("Application Received:\n%s\n", string(prettyJSON))
+	log.Printf("Application %s received from %s for project %s", result.Applicant.ID.Hex(), sub.Source, project.ID.Hex())
+	return true
 }
 
-func processFormResponse(applicant *models.Applicant, resp models.Response, bucket *gridfs.Bucket) error {
-	switch resp.Question {
-	case "firstName":
-		if strVal, ok := resp.Answer.(string); ok {
-			applicant.FirstName = strVal
-		}
-	case "lastName":
-		if strVal, ok := resp.Answer.(string); ok {
-			applicant.LastName = strVal
-		}
-	case "major":
-		if strVal, ok := resp.Answer.(string); ok {
-			applicant.Major = strVal
-		}
-	case "year":
-		if strVal, ok := resp.Answer.(string); ok {
-			applicant.Year = strVal
+func normaliseFormResponses(formId string, data models.FormResponses) (*intakeSubmission, error) {
+	sub := newIntakeSubmission("form", formId, data.Timestamp)
+	for _, resp := range data.Responses {
+		if err := processFormResponse(sub, resp); err != nil {
+			return nil, err
 		}
+	}
+	return sub, nil
+}
+
+func processFormResponse(sub *intakeSubmission, resp models.Response) error {
+	switch resp.Question {
 	case "coverLetter", "resume", "image":
-		if err := processFileUpload(applicant, resp, bucket); err != nil {
-			return err
+		return processFileUpload(sub, resp)
+	default:
+		if strVal, ok := resp.Answer.(string); ok {
+			sub.addAnswer(resp.Question, strVal)
 		}
 	}
 	return nil
 }
 
-func processFileUpload(applicant *models.Applicant, resp models.Response, bucket *gridfs.Bucket) error {
+func processFileUpload(sub *intakeSubmission, resp models.Response) error {
 	answer, ok := resp.Answer.(map[string]interface{})
 	if !ok || answer["type"] != "file" {
 		return fmt.Errorf("invalid file data format")
 	}
 
-	fileInfo, err := uploadFile(answer, bucket)
+	file, err := decodeFileAnswer(answer)
 	if err != nil {
 		return err
 	}
 
-	switch resp.Question {
-	case "coverLetter":
-		applicant.CoverLetter = fileInfo
-	case "resume":
-		applicant.Resume = fileInfo
-	case "image":
-		applicant.Image = fileInfo
-	}
+	sub.Files[resp.Question] = file
 	return nil
 }
 
-func uploadFile(answer map[string]interface{}, bucket *gridfs.Bucket) (*models.FileInfo, error) {
+func decodeFileAnswer(answer map[string]interface{}) (*intakeFile, error) {
 	dataStr, ok := answer["data"].(string)
 	if !ok {
 		return nil, fmt.Errorf("invalid file data format")
 	}
+	fileName, ok := answer["filename"].(string)
+	if !ok || fileName == "" {
+		return nil, fmt.Errorf("file is missing a filename")
+	}
 
 	fileData, err := base64.StdEncoding.DecodeString(dataStr)
 	if err != nil {
 		return nil, fmt.Errorf("error decoding file data: %v", err)
 	}
 
-	timestamp := time.Now().Unix()
-	uniqueFileName := fmt.Sprintf("%d_%s", timestamp, answer["filename"].(string))
-
-	fileID, err := uploadToGridFS(bucket, uniqueFileName, fileData)
-	if err != nil {
-		return nil, err
+	file := &intakeFile{
+		FileName: fileName,
+		Data:     fileData,
 	}
-
-	return &models.FileInfo{
-		FileID:      fileID.Hex(),
-		FileName:    answer["filename"].(string),
-		MimeType:    answer["mimeType"].(string),
-		DriveFileID: answer["fileId"].([]interface{})[0].(string),
-		UniqueName:  uniqueFileName,
-		UploadedAt:  time.Now(),
-	}, nil
-}
-
-func uploadToGridFS(bucket *gridfs.Bucket, filename string, data []byte) (primitive.ObjectID, error) {
-	uploadStream, err := bucket.OpenUploadStream(filename)
-	if err != nil {
-		return primitive.NilObjectID, fmt.Errorf("error opening upload stream: %v", err)
+	file.MimeType, _ = answer["mimeType"].(string)
+	if ids, ok := answer["fileId"].([]interface{}); ok && len(ids) > 0 {
+		file.DriveFileID, _ = ids[0].(string)
 	}
-	defer uploadStream.Close()
-
-	_, err = uploadStream.Write(data)
-	if err != nil {
-		return primitive.NilObjectID, fmt.Errorf("error writing to stream: %v", err)
-	}
-
-	return uploadStream.FileID.(primitive.ObjectID), nil
-}
\ No newline at end of file
+	return file, nil
+}
@@ -0,0 +1,29 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFormWebhooksRejectOversizedBodies(t *testing.T) {
+	t.Setenv("MAX_UPLOAD_REQUEST_BYTES", "64")
+	fc := &FormResponseController{}
+
+	for name, handler := range map[string]http.HandlerFunc{
+		"form":         fc.HandleFormResponse,
+		"typeform":     fc.HandleTypeform,
+		"google-forms": fc.HandleGoogleForms,
+	} {
+		t.Run(name, func(t *testing.T) {
+			body := `{"formId":"f1","responses":"` + strings.Repeat("x", 128) + `"}`
+			r := httptest.NewRequest(http.MethodPost, "/api/formResponseListener", strings.NewReader(body))
+			w := httptest.NewRecorder()
+			handler(w, r)
+			if w.Code != http.StatusRequestEntityTooLarge {
+				t.Fatalf("status = %d, want 413", w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package controllers
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func toDoc(t testing.TB, v interface{}) bson.D {
+	data, err := bson.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc bson.D
+	if err := bson.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func cursorOf(t testing.TB, ns string, values ...interface{}) bson.D {
+	docs := make([]bson.D, len(values))
+	for i, v := range values {
+		docs[i] = toDoc(t, v)
+	}
+	return mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, docs...)
+}
+
+// insertedDocument returns the first document mt's client inserted
+func insertedDocument(mt *mtest.T) bson.Raw {
+	for _, event := range mt.GetAllStartedEvents() {
+		if event.CommandName == "insert" {
+			return event.Command.Lookup("documents", "0").Document()
+		}
+	}
+	mt.Fatal("nothing was inserted")
+	return nil
+}
@@ -0,0 +1,539 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"backend/events"
+	"backend/extract"
+	"backend/models"
+	"backend/scan"
+	"backend/storage"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Every form adapter (bespoke JSON, Typeform, Google Forms) normalises its payload into an
+// intakeSubmission and hands it to ingest, so applicants look the same whatever form they came from.
+
+type intakeSubmission struct {
+	Source    string
+	FormID    string
+	Timestamp string
+	Fields    map[string]string
+	Files     map[string]*intakeFile
+	Responses []models.Response
+}
+
+type intakeFile struct {
+	FileName    string
+	MimeType    string
+	DriveFileID string
+	Data        []byte
+	// URL is downloaded when Data is empty (Typeform only sends file links)
+	URL string
+	// Stored is set once the file is in storage, up front for multipart intake
+	Stored *models.FileInfo
+}
+
+func newIntakeSubmission(source, formID, timestamp string) *intakeSubmission {
+	return &intakeSubmission{
+		Source:    source,
+		FormID:    formID,
+		Timestamp: timestamp,
+		Fields:    map[string]string{},
+		Files:     map[string]*intakeFile{},
+	}
+}
+
+// addAnswer stores a text answer under its canonical field, or keeps it as a free response
+func (s *intakeSubmission) addAnswer(question, answer string) {
+	field := canonicalField(question)
+	switch field {
+	case "firstName", "lastName", "major", "year", "email", "phone":
+		s.Fields[field] = strings.TrimSpace(answer)
+	case "":
+		s.Responses = append(s.Responses, models.Response{Question: question, Answer: answer})
+	}
+}
+
+var nonAlphanumeric = regexp.MustCompile(`[^a-z0-9]+`)
+
+// canonicalField maps a question key or title onto the applicant field it fills.
+// Bespoke payloads use keys like "firstName"; Typeform and Google Forms send the question
+// titles from the form, e.g. "Hello, what's your first name?" or "Cover Letter".
+func canonicalField(question string) string {
+	q := " " + strings.TrimSpace(nonAlphanumeric.ReplaceAllString(strings.ToLower(question), " ")) + " "
+
+	switch strings.TrimSpace(q) {
+	case "firstname", "first name", "first":
+		return "firstName"
+	case "lastname", "last name", "last":
+		return "lastName"
+	case "major":
+		return "major"
+	case "year":
+		return "year"
+	case "email":
+		return "email"
+	case "phone":
+		return "phone"
+	case "resume":
+		return "resume"
+	case "coverletter", "cover letter":
+		return "coverLetter"
+	case "image", "headshot", "photo":
+		return "image"
+	}
+
+	switch {
+	case strings.Contains(q, " first name "):
+		return "firstName"
+	case strings.Contains(q, " last name "):
+		return "lastName"
+	case strings.Contains(q, " email "):
+		return "email"
+	case strings.Contains(q, " phone "):
+		return "phone"
+	case strings.Contains(q, " major "):
+		return "major"
+	case strings.Contains(q, " graduation "):
+		return ""
+	case strings.Contains(q, " what year "), strings.Contains(q, " class standing "):
+		return "year"
+	case strings.Contains(q, " cover letter "):
+		return "coverLetter"
+	case strings.Contains(q, " resume "):
+		return "resume"
+	case strings.Contains(q, " headshot "), strings.Contains(q, " photo "):
+		return "image"
+	}
+	return ""
+}
+
+func isFileField(field string) bool {
+	return field == "resume" || field == "coverLetter" || field == "image"
+}
+
+// Rating every applicant starts from
+const initialElo = 1000
+
+// intakeResult says what ingest did with a submission
+type intakeResult struct {
+	Applicant *models.Applicant
+	// Replaced is set when the submission overwrote an existing duplicate application
+	Replaced bool
+	// Ignored is set when an older duplicate arrived after a newer one under keep_latest
+	Ignored bool
+}
+
+// ingest turns a normalised submission into an applicant for project, storing its files in the blob store.
+// Resubmissions are matched against existing applicants and handled by the project's duplicate policy.
+func ingest(ctx context.Context, collection *mongo.Collection, project *models.Project, sub *intakeSubmission) (*intakeResult, error) {
+	// every file stored for the submission is deleted again unless it ends up on an applicant
+	kept := false
+	defer func() {
+		if !kept {
+			cleanupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+			defer cancel()
+			discardStoredFiles(cleanupCtx, sub)
+		}
+	}()
+
+	duplicates, err := findDuplicates(ctx, collection, project.ID, sub)
+	if err != nil {
+		return nil, fmt.Errorf("error checking for duplicate applications: %v", err)
+	}
+
+	existing := duplicates.Exact
+	if existing != nil {
+		switch project.DuplicatePolicy {
+		case models.DuplicatePolicyReject:
+			return nil, &errDuplicateApplication{ExistingID: existing.ID}
+		case models.DuplicatePolicyReplace:
+			// always overwrite
+		default:
+			if !submittedAfter(sub.Timestamp, existing.Timestamp) {
+				return &intakeResult{Applicant: existing, Ignored: true}, nil
+			}
+		}
+	}
+
+	applicant := models.Applicant{
+		ID:                 primitive.NewObjectID(),
+		ProjectID:          project.ID,
+		FirstName:          sub.Fields["firstName"],
+		LastName:           sub.Fields["lastName"],
+		Major:              sub.Fields["major"],
+		Year:               sub.Fields["year"],
+		Email:              sub.Fields["email"],
+		Phone:              sub.Fields["phone"],
+		Elo:                initialElo,
+		Wins:               0,
+		Losses:             0,
+		MatchesPlayed:      []primitive.ObjectID{},
+		Status:             models.ApplicantStatusActive,
+		Timestamp:          sub.Timestamp,
+		Source:             sub.Source,
+		Responses:          sub.Responses,
+		PossibleDuplicates: duplicates.Possible,
+	}
+
+	for field, file := range sub.Files {
+		fileInfo, err := storeIntakeFile(ctx, field, file)
+		if err != nil {
+			return nil, fmt.Errorf("error storing %s: %w", field, err)
+		}
+		file.Stored = fileInfo
+
+		switch field {
+		case "coverLetter":
+			applicant.CoverLetter = fileInfo
+		case "resume":
+			applicant.Resume = fileInfo
+		case "image":
+			applicant.Image = fileInfo
+		}
+	}
+
+	// scan before anything parses the files
+	for _, file := range []*models.FileInfo{applicant.Resume, applicant.CoverLetter, applicant.Image} {
+		scanStoredFile(ctx, file)
+	}
+	applicant.ResumeText = extractFileText(ctx, applicant.Resume)
+	applicant.CoverLetterText = extractFileText(ctx, applicant.CoverLetter)
+	// thumbnails and previews are generated in the background, see RunPreviewWorkers
+	applicant.PreviewsDueAt = previewsDue(applicant.Resume, applicant.CoverLetter, applicant.Image)
+
+	if existing != nil {
+		if err := replaceApplication(ctx, collection, existing, &applicant); err != nil {
+			return nil, err
+		}
+		kept = true
+		wakePreviews()
+		if err := queueEnrichment(ctx, collection, existing.ID); err != nil {
+			log.Println("Failed to queue enrichment:", err)
+		}
+		return &intakeResult{Applicant: existing, Replaced: true}, nil
+	}
+
+	if _, err := collection.InsertOne(ctx, applicant); err != nil {
+		return nil, fmt.Errorf("error inserting document: %v", err)
+	}
+	kept = true
+	wakePreviews()
+	if err := queueEnrichment(ctx, collection, applicant.ID); err != nil {
+		log.Println("Failed to queue enrichment:", err)
+	}
+	publish(events.ApplicantAdded, project.ID, map[string]interface{}{
+		"applicantId": applicant.ID,
+		"stage":       applicant.Stage,
+		"source":      applicant.Source,
+	})
+	return &intakeResult{Applicant: &applicant}, nil
+}
+
+// replaceApplication overwrites the submitted fields of existing with those of the resubmission,
+// keeping its id, rating and comparison history. Files that were resubmitted replace the old ones.
+func replaceApplication(ctx context.Context, collection *mongo.Collection, existing, resubmission *models.Applicant) error {
+	set := bson.M{
+		"firstName": resubmission.FirstName,
+		"lastName":  resubmission.LastName,
+		"major":     resubmission.Major,
+		"year":      resubmission.Year,
+		"email":     resubmission.Email,
+		"phone":     resubmission.Phone,
+		"timestamp": resubmission.Timestamp,
+		"source":    resubmission.Source,
+		"responses": resubmission.Responses,
+	}
+	if resubmission.Resume != nil {
+		set["resumeText"] = resubmission.ResumeText
+	}
+	if resubmission.CoverLetter != nil {
+		set["coverLetterText"] = resubmission.CoverLetterText
+	}
+	if resubmission.PreviewsDueAt != nil {
+		set["previewsDueAt"] = resubmission.PreviewsDueAt
+	}
+
+	replaced := []*models.FileInfo{}
+	for key, pair := range map[string][2]*models.FileInfo{
+		"resume":      {existing.Resume, resubmission.Resume},
+		"coverLetter": {existing.CoverLetter, resubmission.CoverLetter},
+		"image":       {existing.Image, resubmission.Image},
+	} {
+		if pair[1] == nil {
+			continue
+		}
+		set[key] = pair[1]
+		if pair[0] != nil {
+			replaced = append(replaced, pair[0])
+		}
+	}
+
+	if _, err := collection.UpdateOne(ctx, bson.M{"_id": existing.ID}, bson.M{"$set": set}); err != nil {
+		return fmt.Errorf("error replacing application: %v", err)
+	}
+
+	for _, file := range replaced {
+		deleteStoredFile(ctx, file)
+	}
+
+	existing.FirstName, existing.LastName = resubmission.FirstName, resubmission.LastName
+	existing.Major, existing.Year = resubmission.Major, resubmission.Year
+	existing.Email, existing.Phone = resubmission.Email, resubmission.Phone
+	existing.Timestamp, existing.Source, existing.Responses = resubmission.Timestamp, resubmission.Source, resubmission.Responses
+	if resubmission.Resume != nil {
+		existing.Resume, existing.ResumeText = resubmission.Resume, resubmission.ResumeText
+	}
+	if resubmission.CoverLetter != nil {
+		existing.CoverLetter, existing.CoverLetterText = resubmission.CoverLetter, resubmission.CoverLetterText
+	}
+	if resubmission.Image != nil {
+		existing.Image = resubmission.Image
+	}
+	return nil
+}
+
+// submittedAfter reports whether timestamp a is later than b. Unparseable timestamps
+// count as latest so a resubmission is never dropped because of a formatting quirk.
+func submittedAfter(a, b string) bool {
+	ta, errA := time.Parse(time.RFC3339, a)
+	tb, errB := time.Parse(time.RFC3339, b)
+	if errA != nil || errB != nil {
+		return true
+	}
+	return !ta.Before(tb)
+}
+
+// Files larger than this are not read back for text extraction or previews
+const maxDerivedSourceBytes = 25 << 20
+
+// readStoredFile reads a stored file back into memory, up to maxDerivedSourceBytes
+func readStoredFile(ctx context.Context, file *models.FileInfo) ([]byte, error) {
+	store, err := storage.Get(file.Storage)
+	if err != nil {
+		return nil, err
+	}
+	blob, err := store.Open(ctx, file.FileID)
+	if err != nil {
+		return nil, err
+	}
+	defer blob.Close()
+
+	return io.ReadAll(io.LimitReader(blob, maxDerivedSourceBytes))
+}
+
+// extractFileText returns the plain text of a stored PDF or DOCX, or "" when the file is
+// missing, not a document, or unreadable. Extraction never fails an intake.
+func extractFileText(ctx context.Context, file *models.FileInfo) string {
+	if file == nil || file.ScanStatus == scan.StatusInfected {
+		return ""
+	}
+	data, err := readStoredFile(ctx, file)
+	if err != nil {
+		log.Printf("Error extracting text from %s: %v", file.FileID, err)
+		return ""
+	}
+	text, err := extract.Text(file.MimeType, data)
+	if err != nil {
+		if !errors.Is(err, extract.ErrUnsupported) {
+			log.Printf("Error extracting text from %s: %v", file.FileID, err)
+		}
+		return ""
+	}
+	return text
+}
+
+// scanStoredFile runs the configured malware scanner over a stored file and records the verdict on it.
+// Files stay pending when there is no scanner or it can't be reached, so they can be rescanned later.
+func scanStoredFile(ctx context.Context, file *models.FileInfo) {
+	if file == nil {
+		return
+	}
+	file.ScanStatus = scan.StatusPending
+
+	scanner := scan.Default()
+	if scanner == nil {
+		return
+	}
+	store, err := storage.Get(file.Storage)
+	if err != nil {
+		log.Printf("Error scanning %s: %v", file.FileID, err)
+		return
+	}
+	blob, err := store.Open(ctx, file.FileID)
+	if err != nil {
+		log.Printf("Error scanning %s: %v", file.FileID, err)
+		return
+	}
+	defer blob.Close()
+
+	result, err := scanner.Scan(ctx, blob)
+	if err != nil {
+		log.Printf("Error scanning %s with %s: %v", file.FileID, scanner.Name(), err)
+		return
+	}
+	now := time.Now()
+	file.ScanStatus, file.ScanSignature, file.ScannedAt = result.Status, result.Signature, &now
+	if result.Status == scan.StatusInfected {
+		log.Printf("Malware found in %s (%s): %s", file.FileID, file.FileName, result.Signature)
+	}
+}
+
+// deleteStoredFile removes a file and its derived copies from whichever backend holds them, logging failures
+func deleteStoredFile(ctx context.Context, fileInfo *models.FileInfo) {
+	for _, derived := range []*models.FileInfo{fileInfo.Thumbnail, fileInfo.Preview} {
+		if derived != nil {
+			deleteStoredFile(ctx, derived)
+		}
+	}
+
+	store, err := storage.Get(fileInfo.Storage)
+	if err != nil {
+		log.Printf("Error deleting file %s: %v", fileInfo.FileID, err)
+		return
+	}
+	if err := store.Delete(ctx, fileInfo.FileID); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		log.Printf("Error deleting file %s from %s: %v", fileInfo.FileID, store.Name(), err)
+	}
+}
+
+// discardStoredFiles deletes the files stored for a submission that was not kept
+func discardStoredFiles(ctx context.Context, sub *intakeSubmission) {
+	for _, file := range sub.Files {
+		if file.Stored != nil {
+			deleteStoredFile(ctx, file.Stored)
+		}
+	}
+}
+
+// storeIntakeFile puts a submitted file in the blob store. Its type is sniffed from the bytes, never
+// taken from what the form declared, and must be one allowed for field.
+func storeIntakeFile(ctx context.Context, field string, file *intakeFile) (*models.FileInfo, error) {
+	if file.Stored != nil {
+		return file.Stored, nil
+	}
+
+	data := file.Data
+	if len(data) == 0 && file.URL != "" {
+		downloaded, err := downloadFormFile(file.URL)
+		if err != nil {
+			return nil, err
+		}
+		data = downloaded
+	}
+
+	mimeType := sniffContentType(data)
+	if !allowedType(field, mimeType) {
+		return nil, &errUnsupportedFileType{Field: field, MimeType: mimeType}
+	}
+
+	uniqueFileName := fmt.Sprintf("%d_%s", time.Now().Unix(), file.FileName)
+
+	store := storage.Default()
+	fileID, err := store.Put(ctx, uniqueFileName, bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("error storing file: %v", err)
+	}
+
+	return &models.FileInfo{
+		FileID:      fileID,
+		FileName:    file.FileName,
+		MimeType:    mimeType,
+		DriveFileID: file.DriveFileID,
+		UniqueName:  uniqueFileName,
+		UploadedAt:  time.Now(),
+		Storage:     store.Name(),
+	}, nil
+}
+
+// Hosts form providers serve uploaded files from. FORM_FILE_HOSTS adds more, comma-separated.
+var formFileHosts = []string{"api.typeform.com", "api.typeform.eu"}
+
+var formFileClient = &http.Client{
+	Timeout: 30 * time.Second,
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 5 {
+			return errors.New("too many redirects")
+		}
+		if !formFileHostAllowed(req.URL) {
+			return fmt.Errorf("redirect to %s is not a form provider file host", req.URL.Host)
+		}
+		return nil
+	},
+}
+
+// formFileHostAllowed reports whether u is an https link on one of the form providers' file hosts,
+// so a forged payload can't make the server fetch internal or arbitrary URLs
+func formFileHostAllowed(u *url.URL) bool {
+	if u.Scheme != "https" || u.User != nil {
+		return false
+	}
+	hosts := formFileHosts
+	if extra := os.Getenv("FORM_FILE_HOSTS"); extra != "" {
+		hosts = append(append([]string{}, hosts...), strings.Split(extra, ",")...)
+	}
+	for _, host := range hosts {
+		if strings.EqualFold(strings.TrimSpace(host), u.Hostname()) {
+			return true
+		}
+	}
+	return false
+}
+
+// downloadFormFile fetches a file link from a form provider, up to the per-file upload limit.
+// Typeform file links need the account's personal access token.
+func downloadFormFile(rawURL string) ([]byte, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid file url: %v", err)
+	}
+	if !formFileHostAllowed(u) {
+		return nil, fmt.Errorf("file url %q is not on a form provider file host", u.Redacted())
+	}
+
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid file url: %v", err)
+	}
+	if token := os.Getenv("TYPEFORM_API_TOKEN"); token != "" && strings.HasPrefix(u.Hostname(), "api.typeform.") {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := formFileClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error downloading file: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error downloading file: %s", resp.Status)
+	}
+
+	maxFile := uploadLimit("MAX_UPLOAD_FILE_BYTES", defaultMaxFileBytes)
+	if resp.ContentLength > maxFile {
+		return nil, errFileTooLarge
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxFile+1))
+	if err != nil {
+		return nil, fmt.Errorf("error downloading file: %v", err)
+	}
+	if int64(len(data)) > maxFile {
+		return nil, errFileTooLarge
+	}
+	return data, nil
+}
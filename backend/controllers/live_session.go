@@ -0,0 +1,574 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"backend/blind"
+	"backend/middleware"
+	"backend/models"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gorilla/websocket"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	defaultLiveVoteTimeout = 60 * time.Second
+	maxLiveVoteTimeout     = 10 * time.Minute
+	// how long a round's result stays up before the next pair
+	liveResultPause = 5 * time.Second
+	liveWriteWait   = 10 * time.Second
+	livePingPeriod  = 30 * time.Second
+)
+
+// liveSessions holds the session running on each project, at most one per project
+var liveSessions = struct {
+	sync.Mutex
+	byProject map[primitive.ObjectID]*liveSession
+}{byProject: map[primitive.ObjectID]*liveSession{}}
+
+// liveSession is a deliberation round-robin: every connected reviewer sees the same pair, and the
+// majority of their votes is applied to the ratings once all have voted or the timer runs out.
+// One goroutine (run) owns the rounds; connections talk to it over channels.
+type liveSession struct {
+	ID          primitive.ObjectID
+	ProjectID   primitive.ObjectID
+	StartedBy   string
+	StartedAt   time.Time
+	VoteTimeout time.Duration
+
+	ac      *ApplicantController
+	project *models.Project
+	join    chan *liveClient
+	leave   chan *liveClient
+	votes   chan liveVote
+	stop    chan struct{}
+	done    chan struct{}
+
+	// what the status endpoint reports, written by run
+	mu      sync.Mutex
+	round   int
+	clients int
+}
+
+// liveClient is one reviewer's connection; run is the only writer to send
+type liveClient struct {
+	// signed-in reviewer; their votes count once however many connections they have open
+	userID string
+	send   chan []byte
+}
+
+type liveVote struct {
+	client   *liveClient
+	round    int
+	winnerID primitive.ObjectID
+}
+
+type liveRound struct {
+	number   int
+	pair     [2]models.Applicant
+	deadline time.Time
+	// winner chosen by each reviewer, by user id
+	votes map[string]primitive.ObjectID
+}
+
+// Messages sent to reviewers, told apart by type
+type liveMessage struct {
+	Type string `json:"type"`
+	// "pair": the applicants to choose between and when voting ends
+	Round      int                `json:"round,omitempty"`
+	Applicants []models.Applicant `json:"applicants,omitempty"`
+	Deadline   *time.Time         `json:"deadline,omitempty"`
+	// "progress" and "result": votes cast so far out of the reviewers connected
+	Votes  int `json:"votes,omitempty"`
+	Voters int `json:"voters,omitempty"`
+	// "result": votes per applicant and the outcome applied to the ratings, if any
+	Tally    map[string]int     `json:"tally,omitempty"`
+	WinnerID primitive.ObjectID `json:"winnerId,omitempty"`
+	LoserID  primitive.ObjectID `json:"loserId,omitempty"`
+	Applied  bool               `json:"applied,omitempty"`
+	// "result" when nothing was applied, and "ended"
+	Message string `json:"message,omitempty"`
+}
+
+// StartLiveSession starts a live review session on the project. Only owners can, when the project has any.
+// Body: {"voteTimeoutSeconds": 60}, optional.
+func (ac *ApplicantController) StartLiveSession(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		VoteTimeoutSeconds int `json:"voteTimeoutSeconds"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid JSON input: "+err.Error())
+			return
+		}
+	}
+	timeout := defaultLiveVoteTimeout
+	if request.VoteTimeoutSeconds != 0 {
+		timeout = time.Duration(request.VoteTimeoutSeconds) * time.Second
+		if timeout < time.Second || timeout > maxLiveVoteTimeout {
+			writeValidationError(w, map[string]string{"voteTimeoutSeconds": "must be between 1 and 600"})
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	project := ac.loadProject(ctx, w, chi.URLParam(r, "id"))
+	if project == nil {
+		return
+	}
+	userID := middleware.UserID(r.Context())
+	if len(project.OwnerIDs) > 0 && !project.IsOwner(userID) {
+		writeError(w, http.StatusForbidden, "Only project owners can start a live session")
+		return
+	}
+	if project.Closed {
+		writeError(w, http.StatusConflict, "Project is closed for review")
+		return
+	}
+
+	session := &liveSession{
+		ID:          primitive.NewObjectID(),
+		ProjectID:   project.ID,
+		StartedBy:   userID,
+		StartedAt:   time.Now(),
+		VoteTimeout: timeout,
+		ac:          ac,
+		project:     project,
+		join:        make(chan *liveClient),
+		leave:       make(chan *liveClient),
+		votes:       make(chan liveVote),
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+
+	liveSessions.Lock()
+	if _, running := liveSessions.byProject[project.ID]; running {
+		liveSessions.Unlock()
+		writeError(w, http.StatusConflict, "A live session is already running on this project")
+		return
+	}
+	liveSessions.byProject[project.ID] = session
+	liveSessions.Unlock()
+
+	go session.run()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(session.status())
+}
+
+// GetLiveSession reports the session running on the project
+func (ac *ApplicantController) GetLiveSession(w http.ResponseWriter, r *http.Request) {
+	session := findLiveSession(w, r)
+	if session == nil {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(session.status())
+}
+
+// EndLiveSession stops the session running on the project; a round in progress is dropped
+func (ac *ApplicantController) EndLiveSession(w http.ResponseWriter, r *http.Request) {
+	session := findLiveSession(w, r)
+	if session == nil {
+		return
+	}
+	if len(session.project.OwnerIDs) > 0 && !session.project.IsOwner(middleware.UserID(r.Context())) {
+		writeError(w, http.StatusForbidden, "Only project owners can end a live session")
+		return
+	}
+	session.end()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func findLiveSession(w http.ResponseWriter, r *http.Request) *liveSession {
+	projectID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid Project ID")
+		return nil
+	}
+	liveSessions.Lock()
+	session := liveSessions.byProject[projectID]
+	liveSessions.Unlock()
+	if session == nil {
+		writeError(w, http.StatusNotFound, "No live session on this project")
+		return nil
+	}
+	return session
+}
+
+var liveUpgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+		frontend := os.Getenv("FRONTEND_URL")
+		if frontend == "" {
+			frontend = "http://localhost:3000"
+		}
+		return origin == frontend
+	},
+}
+
+// JoinLiveSession upgrades to a WebSocket carrying the session to one reviewer. Reviewers receive
+// "pair", "progress", "result" and "ended" messages and send {"type": "vote", "round": n,
+// "winnerId": "..."}; a later vote in the same round replaces an earlier one. Reviewers must be
+// signed in and vote once however many tabs they have open.
+func (ac *ApplicantController) JoinLiveSession(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.UserID(r.Context())
+	if userID == "" {
+		writeError(w, http.StatusUnauthorized, "Sign in to join a live session")
+		return
+	}
+	session := findLiveSession(w, r)
+	if session == nil {
+		return
+	}
+	conn, err := liveUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// the upgrader has already answered
+		log.Println("WebSocket upgrade error:", err)
+		return
+	}
+
+	client := &liveClient{userID: userID, send: make(chan []byte, 16)}
+	select {
+	case session.join <- client:
+	case <-session.done:
+		conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "session ended"))
+		conn.Close()
+		return
+	}
+
+	go client.writePump(conn)
+	client.readPump(conn, session)
+}
+
+// readPump forwards the reviewer's votes to the session until the connection drops
+func (c *liveClient) readPump(conn *websocket.Conn, session *liveSession) {
+	defer func() {
+		select {
+		case session.leave <- c:
+		case <-session.done:
+		}
+		conn.Close()
+	}()
+
+	conn.SetReadLimit(4096)
+	conn.SetReadDeadline(time.Now().Add(2 * livePingPeriod))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(2 * livePingPeriod))
+	})
+
+	for {
+		var message struct {
+			Type     string `json:"type"`
+			Round    int    `json:"round"`
+			WinnerID string `json:"winnerId"`
+		}
+		if err := conn.ReadJSON(&message); err != nil {
+			if _, ok := err.(*json.SyntaxError); ok {
+				continue
+			}
+			return
+		}
+		if message.Type != "vote" {
+			continue
+		}
+		winnerID, err := primitive.ObjectIDFromHex(message.WinnerID)
+		if err != nil {
+			continue
+		}
+		select {
+		case session.votes <- liveVote{client: c, round: message.Round, winnerID: winnerID}:
+		case <-session.done:
+			return
+		}
+	}
+}
+
+// writePump writes what the session sends and keeps the connection alive, until send is closed
+func (c *liveClient) writePump(conn *websocket.Conn) {
+	ping := time.NewTicker(livePingPeriod)
+	defer func() {
+		ping.Stop()
+		conn.Close()
+	}()
+
+	for {
+		select {
+		case message, ok := <-c.send:
+			conn.SetWriteDeadline(time.Now().Add(liveWriteWait))
+			if !ok {
+				conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "session ended"))
+				return
+			}
+			if err := conn.WriteMessage(websocket.TextMessage, message); err != nil {
+				return
+			}
+		case <-ping.C:
+			conn.SetWriteDeadline(time.Now().Add(liveWriteWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *liveSession) status() map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return map[string]interface{}{
+		"id":                 s.ID,
+		"projectId":          s.ProjectID,
+		"startedBy":          s.StartedBy,
+		"startedAt":          s.StartedAt,
+		"voteTimeoutSeconds": int(s.VoteTimeout / time.Second),
+		"round":              s.round,
+		"reviewers":          s.clients,
+	}
+}
+
+func (s *liveSession) end() {
+	select {
+	case s.stop <- struct{}{}:
+	case <-s.done:
+	}
+}
+
+// run deals pairs to the connected reviewers one round at a time until the session is ended or
+// the project runs out of pairs. Rounds only start while someone is connected.
+func (s *liveSession) run() {
+	clients := map[*liveClient]bool{}
+	var round *liveRound
+	var timer *time.Timer
+	var timeout <-chan time.Time
+	resting := false
+	number := 0
+
+	setTimer := func(d time.Duration) {
+		if timer != nil {
+			timer.Stop()
+		}
+		timer = time.NewTimer(d)
+		timeout = timer.C
+	}
+	send := func(c *liveClient, message liveMessage) {
+		data, _ := json.Marshal(message)
+		select {
+		case c.send <- data:
+		default:
+			// too far behind, it will see the next pair
+		}
+	}
+	broadcast := func(message liveMessage) {
+		for c := range clients {
+			send(c, message)
+		}
+	}
+	voters := func() int {
+		ids := map[string]bool{}
+		for c := range clients {
+			ids[c.userID] = true
+		}
+		return len(ids)
+	}
+	finish := func() {
+		message := s.settle(round)
+		message.Voters = voters()
+		broadcast(message)
+		round = nil
+		resting = true
+		setTimer(liveResultPause)
+	}
+	update := func() {
+		s.mu.Lock()
+		s.round, s.clients = number, len(clients)
+		s.mu.Unlock()
+	}
+
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+		liveSessions.Lock()
+		delete(liveSessions.byProject, s.ProjectID)
+		liveSessions.Unlock()
+		close(s.done)
+		for c := range clients {
+			close(c.send)
+		}
+	}()
+
+	for {
+		if round == nil && !resting && len(clients) > 0 {
+			pair, err := s.nextPair()
+			if err != nil {
+				broadcast(liveMessage{Type: "ended", Message: err.Error()})
+				return
+			}
+			number++
+			round = &liveRound{
+				number:   number,
+				pair:     pair,
+				deadline: time.Now().Add(s.VoteTimeout),
+				votes:    map[string]primitive.ObjectID{},
+			}
+			for c := range clients {
+				send(c, s.pairMessage(round, c, voters()))
+			}
+			setTimer(s.VoteTimeout)
+		}
+		update()
+
+		select {
+		case c := <-s.join:
+			clients[c] = true
+			if round != nil {
+				send(c, s.pairMessage(round, c, voters()))
+			}
+		case c := <-s.leave:
+			if clients[c] {
+				delete(clients, c)
+				close(c.send)
+			}
+			// whoever is left may all have voted already
+			if round != nil && len(round.votes) > 0 && allVoted(round, clients) {
+				finish()
+			}
+		case vote := <-s.votes:
+			if round == nil || vote.round != round.number || !clients[vote.client] {
+				continue
+			}
+			if vote.winnerID != round.pair[0].ID && vote.winnerID != round.pair[1].ID {
+				continue
+			}
+			round.votes[vote.client.userID] = vote.winnerID
+			if allVoted(round, clients) {
+				finish()
+			} else {
+				broadcast(liveMessage{Type: "progress", Round: round.number, Votes: len(round.votes), Voters: voters()})
+			}
+		case <-timeout:
+			timeout = nil
+			if round != nil {
+				finish()
+			} else {
+				resting = false
+			}
+		case <-s.stop:
+			broadcast(liveMessage{Type: "ended", Message: "The session was ended"})
+			return
+		}
+	}
+}
+
+func allVoted(round *liveRound, clients map[*liveClient]bool) bool {
+	for c := range clients {
+		if _, ok := round.votes[c.userID]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// nextPair picks the next pair the way review does, refusing once the project has been closed
+func (s *liveSession) nextPair() ([2]models.Applicant, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var pair [2]models.Applicant
+	var project models.Project
+	if err := s.ac.projects.FindOne(ctx, bson.M{"_id": s.ProjectID}).Decode(&project); err != nil {
+		return pair, errLiveProjectGone
+	}
+	if project.Closed {
+		return pair, errLiveProjectClosed
+	}
+	s.project = &project
+
+	filter, _ := s.ac.currentStageFilter(ctx, activeApplicantFilter(bson.M{}), &project)
+	a, b, err := s.ac.pickPair(ctx, filter)
+	if err == errMatchHistoryReset {
+		a, b, err = s.ac.pickPair(ctx, filter)
+	}
+	if err != nil {
+		log.Println("Live session pair error:", err)
+		return pair, errLiveNoPairs
+	}
+	return [2]models.Applicant{a, b}, nil
+}
+
+var (
+	errLiveProjectGone   = errors.New("The project no longer exists")
+	errLiveProjectClosed = errors.New("The project was closed for review")
+	errLiveNoPairs       = errors.New("There are no more applicants to compare")
+)
+
+// pairMessage shows the round's pair to c. Everyone in the room sees the same pair, so in
+// blind-review projects owners get pseudonyms during a session too.
+func (s *liveSession) pairMessage(round *liveRound, c *liveClient, voters int) liveMessage {
+	applicants := []models.Applicant{round.pair[0], round.pair[1]}
+	for i := range applicants {
+		if s.project.BlindReview {
+			blind.Anonymise(&applicants[i])
+		}
+		applicants[i].MatchesPlayed = nil
+		signFileURLs(&applicants[i], c.userID)
+	}
+	deadline := round.deadline
+	return liveMessage{
+		Type:       "pair",
+		Round:      round.number,
+		Applicants: applicants,
+		Deadline:   &deadline,
+		Votes:      len(round.votes),
+		Voters:     voters,
+	}
+}
+
+// settle applies the majority of a round's votes to the ratings. A tie or no votes leaves them alone.
+func (s *liveSession) settle(round *liveRound) liveMessage {
+	message := liveMessage{Type: "result", Round: round.number, Votes: len(round.votes), Tally: map[string]int{}}
+	counts := map[primitive.ObjectID]int{}
+	for _, winnerID := range round.votes {
+		counts[winnerID]++
+	}
+	a, b := round.pair[0].ID, round.pair[1].ID
+	message.Tally[a.Hex()], message.Tally[b.Hex()] = counts[a], counts[b]
+
+	switch {
+	case len(round.votes) == 0:
+		message.Message = "No votes were cast"
+		return message
+	case counts[a] == counts[b]:
+		message.Message = "The vote was tied"
+		return message
+	case counts[a] > counts[b]:
+		message.WinnerID, message.LoserID = a, b
+	default:
+		message.WinnerID, message.LoserID = b, a
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := s.ac.recordVote(ctx, message.WinnerID, message.LoserID, "live-session:"+s.ID.Hex()); err != nil {
+		log.Println("Live session vote error:", err)
+		message.Message = "The result could not be applied: " + err.Error()
+		return message
+	}
+	message.Applied = true
+	return message
+}
@@ -0,0 +1,60 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"backend/middleware"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestJoinLiveSessionRequiresUser(t *testing.T) {
+	projectID := primitive.NewObjectID()
+	liveSessions.Lock()
+	liveSessions.byProject[projectID] = &liveSession{ProjectID: projectID}
+	liveSessions.Unlock()
+	defer func() {
+		liveSessions.Lock()
+		delete(liveSessions.byProject, projectID)
+		liveSessions.Unlock()
+	}()
+
+	r := withURLParams(httptest.NewRequest(http.MethodGet, "/", nil), map[string]string{"id": projectID.Hex()})
+	w := httptest.NewRecorder()
+	(&ApplicantController{}).JoinLiveSession(w, r)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want 401", w.Code)
+	}
+
+	// signed in, it gets as far as the upgrade, which a plain GET fails
+	r = r.WithContext(middleware.WithUserID(r.Context(), "user_1"))
+	w = httptest.NewRecorder()
+	(&ApplicantController{}).JoinLiveSession(w, r)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want the upgrader's 400", w.Code)
+	}
+}
+
+func TestLiveVotesOnePerUser(t *testing.T) {
+	a, b := primitive.NewObjectID(), primitive.NewObjectID()
+	tab1, tab2 := &liveClient{userID: "user_1"}, &liveClient{userID: "user_1"}
+	other := &liveClient{userID: "user_2"}
+	clients := map[*liveClient]bool{tab1: true, tab2: true, other: true}
+	round := &liveRound{votes: map[string]primitive.ObjectID{}}
+
+	// both tabs of user_1 voting leaves a single vote, the later one
+	round.votes[tab1.userID] = a
+	round.votes[tab2.userID] = b
+	if len(round.votes) != 1 || round.votes["user_1"] != b {
+		t.Fatalf("votes = %v, want one vote for %s", round.votes, b.Hex())
+	}
+	if allVoted(round, clients) {
+		t.Fatal("allVoted() before user_2 voted")
+	}
+	round.votes[other.userID] = a
+	if !allVoted(round, clients) {
+		t.Fatal("allVoted() = false once every user voted")
+	}
+}
@@ -0,0 +1,249 @@
+package controllers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/models"
+	"backend/storage"
+	"backend/webhook"
+)
+
+// Default upload limits, overridable with MAX_UPLOAD_FILE_BYTES and MAX_UPLOAD_REQUEST_BYTES
+const (
+	defaultMaxFileBytes    = 10 << 20
+	defaultMaxRequestBytes = 25 << 20
+	maxTextFieldBytes      = 64 << 10
+)
+
+var errFileTooLarge = errors.New("file exceeds the per-file size limit")
+
+// Content types accepted for each file field, decided by sniffing the bytes rather than
+// trusting the part's Content-Type header
+var allowedUploadTypes = map[string][]string{
+	"resume":      {"application/pdf", docxMimeType},
+	"coverLetter": {"application/pdf", docxMimeType},
+	"image":       {"image/jpeg", "image/png", "image/webp", "image/gif"},
+}
+
+const docxMimeType = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+
+func uploadLimit(env string, fallback int64) int64 {
+	if v, err := strconv.ParseInt(os.Getenv(env), 10, 64); err == nil && v > 0 {
+		return v
+	}
+	return fallback
+}
+
+// HandleMultipart accepts a multipart/form-data submission and streams each file part straight
+// into the blob store. Text parts are answers keyed by question, file parts must be named resume,
+// coverLetter or image. The project comes from the formId query parameter. The signature is
+// computed while the body streams and checked at the end; files from a bad request are deleted.
+func (fc *FormResponseController) HandleMultipart(w http.ResponseWriter, r *http.Request) {
+	formId := r.URL.Query().Get("formId")
+
+	project, err := fc.resolveProject(r, formId)
+	if err != nil {
+		// the body is never read for unknown forms, so there is nothing worth quarantining
+		if errors.Is(err, errUnknownForm) {
+			http.Error(w, fmt.Sprintf("No project registered for form %q", formId), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Unauthorized webhook: "+err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	header := r.Header.Get(webhook.SignatureHeader)
+	now := time.Now()
+	verifier, err := webhook.NewStreamVerifier(project.WebhookSecret, header, now, webhook.DefaultTolerance)
+	if err != nil {
+		log.Printf("Rejected multipart webhook from %s for project %s: %v", r.RemoteAddr, formId, err)
+		http.Error(w, "Unauthorized webhook: "+err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	maxFile := uploadLimit("MAX_UPLOAD_FILE_BYTES", defaultMaxFileBytes)
+	maxRequest := uploadLimit("MAX_UPLOAD_REQUEST_BYTES", defaultMaxRequestBytes)
+	body := http.MaxBytesReader(w, r.Body, maxRequest)
+	r.Body = io.NopCloser(io.TeeReader(body, verifier))
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		http.Error(w, "Expected multipart/form-data: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	sub := newIntakeSubmission("multipart", formId, "")
+	status, err := readMultipartSubmission(ctx, reader, sub, maxFile)
+	if err == nil {
+		// drain the epilogue so the signature covers every byte that was sent
+		if _, err = io.Copy(io.Discard, r.Body); err != nil {
+			status = http.StatusBadRequest
+		}
+	}
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			status, err = http.StatusRequestEntityTooLarge, fmt.Errorf("request exceeds %d bytes", maxRequest)
+		}
+		discardStoredFiles(ctx, sub)
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	if err := verifier.Verify(); err != nil {
+		log.Printf("Rejected multipart webhook from %s for project %s: %v", r.RemoteAddr, formId, err)
+		discardStoredFiles(ctx, sub)
+		http.Error(w, "Unauthorized webhook: "+err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if err := fc.replays.claim(verifier.ReplayKey(), now.Add(2*webhook.DefaultTolerance)); err != nil {
+		log.Printf("Rejected multipart webhook from %s for project %s: %v", r.RemoteAddr, formId, err)
+		discardStoredFiles(ctx, sub)
+		if errors.Is(err, errReplayCheck) {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		http.Error(w, "Unauthorized webhook: "+err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	if sub.Timestamp == "" {
+		sub.Timestamp = now.Format(time.RFC3339)
+	}
+	fc.ingestClaimed(w, project, sub, verifier.ReplayKey())
+}
+
+// readMultipartSubmission consumes every part, filling sub. On error it returns the HTTP status to respond with.
+func readMultipartSubmission(ctx context.Context, reader *multipart.Reader, sub *intakeSubmission, maxFile int64) (int, error) {
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return 0, nil
+		}
+		if err != nil {
+			return http.StatusBadRequest, fmt.Errorf("error reading multipart body: %w", err)
+		}
+
+		name := part.FormName()
+		if part.FileName() == "" {
+			value, err := io.ReadAll(io.LimitReader(part, maxTextFieldBytes))
+			part.Close()
+			if err != nil {
+				return http.StatusBadRequest, fmt.Errorf("error reading field %q: %w", name, err)
+			}
+			if name == "timestamp" || name == "submission_timestamp" {
+				sub.Timestamp = string(value)
+				continue
+			}
+			sub.addAnswer(name, string(value))
+			continue
+		}
+
+		field := canonicalField(name)
+		if !isFileField(field) {
+			part.Close()
+			return http.StatusBadRequest, fmt.Errorf("unexpected file field %q", name)
+		}
+		if _, ok := sub.Files[field]; ok {
+			part.Close()
+			return http.StatusBadRequest, fmt.Errorf("more than one file for %q", name)
+		}
+
+		fileInfo, status, err := streamUpload(ctx, field, part, maxFile)
+		part.Close()
+		if err != nil {
+			return status, err
+		}
+		sub.Files[field] = &intakeFile{
+			FileName: fileInfo.FileName,
+			MimeType: fileInfo.MimeType,
+			Stored:   fileInfo,
+		}
+	}
+}
+
+// streamUpload sniffs the first bytes of part, checks them against the types allowed for field,
+// then copies the part into the blob store without holding it in memory
+func streamUpload(ctx context.Context, field string, part *multipart.Part, maxFile int64) (*models.FileInfo, int, error) {
+	buffered := bufio.NewReaderSize(part, 512)
+	head, err := buffered.Peek(512)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, http.StatusBadRequest, fmt.Errorf("error reading %s: %w", field, err)
+	}
+
+	mimeType := sniffContentType(head)
+	if !allowedType(field, mimeType) {
+		return nil, http.StatusUnsupportedMediaType, &errUnsupportedFileType{Field: field, MimeType: mimeType}
+	}
+
+	fileName := filepath.Base(part.FileName())
+	uniqueFileName := fmt.Sprintf("%d_%s", time.Now().Unix(), fileName)
+
+	store := storage.Default()
+	fileID, err := store.Put(ctx, uniqueFileName, storage.LimitReader(buffered, maxFile, errFileTooLarge))
+	if err != nil {
+		if errors.Is(err, errFileTooLarge) {
+			return nil, http.StatusRequestEntityTooLarge, fmt.Errorf("%s exceeds %d bytes", field, maxFile)
+		}
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return nil, http.StatusRequestEntityTooLarge, err
+		}
+		return nil, http.StatusBadRequest, fmt.Errorf("error streaming %s: %w", field, err)
+	}
+
+	return &models.FileInfo{
+		FileID:     fileID,
+		FileName:   fileName,
+		MimeType:   mimeType,
+		UniqueName: uniqueFileName,
+		UploadedAt: time.Now(),
+		Storage:    store.Name(),
+	}, 0, nil
+}
+
+// sniffContentType wraps http.DetectContentType, which reports DOCX files as plain zip
+// archives; a zip whose first entry is an OOXML part is reported as DOCX instead
+func sniffContentType(head []byte) string {
+	mimeType := http.DetectContentType(head)
+	if mimeType == "application/zip" && (bytes.Contains(head, []byte("[Content_Types].xml")) || bytes.Contains(head, []byte("word/"))) {
+		return docxMimeType
+	}
+	if i := strings.Index(mimeType, ";"); i >= 0 {
+		mimeType = mimeType[:i]
+	}
+	return mimeType
+}
+
+// errUnsupportedFileType rejects a file whose sniffed type isn't allowed for its field
+type errUnsupportedFileType struct {
+	Field    string
+	MimeType string
+}
+
+func (e *errUnsupportedFileType) Error() string {
+	return fmt.Sprintf("%s has unsupported content type %s", e.Field, e.MimeType)
+}
+
+func allowedType(field, mimeType string) bool {
+	for _, allowed := range allowedUploadTypes[field] {
+		if mimeType == allowed {
+			return true
+		}
+	}
+	return false
+}
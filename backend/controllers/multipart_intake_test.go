@@ -0,0 +1,210 @@
+package controllers
+
+import (
+	"archive/zip"
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/webhook"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+var (
+	pdfHead  = []byte("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n1 0 obj\n<< /Type /Catalog >>\nendobj\n")
+	pngHead  = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01\x00\x00\x00\x01\x08\x06\x00\x00\x00")
+	jpegHead = []byte("\xff\xd8\xff\xe0\x00\x10JFIF\x00\x01\x01\x00\x00\x01\x00\x01\x00\x00")
+	gifHead  = []byte("GIF89a\x01\x00\x01\x00\x80\x00\x00")
+	webpHead = []byte("RIFF\x24\x00\x00\x00WEBPVP8 \x18\x00\x00\x00")
+)
+
+// zipWith builds a zip archive whose entries are named names, in order
+func zipWith(t testing.TB, names ...string) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range names {
+		f, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte("<xml/>"))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestSniffContentType(t *testing.T) {
+	tests := []struct {
+		name string
+		head []byte
+		want string
+	}{
+		{"pdf", pdfHead, "application/pdf"},
+		{"png", pngHead, "image/png"},
+		{"jpeg", jpegHead, "image/jpeg"},
+		{"gif", gifHead, "image/gif"},
+		{"webp", webpHead, "image/webp"},
+		{"docx", zipWith(t, "[Content_Types].xml", "word/document.xml"), docxMimeType},
+		{"docx without content types first", zipWith(t, "word/document.xml"), docxMimeType},
+		{"plain zip", zipWith(t, "notes.txt"), "application/zip"},
+		{"plain text drops the charset", []byte("just some text"), "text/plain"},
+		{"html", []byte("<!DOCTYPE html><script>alert(1)</script>"), "text/html"},
+		{"empty", nil, "text/plain"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sniffContentType(tt.head); got != tt.want {
+				t.Errorf("sniffContentType() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAllowedType(t *testing.T) {
+	tests := []struct {
+		field, mimeType string
+		want            bool
+	}{
+		{"resume", "application/pdf", true},
+		{"resume", docxMimeType, true},
+		{"coverLetter", "application/pdf", true},
+		{"resume", "image/png", false},
+		{"resume", "application/zip", false},
+		{"resume", "text/html", false},
+		{"image", "image/png", true},
+		{"image", "image/jpeg", true},
+		{"image", "image/webp", true},
+		{"image", "image/gif", true},
+		{"image", "image/svg+xml", false},
+		{"image", "application/pdf", false},
+		{"unknown", "application/pdf", false},
+	}
+	for _, tt := range tests {
+		if got := allowedType(tt.field, tt.mimeType); got != tt.want {
+			t.Errorf("allowedType(%q, %q) = %t, want %t", tt.field, tt.mimeType, got, tt.want)
+		}
+	}
+}
+
+// multipartBody encodes fields and files as multipart/form-data, returning the body and its content type
+func multipartBody(t testing.TB, fields map[string]string, files map[string][]byte) ([]byte, string) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	for name, value := range fields {
+		mw.WriteField(name, value)
+	}
+	for name, data := range files {
+		part, err := mw.CreateFormFile(name, name+".bin")
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write(data)
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes(), mw.FormDataContentType()
+}
+
+func TestHandleMultipart(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	const secret = "s3cr3t"
+	projectID := primitive.NewObjectID()
+	project := mtest.CreateCursorResponse(0, "db.projects", mtest.FirstBatch, bson.D{{Key: "_id", Value: projectID}, {Key: "webhookSecret", Value: secret}})
+	fields := map[string]string{"First name": "Ada", "Last name": "Lovelace"}
+	resume := append(append([]byte{}, pdfHead...), bytes.Repeat([]byte("x"), 4<<10)...)
+
+	tests := []struct {
+		name       string
+		files      map[string][]byte
+		signWith   string
+		env        map[string]string
+		responses  []bson.D
+		wantStatus int
+		wantStored int
+	}{
+		{
+			name:     "accepted",
+			files:    map[string][]byte{"resume": resume, "image": pngHead},
+			signWith: secret,
+			responses: []bson.D{
+				project,
+				mtest.CreateSuccessResponse(),
+				mtest.CreateCursorResponse(0, "db.applicants", mtest.FirstBatch),
+				mtest.CreateSuccessResponse(),
+			},
+			wantStatus: http.StatusCreated,
+			wantStored: 2,
+		},
+		{
+			name:       "bad signature deletes the streamed files",
+			files:      map[string][]byte{"resume": resume, "image": pngHead},
+			signWith:   "not-the-secret",
+			responses:  []bson.D{project},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "file over the per-file limit",
+			files:      map[string][]byte{"image": pngHead, "resume": resume},
+			signWith:   secret,
+			env:        map[string]string{"MAX_UPLOAD_FILE_BYTES": "1024"},
+			responses:  []bson.D{project},
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:       "request over the per-request limit",
+			files:      map[string][]byte{"resume": resume},
+			signWith:   secret,
+			env:        map[string]string{"MAX_UPLOAD_REQUEST_BYTES": "2048"},
+			responses:  []bson.D{project},
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:       "resume that isn't a PDF or DOCX",
+			files:      map[string][]byte{"resume": []byte("<html><script>alert(1)</script></html>")},
+			signWith:   secret,
+			responses:  []bson.D{project},
+			wantStatus: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:       "DOCX passed off as an image",
+			files:      map[string][]byte{"image": zipWith(t, "[Content_Types].xml", "word/document.xml")},
+			signWith:   secret,
+			responses:  []bson.D{project},
+			wantStatus: http.StatusUnsupportedMediaType,
+		},
+	}
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			dir := setupLocalStorage(t, mt)
+			fc := &FormResponseController{collection: mt.Coll, projects: mt.Coll, replays: &replayStore{collection: mt.Coll}}
+			mt.AddMockResponses(tt.responses...)
+
+			body, contentType := multipartBody(mt, fields, tt.files)
+			r := httptest.NewRequest(http.MethodPost, "/api/formResponseListener/multipart?formId="+projectID.Hex(), bytes.NewReader(body))
+			r.Header.Set("Content-Type", contentType)
+			r.Header.Set(webhook.SignatureHeader, webhook.Sign(tt.signWith, time.Now(), body))
+			w := httptest.NewRecorder()
+			fc.HandleMultipart(w, r)
+
+			if w.Code != tt.wantStatus {
+				mt.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, strings.TrimSpace(w.Body.String()))
+			}
+			if n := countStoredFiles(mt, dir); n != tt.wantStored {
+				mt.Fatalf("%d files left in storage, want %d", n, tt.wantStored)
+			}
+		})
+	}
+}
@@ -0,0 +1,350 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"backend/mail"
+	"backend/middleware"
+	"backend/models"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// sendEmail renders the project's version of a template and sends it to one recipient
+func sendEmail(project *models.Project, name, to string, data interface{}) error {
+	msg, err := mail.Render(mail.Lookup(project, name), data)
+	if err != nil {
+		return err
+	}
+	msg.To = to
+	return mail.Default().Send(msg)
+}
+
+// sendIntakeReceipt emails an applicant that their application arrived, when the project asks for receipts
+func sendIntakeReceipt(project *models.Project, applicant *models.Applicant) {
+	if mail.Default() == nil || !project.IntakeReceipts || applicant.Email == "" {
+		return
+	}
+	data := mail.ReceiptData{FirstName: applicant.FirstName, LastName: applicant.LastName, ProjectName: project.Name}
+	to := applicant.Email
+	go func() {
+		if err := sendEmail(project, mail.TemplateReceipt, to, data); err != nil {
+			log.Printf("Failed to send intake receipt for applicant %s: %v", applicant.ID.Hex(), err)
+		}
+	}()
+}
+
+// sendDecisionEmails tells the applicants of a cut stage whether they made it into the next one.
+// It returns how many emails it queued; they are sent in the background.
+func sendDecisionEmails(project *models.Project, stage int, applicants []*models.Applicant, advanced map[primitive.ObjectID]bool) int {
+	if mail.Default() == nil {
+		return 0
+	}
+	type decision struct {
+		template string
+		to       string
+		data     mail.DecisionData
+	}
+	var decisions []decision
+	for _, a := range applicants {
+		if a.Email == "" {
+			continue
+		}
+		d := decision{template: mail.TemplateNotAdvanced, to: a.Email, data: mail.DecisionData{
+			FirstName:     a.FirstName,
+			LastName:      a.LastName,
+			ProjectName:   project.Name,
+			StageName:     project.Stages[stage].Name,
+			NextStageName: project.Stages[stage+1].Name,
+		}}
+		if advanced[a.ID] {
+			d.template = mail.TemplateAdvanced
+		}
+		decisions = append(decisions, d)
+	}
+
+	go func() {
+		failed := 0
+		for _, d := range decisions {
+			if err := sendEmail(project, d.template, d.to, d.data); err != nil {
+				failed++
+				log.Printf("Failed to send %s email to %s: %v", d.template, d.to, err)
+			}
+		}
+		log.Printf("Sent %d of %d decision emails for project %s", len(decisions)-failed, len(decisions), project.ID.Hex())
+	}()
+	return len(decisions)
+}
+
+// reviewerReminder is one reviewer's standing against the quota, and whether they were emailed
+type reviewerReminder struct {
+	UserID   string `json:"userId"`
+	Name     string `json:"name,omitempty"`
+	Votes    int    `json:"votes"`
+	Quota    int    `json:"quota"`
+	Reminded bool   `json:"reminded"`
+	Error    string `json:"error,omitempty"`
+}
+
+// remindReviewers emails every reviewer of project who has cast fewer than VoteQuota votes in the current stage
+func (ac *ApplicantController) remindReviewers(ctx context.Context, project *models.Project) ([]reviewerReminder, error) {
+	ids := make([]string, len(project.Reviewers))
+	for i, reviewer := range project.Reviewers {
+		ids[i] = reviewer.UserID
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"project_id": project.ID,
+			"stage":      stageFilter(project.CurrentStage),
+			"voided":     bson.M{"$ne": true},
+			"reviewerId": bson.M{"$in": ids},
+		}}},
+		{{Key: "$group", Value: bson.M{"_id": "$reviewerId", "votes": bson.M{"$sum": 1}}}},
+	}
+	cursor, err := ac.comparisons.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	var counts []struct {
+		ReviewerID string `bson:"_id"`
+		Votes      int    `bson:"votes"`
+	}
+	if err := cursor.All(ctx, &counts); err != nil {
+		return nil, err
+	}
+	votes := map[string]int{}
+	for _, c := range counts {
+		votes[c.ReviewerID] = c.Votes
+	}
+
+	reviewURL := ""
+	if frontend := os.Getenv("FRONTEND_URL"); frontend != "" {
+		reviewURL = strings.TrimRight(frontend, "/") + "/review/" + project.ID.Hex()
+	}
+	reminders := []reviewerReminder{}
+	for _, reviewer := range project.Reviewers {
+		reminder := reviewerReminder{UserID: reviewer.UserID, Name: reviewer.Name, Votes: votes[reviewer.UserID], Quota: project.VoteQuota}
+		if reminder.Votes < project.VoteQuota && reviewer.Email != "" {
+			err := sendEmail(project, mail.TemplateReminder, reviewer.Email, mail.ReminderData{
+				ReviewerName: reviewer.Name,
+				ProjectName:  project.Name,
+				StageName:    stageName(project, project.CurrentStage),
+				Votes:        reminder.Votes,
+				Quota:        project.VoteQuota,
+				Remaining:    project.VoteQuota - reminder.Votes,
+				ReviewURL:    reviewURL,
+			})
+			if err != nil {
+				reminder.Error = err.Error()
+				log.Printf("Failed to remind reviewer %s of project %s: %v", reviewer.UserID, project.ID.Hex(), err)
+			} else {
+				reminder.Reminded = true
+			}
+		}
+		reminders = append(reminders, reminder)
+	}
+	return reminders, nil
+}
+
+// SendReminders emails the project's reviewers who are behind their vote quota for the current
+// stage, and returns every reviewer's vote count
+func (ac *ApplicantController) SendReminders(w http.ResponseWriter, r *http.Request) {
+	if mail.Default() == nil {
+		writeError(w, http.StatusServiceUnavailable, "Email is not configured, set SMTP_HOST")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	project := ac.loadProject(ctx, w, chi.URLParam(r, "id"))
+	if project == nil {
+		return
+	}
+	if len(project.OwnerIDs) > 0 && !project.IsOwner(middleware.UserID(r.Context())) {
+		writeError(w, http.StatusForbidden, "Only project owners can send reminders")
+		return
+	}
+	if project.VoteQuota == 0 {
+		writeError(w, http.StatusConflict, "Project has no vote quota, set voteQuota first")
+		return
+	}
+	if project.Closed {
+		writeError(w, http.StatusConflict, "Project is closed for review")
+		return
+	}
+
+	reminders, err := ac.remindReviewers(ctx, project)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to count reviewer votes")
+		log.Println("MongoDB Aggregate comparisons error:", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"reviewers": reminders})
+}
+
+// ScheduleReviewerReminders reminds the reviewers behind quota on every open project once per interval
+func ScheduleReviewerReminders(interval time.Duration) {
+	ac := NewApplicantController()
+	for range time.Tick(interval) {
+		if mail.Default() != nil {
+			ac.remindAllReviewers()
+		}
+	}
+}
+
+func (ac *ApplicantController) remindAllReviewers() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	cursor, err := ac.projects.Find(ctx, bson.M{
+		"voteQuota":   bson.M{"$gt": 0},
+		"reviewers.0": bson.M{"$exists": true},
+		"closed":      bson.M{"$ne": true},
+	})
+	if err != nil {
+		log.Println("MongoDB Find projects error:", err)
+		return
+	}
+	var projects []models.Project
+	if err := cursor.All(ctx, &projects); err != nil {
+		log.Println("Cursor decode error:", err)
+		return
+	}
+	for i := range projects {
+		if _, err := ac.remindReviewers(ctx, &projects[i]); err != nil {
+			log.Printf("Failed to remind reviewers of project %s: %v", projects[i].ID.Hex(), err)
+		}
+	}
+}
+
+// emailTemplate is a template as the project will send it
+type emailTemplate struct {
+	models.EmailTemplate
+	// false while the default is in use
+	Custom    bool     `json:"custom"`
+	Variables []string `json:"variables"`
+}
+
+// ownedProject loads the project in the URL for the email template endpoints, which only owners may use
+func (pc *ProjectController) ownedProject(ctx context.Context, w http.ResponseWriter, r *http.Request) *models.Project {
+	projectID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid Project ID")
+		return nil
+	}
+	var project models.Project
+	if err := pc.collection.FindOne(ctx, bson.M{"_id": projectID}).Decode(&project); err != nil {
+		if err == mongo.ErrNoDocuments {
+			writeError(w, http.StatusNotFound, "Project not found")
+			return nil
+		}
+		writeError(w, http.StatusInternalServerError, "Failed to fetch project")
+		log.Println("MongoDB Find project error:", err)
+		return nil
+	}
+	if len(project.OwnerIDs) > 0 && !project.IsOwner(middleware.UserID(r.Context())) {
+		writeError(w, http.StatusForbidden, "Only project owners can manage email templates")
+		return nil
+	}
+	return &project
+}
+
+// EmailTemplates lists every notification email as the project sends it, with the variables each can use
+func (pc *ProjectController) EmailTemplates(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	project := pc.ownedProject(ctx, w, r)
+	if project == nil {
+		return
+	}
+	templates := map[string]emailTemplate{}
+	for _, name := range mail.Templates {
+		_, custom := project.EmailTemplates[name]
+		templates[name] = emailTemplate{EmailTemplate: mail.Lookup(project, name), Custom: custom, Variables: mail.Variables(name)}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(templates)
+}
+
+// SetEmailTemplate replaces one of the project's emails. Body: {"subject", "body"} in Go text/template
+// syntax, e.g. "Hi {{.FirstName}}".
+func (pc *ProjectController) SetEmailTemplate(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "template")
+	var request models.EmailTemplate
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid JSON input: "+err.Error())
+		return
+	}
+	if !validTemplateName(name) {
+		writeError(w, http.StatusNotFound, "Unknown email template")
+		return
+	}
+	if err := mail.Validate(name, request); err != nil {
+		writeValidationError(w, map[string]string{"template": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	project := pc.ownedProject(ctx, w, r)
+	if project == nil {
+		return
+	}
+	if _, err := pc.collection.UpdateOne(ctx, bson.M{"_id": project.ID}, bson.M{"$set": bson.M{"emailTemplates." + name: request}}); err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to save email template")
+		log.Println("MongoDB Update project error:", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(emailTemplate{EmailTemplate: request, Custom: true, Variables: mail.Variables(name)})
+}
+
+// ResetEmailTemplate goes back to the default for one of the project's emails
+func (pc *ProjectController) ResetEmailTemplate(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "template")
+	if !validTemplateName(name) {
+		writeError(w, http.StatusNotFound, "Unknown email template")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	project := pc.ownedProject(ctx, w, r)
+	if project == nil {
+		return
+	}
+	if _, err := pc.collection.UpdateOne(ctx, bson.M{"_id": project.ID}, bson.M{"$unset": bson.M{"emailTemplates." + name: ""}}); err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to reset email template")
+		log.Println("MongoDB Update project error:", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(emailTemplate{EmailTemplate: mail.Defaults[name], Variables: mail.Variables(name)})
+}
+
+func validTemplateName(name string) bool {
+	for _, t := range mail.Templates {
+		if t == name {
+			return true
+		}
+	}
+	return false
+}
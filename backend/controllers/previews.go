@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"backend/db"
+	"backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// applications whose previews are generated at once; decoding and pdftoppm are CPU heavy
+	previewWorkers = 1
+	// how often idle workers look for applications waiting for previews
+	previewPollInterval = 5 * time.Second
+	// time to preview every file of one application; a claimed application is tried again after
+	// this if its worker never finishes it
+	previewTimeout = 2 * time.Minute
+)
+
+// previewWake tells an idle worker there is work before its next poll
+var previewWake = make(chan struct{}, 1)
+
+// previewsDue returns the time an application's previews become due, or nil when none of its files
+// needs one. Intake stores it as previewsDueAt in the same write as the files, then calls wakePreviews.
+func previewsDue(files ...*models.FileInfo) *time.Time {
+	for _, file := range files {
+		if needsPreview(file) {
+			now := time.Now()
+			return &now
+		}
+	}
+	return nil
+}
+
+// wakePreviews tells the preview workers an application is waiting
+func wakePreviews() {
+	select {
+	case previewWake <- struct{}{}:
+	default:
+	}
+}
+
+// RunPreviewWorkers starts the workers that generate thumbnails and PDF previews in the background,
+// picking up applications as they arrive and any a restart left waiting
+func RunPreviewWorkers() {
+	collection := db.GetCollection("applicants")
+	for i := 0; i < previewWorkers; i++ {
+		go func() {
+			for {
+				if !previewNext(collection) {
+					select {
+					case <-previewWake:
+					case <-time.After(previewPollInterval):
+					}
+				}
+			}
+		}()
+	}
+}
+
+// previewNext claims the application whose previews are due soonest and generates them. It reports
+// whether there was one.
+func previewNext(collection *mongo.Collection) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), previewTimeout)
+	defer cancel()
+
+	// claiming pushes the due time past the timeout, so a worker that dies mid-way only delays it
+	now := time.Now()
+	var applicant models.Applicant
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "previewsDueAt", Value: 1}}).
+		SetProjection(bson.M{"resume": 1, "coverLetter": 1, "image": 1, "previewsDueAt": 1}).
+		SetReturnDocument(options.After)
+	err := collection.FindOneAndUpdate(ctx,
+		bson.M{"previewsDueAt": bson.M{"$lte": now}},
+		bson.M{"$set": bson.M{"previewsDueAt": now.Add(previewTimeout)}},
+		opts).Decode(&applicant)
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			log.Println("MongoDB claim previews error:", err)
+		}
+		return false
+	}
+
+	generatePreviews(ctx, collection, &applicant)
+	return true
+}
+
+// generatePreviews derives the display copies of a claimed application's files and links each
+// from its file, as long as the application still has that file; a copy of a file replaced in
+// the meantime is deleted again
+func generatePreviews(ctx context.Context, collection *mongo.Collection, applicant *models.Applicant) {
+	for field, file := range map[string]*models.FileInfo{
+		"resume":      applicant.Resume,
+		"coverLetter": applicant.CoverLetter,
+		"image":       applicant.Image,
+	} {
+		if !needsPreview(file) {
+			continue
+		}
+		attachDerivedFiles(ctx, file)
+
+		key, derived := "thumbnail", file.Thumbnail
+		if derived == nil {
+			key, derived = "preview", file.Preview
+		}
+		if derived == nil {
+			continue
+		}
+		result, err := collection.UpdateOne(ctx,
+			bson.M{"_id": applicant.ID, field + ".fileId": file.FileID},
+			bson.M{"$set": bson.M{field + "." + key: derived}})
+		if err != nil {
+			log.Println("MongoDB Update preview error:", err)
+		}
+		if err != nil || result.MatchedCount == 0 {
+			deleteStoredFile(ctx, derived)
+		}
+	}
+
+	// files uploaded while this ran queued the application again with a new due time, which stays
+	filter := bson.M{"_id": applicant.ID, "previewsDueAt": applicant.PreviewsDueAt}
+	if _, err := collection.UpdateOne(ctx, filter, bson.M{"$unset": bson.M{"previewsDueAt": ""}}); err != nil {
+		log.Println("MongoDB Update previews error:", err)
+	}
+}
@@ -0,0 +1,99 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"testing"
+	"time"
+
+	"backend/models"
+	"backend/scan"
+	"backend/storage"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestPreviewsDue(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []*models.FileInfo
+		want  bool
+	}{
+		{"no files", nil, false},
+		{"docx resume", []*models.FileInfo{{MimeType: docxMimeType}}, false},
+		{"headshot", []*models.FileInfo{nil, {MimeType: "image/png"}}, true},
+		{"pdf resume", []*models.FileInfo{{MimeType: "application/pdf"}}, true},
+		{"already has a thumbnail", []*models.FileInfo{{MimeType: "image/png", Thumbnail: &models.FileInfo{}}}, false},
+		{"infected", []*models.FileInfo{{MimeType: "image/png", ScanStatus: scan.StatusInfected}}, false},
+	}
+	for _, tt := range tests {
+		if got := previewsDue(tt.files...); (got != nil) != tt.want {
+			t.Errorf("%s: previewsDue() = %v, want due = %t", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGeneratePreviews(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	var headshot bytes.Buffer
+	if err := png.Encode(&headshot, image.NewNRGBA(image.Rect(0, 0, 1024, 768))); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		matched    int
+		wantStored int
+	}{
+		{"linked from the file", 1, 2},
+		{"file replaced meanwhile", 0, 1},
+	}
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			dir := setupLocalStorage(t, mt)
+			fileID, err := storage.Default().Put(context.Background(), "me.png", bytes.NewReader(headshot.Bytes()))
+			if err != nil {
+				mt.Fatal(err)
+			}
+			due := time.Now()
+			applicant := &models.Applicant{
+				ID:            primitive.NewObjectID(),
+				Image:         &models.FileInfo{FileID: fileID, FileName: "me.png", MimeType: "image/png", Storage: storage.Local},
+				PreviewsDueAt: &due,
+			}
+
+			mt.AddMockResponses(
+				mtest.CreateSuccessResponse(bson.E{Key: "n", Value: tt.matched}),
+				mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+			)
+			generatePreviews(context.Background(), mt.Coll, applicant)
+
+			events := mt.GetAllStartedEvents()
+			if len(events) != 2 {
+				mt.Fatalf("commands = %v, want the thumbnail linked and the application cleared", commandNames(mt))
+			}
+			link := events[0].Command.Lookup("updates", "0").Document()
+			if id := link.Lookup("q", "image.fileId").StringValue(); id != fileID {
+				mt.Errorf("thumbnail linked to file %q, want only while the image is still %q", id, fileID)
+			}
+			if mimeType := link.Lookup("u", "$set", "image.thumbnail", "mimeType").StringValue(); mimeType != "image/png" {
+				mt.Errorf("thumbnail type = %q, want PNG for a transparent headshot", mimeType)
+			}
+			clear := events[1].Command.Lookup("updates", "0").Document()
+			if _, err := clear.LookupErr("u", "$unset", "previewsDueAt"); err != nil {
+				mt.Errorf("previewsDueAt not cleared: %v", clear)
+			}
+			if claimed := clear.Lookup("q", "previewsDueAt").Time(); !claimed.Equal(due.Truncate(time.Millisecond)) {
+				mt.Errorf("cleared previewsDueAt %v, want only the claimed %v", claimed, due)
+			}
+			if n := countStoredFiles(mt, dir); n != tt.wantStored {
+				mt.Errorf("%d files stored, want %d", n, tt.wantStored)
+			}
+		})
+	}
+}
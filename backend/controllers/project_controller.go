@@ -6,14 +6,22 @@ import (
 	"log"
 	"math"
 	"net/http"
+	netmail "net/mail"
+	"strings"
 	"time"
 
 	"backend/db"
+	"backend/mail"
+	"backend/middleware"
 	"backend/models"
+	"backend/webhook"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/go-chi/chi/v5"
 )
 
 type ProjectController struct {
@@ -60,27 +68,386 @@ func (pc *ProjectController) Create(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Project name is required", http.StatusBadRequest)
 		return
 	}
+	if !models.ValidDuplicatePolicy(project.DuplicatePolicy) {
+		http.Error(w, "Invalid duplicate policy", http.StatusBadRequest)
+		return
+	}
+
+	if msg := validateStageNames(stageNames(project.Stages)); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	if project.AutoClose != nil && (project.AutoClose.TopK < 1 || project.AutoClose.Window < 1) {
+		http.Error(w, "autoClose needs a positive topK and window", http.StatusBadRequest)
+		return
+	}
+	if msg := validateReviewers(project.Reviewers); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	if project.VoteQuota < 0 {
+		http.Error(w, "voteQuota can't be negative", http.StatusBadRequest)
+		return
+	}
+	for name, t := range project.EmailTemplates {
+		if err := mail.Validate(name, t); err != nil {
+			http.Error(w, "Invalid "+name+" email template: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
 
 	project.ID = primitive.NewObjectID()
 	project.CompletedComparisons = 0
+	project.CurrentStage = 0
+	// whoever creates the project owns it; owners are added afterwards by an owner, never claimed here
+	project.OwnerIDs = nil
+	if userID := middleware.UserID(r.Context()); userID != "" {
+		project.OwnerIDs = []string{userID}
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	if taken, err := pc.claimedFormID(ctx, project.ExternalFormIDs, project.ID); err != nil || taken != "" {
+		writeFormIDConflict(w, taken, err)
+		return
+	}
+
 	project.TotalComparisons = calculateSwissTotalComparisons(int(150)) // NEED APPLICATIONS IN MONGODB
 
-	_, err := pc.collection.InsertOne(ctx, project)
+	secret, err := webhook.GenerateSecret()
+	if err != nil {
+		http.Error(w, "Failed to create project", http.StatusInternalServerError)
+		log.Println(err)
+		return
+	}
+	project.WebhookSecret = secret
+
+	_, err = pc.collection.InsertOne(ctx, project)
 	if err != nil {
 		http.Error(w, "Failed to create project", http.StatusInternalServerError)
 		log.Println("mongoDB Insert project error:", err)
 		return
 	}
 
+	// the secret is only ever returned here and on rotation, never from GetAll
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(struct {
+		models.Project
+		WebhookSecret string `json:"webhookSecret"`
+	}{project, project.WebhookSecret})
+}
+
+// Update changes a project's name and settings. Only the fields present in the body are updated,
+// and only by the project's owners.
+func (pc *ProjectController) Update(w http.ResponseWriter, r *http.Request) {
+	projectID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid Project ID", http.StatusBadRequest)
+		return
+	}
+
+	var request struct {
+		Name            *string   `json:"name"`
+		DuplicatePolicy *string   `json:"duplicatePolicy"`
+		ExternalFormIDs *[]string `json:"externalFormIds"`
+		BlindReview     *bool     `json:"blindReview"`
+		OwnerIDs        *[]string `json:"ownerIds"`
+		// stage names in order; stages already reached can be renamed but not removed
+		Stages *[]string `json:"stages"`
+		// close or reopen the current stage for review
+		Closed *bool `json:"closed"`
+		// {"topK": 0} turns auto-close off
+		AutoClose *models.AutoClose `json:"autoClose"`
+		Reviewers      *[]models.Reviewer `json:"reviewers"`
+		VoteQuota      *int               `json:"voteQuota"`
+		IntakeReceipts *bool              `json:"intakeReceipts"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid JSON input", http.StatusBadRequest)
+		return
+	}
+
+	set := bson.M{}
+	if request.Name != nil {
+		if *request.Name == "" {
+			http.Error(w, "Project name is required", http.StatusBadRequest)
+			return
+		}
+		set["name"] = *request.Name
+	}
+	if request.DuplicatePolicy != nil {
+		if !models.ValidDuplicatePolicy(*request.DuplicatePolicy) {
+			http.Error(w, "Invalid duplicate policy", http.StatusBadRequest)
+			return
+		}
+		set["duplicatePolicy"] = *request.DuplicatePolicy
+	}
+	if request.BlindReview != nil {
+		set["blindReview"] = *request.BlindReview
+	}
+	if request.OwnerIDs != nil {
+		if len(*request.OwnerIDs) == 0 {
+			http.Error(w, "A project needs at least one owner", http.StatusBadRequest)
+			return
+		}
+		set["ownerIds"] = *request.OwnerIDs
+	}
+	if request.Stages != nil {
+		if msg := validateStageNames(*request.Stages); msg != "" {
+			http.Error(w, msg, http.StatusBadRequest)
+			return
+		}
+	}
+	unset := bson.M{}
+	if request.Closed != nil {
+		set["closed"] = *request.Closed
+		if *request.Closed {
+			set["closedAt"] = time.Now()
+		} else {
+			unset["closedAt"] = ""
+		}
+	}
+	if request.AutoClose != nil {
+		switch {
+		case request.AutoClose.TopK == 0:
+			unset["autoClose"] = ""
+		case request.AutoClose.TopK < 0 || request.AutoClose.Window < 1:
+			http.Error(w, "autoClose needs a positive topK and window", http.StatusBadRequest)
+			return
+		default:
+			set["autoClose"] = *request.AutoClose
+		}
+	}
+	if request.Reviewers != nil {
+		if msg := validateReviewers(*request.Reviewers); msg != "" {
+			http.Error(w, msg, http.StatusBadRequest)
+			return
+		}
+		set["reviewers"] = *request.Reviewers
+	}
+	if request.VoteQuota != nil {
+		if *request.VoteQuota < 0 {
+			http.Error(w, "voteQuota can't be negative", http.StatusBadRequest)
+			return
+		}
+		set["voteQuota"] = *request.VoteQuota
+	}
+	if request.IntakeReceipts != nil {
+		set["intakeReceipts"] = *request.IntakeReceipts
+	}
+	if len(set) == 0 && len(unset) == 0 && request.ExternalFormIDs == nil && request.Stages == nil {
+		http.Error(w, "No fields to update", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var existing models.Project
+	if err := pc.collection.FindOne(ctx, bson.M{"_id": projectID}).Decode(&existing); err != nil {
+		if err == mongo.ErrNoDocuments {
+			http.Error(w, "Project not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to update project", http.StatusInternalServerError)
+		log.Println("mongoDB Find project error:", err)
+		return
+	}
+	if len(existing.OwnerIDs) > 0 && !existing.IsOwner(middleware.UserID(r.Context())) {
+		http.Error(w, "Only project owners can change the project", http.StatusForbidden)
+		return
+	}
+	if request.Stages != nil {
+		if len(*request.Stages) <= existing.CurrentStage {
+			http.Error(w, "Stages up to the current one can't be removed", http.StatusBadRequest)
+			return
+		}
+		stages := make([]models.Stage, len(*request.Stages))
+		for i, name := range *request.Stages {
+			stages[i].Name = name
+			if i < len(existing.Stages) {
+				stages[i].StartedAt = existing.Stages[i].StartedAt
+			}
+		}
+		set["stages"] = stages
+	}
+
+	if request.ExternalFormIDs != nil {
+		if taken, err := pc.claimedFormID(ctx, *request.ExternalFormIDs, projectID); err != nil || taken != "" {
+			writeFormIDConflict(w, taken, err)
+			return
+		}
+		set["externalFormIds"] = *request.ExternalFormIDs
+	}
+
+	update := bson.M{}
+	if len(set) > 0 {
+		update["$set"] = set
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	var project models.Project
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err = pc.collection.FindOneAndUpdate(ctx, bson.M{"_id": projectID}, update, opts).Decode(&project)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			http.Error(w, "Project not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to update project", http.StatusInternalServerError)
+		log.Println("mongoDB Update project error:", err)
+		return
+	}
+
+	reason := "updated"
+	if request.Closed != nil && *request.Closed {
+		reason = "closed"
+	} else if request.Closed != nil {
+		reason = "reopened"
+	}
+	publishProjectStatus(&project, reason)
+
+	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(project)
 }
 
+func stageNames(stages []models.Stage) []string {
+	names := make([]string, len(stages))
+	for i, stage := range stages {
+		names[i] = stage.Name
+	}
+	return names
+}
+
+// validateStageNames returns what is wrong with a list of stage names, or "" when nothing is
+func validateStageNames(names []string) string {
+	seen := map[string]bool{}
+	for _, name := range names {
+		if strings.TrimSpace(name) == "" {
+			return "Stage names are required"
+		}
+		if seen[name] {
+			return "Stage names must be unique"
+		}
+		seen[name] = true
+	}
+	return ""
+}
+
+// validateReviewers returns what is wrong with a reviewer list, or "" when nothing is
+func validateReviewers(reviewers []models.Reviewer) string {
+	seen := map[string]bool{}
+	for _, reviewer := range reviewers {
+		if reviewer.UserID == "" {
+			return "Reviewers need a userId"
+		}
+		if seen[reviewer.UserID] {
+			return "Reviewers must be unique"
+		}
+		seen[reviewer.UserID] = true
+		if reviewer.Email != "" {
+			if _, err := netmail.ParseAddress(reviewer.Email); err != nil {
+				return "Invalid reviewer email " + reviewer.Email
+			}
+		}
+	}
+	return ""
+}
+
+// claimedFormID returns the first of formIDs already registered to a project other than projectID,
+// so every external form routes to exactly one project
+func (pc *ProjectController) claimedFormID(ctx context.Context, formIDs []string, projectID primitive.ObjectID) (string, error) {
+	if len(formIDs) == 0 {
+		return "", nil
+	}
+
+	var other models.Project
+	err := pc.collection.FindOne(ctx, bson.M{"_id": bson.M{"$ne": projectID}, "externalFormIds": bson.M{"$in": formIDs}}).Decode(&other)
+	if err == mongo.ErrNoDocuments {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	for _, id := range formIDs {
+		for _, claimed := range other.ExternalFormIDs {
+			if id == claimed {
+				return id, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+func writeFormIDConflict(w http.ResponseWriter, formID string, err error) {
+	if err != nil {
+		http.Error(w, "Failed to check external form ids", http.StatusInternalServerError)
+		log.Println("MongoDB Find project error:", err)
+		return
+	}
+	http.Error(w, "Form "+formID+" is already registered to another project", http.StatusConflict)
+}
+
+// RotateWebhookSecret replaces the secret used to verify form webhooks for a project
+func (pc *ProjectController) RotateWebhookSecret(w http.ResponseWriter, r *http.Request) {
+	projectID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid Project ID", http.StatusBadRequest)
+		return
+	}
+
+	// whoever holds the secret can submit applications, so only the project's owners may mint one
+	userID := middleware.UserID(r.Context())
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var project models.Project
+	if err := pc.collection.FindOne(ctx, bson.M{"_id": projectID}).Decode(&project); err != nil {
+		if err == mongo.ErrNoDocuments {
+			http.Error(w, "Project not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to rotate webhook secret", http.StatusInternalServerError)
+		log.Println("mongoDB Find project error:", err)
+		return
+	}
+	if !project.IsOwner(userID) {
+		http.Error(w, "Only project owners can rotate the webhook secret", http.StatusForbidden)
+		return
+	}
+
+	secret, err := webhook.GenerateSecret()
+	if err != nil {
+		http.Error(w, "Failed to rotate webhook secret", http.StatusInternalServerError)
+		log.Println(err)
+		return
+	}
+
+	// the owner filter keeps a concurrent change of owners from letting a former owner through
+	result, err := pc.collection.UpdateOne(ctx, bson.M{"_id": projectID, "ownerIds": userID}, bson.M{"$set": bson.M{"webhookSecret": secret}})
+	if err != nil {
+		http.Error(w, "Failed to rotate webhook secret", http.StatusInternalServerError)
+		log.Println("mongoDB Update project error:", err)
+		return
+	}
+	if result.MatchedCount == 0 {
+		http.Error(w, "Only project owners can rotate the webhook secret", http.StatusForbidden)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"webhookSecret": secret})
+}
+
 func calculateSwissTotalComparisons(numApplicants int) int {
 	if numApplicants < 2 {
 		return 0
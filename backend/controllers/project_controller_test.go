@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"backend/middleware"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestCreateProjectOwnedByCaller(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("ownerIds in the body", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+		pc := &ProjectController{collection: mt.Coll}
+
+		body := `{"name":"Fall recruiting","ownerIds":["user_mallory","user_alice"]}`
+		r := httptest.NewRequest(http.MethodPost, "/api/projects", strings.NewReader(body))
+		r = r.WithContext(middleware.WithUserID(r.Context(), "user_alice"))
+		w := httptest.NewRecorder()
+		pc.Create(w, r)
+		if w.Code != http.StatusCreated {
+			mt.Fatalf("status = %d: %s", w.Code, w.Body)
+		}
+
+		owners, err := insertedDocument(mt).Lookup("ownerIds").Array().Values()
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if len(owners) != 1 || owners[0].StringValue() != "user_alice" {
+			mt.Fatalf("ownerIds = %v, want only the caller", owners)
+		}
+	})
+}
+
+func TestUpdateProjectRequiresOwner(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	projectID := primitive.NewObjectID()
+	owned := bson.D{{Key: "_id", Value: projectID}, {Key: "name", Value: "Fall"}, {Key: "ownerIds", Value: bson.A{"user_alice"}}}
+	ownerless := bson.D{{Key: "_id", Value: projectID}, {Key: "name", Value: "Fall"}}
+	updated := bson.D{{Key: "_id", Value: projectID}, {Key: "name", Value: "Spring"}}
+
+	tests := []struct {
+		name       string
+		body       string
+		userID     string
+		existing   bson.D
+		wantStatus int
+	}{
+		{"owner renames", `{"name":"Spring"}`, "user_alice", owned, http.StatusOK},
+		{"reviewer renames", `{"name":"Spring"}`, "user_bob", owned, http.StatusForbidden},
+		{"reviewer closes", `{"closed":true}`, "user_bob", owned, http.StatusForbidden},
+		{"reviewer changes the quota", `{"voteQuota":1}`, "user_bob", owned, http.StatusForbidden},
+		{"reviewer registers a form", `{"externalFormIds":["theirs"]}`, "user_bob", owned, http.StatusForbidden},
+		{"reviewer makes themselves owner", `{"ownerIds":["user_bob"]}`, "user_bob", owned, http.StatusForbidden},
+		{"not signed in", `{"name":"Spring"}`, "", owned, http.StatusForbidden},
+		{"project without owners", `{"name":"Spring"}`, "user_bob", ownerless, http.StatusOK},
+	}
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			mt.AddMockResponses(mtest.CreateCursorResponse(0, "db.projects", mtest.FirstBatch, tt.existing))
+			if tt.wantStatus == http.StatusOK {
+				mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "value", Value: updated}))
+			}
+			pc := &ProjectController{collection: mt.Coll}
+
+			r := httptest.NewRequest(http.MethodPatch, "/api/projects/"+projectID.Hex(), strings.NewReader(tt.body))
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", projectID.Hex())
+			ctx := context.WithValue(r.Context(), chi.RouteCtxKey, rctx)
+			if tt.userID != "" {
+				ctx = middleware.WithUserID(ctx, tt.userID)
+			}
+			w := httptest.NewRecorder()
+			pc.Update(w, r.WithContext(ctx))
+
+			if w.Code != tt.wantStatus {
+				mt.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			for _, name := range commandNames(mt) {
+				if name == "findAndModify" && tt.wantStatus != http.StatusOK {
+					mt.Fatal("project updated by someone who doesn't own it")
+				}
+			}
+		})
+	}
+}
+
+func TestUpdateProjectKeepsAnOwner(t *testing.T) {
+	pc := &ProjectController{}
+	r := httptest.NewRequest(http.MethodPatch, "/api/projects/"+primitive.NewObjectID().Hex(), strings.NewReader(`{"ownerIds":[]}`))
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", primitive.NewObjectID().Hex())
+	w := httptest.NewRecorder()
+	pc.Update(w, r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx)))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", w.Code)
+	}
+}
@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"backend/events"
+	"backend/models"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// how often an idle stream gets a comment line, so proxies don't time it out
+const eventStreamHeartbeat = 25 * time.Second
+
+// Events streams a project's activity as Server-Sent Events: vote-recorded, ranking-changed,
+// applicant-added and project-status. Each message's data is the JSON events.Event. Events are
+// only delivered while connected; a client that reconnects should refetch what it shows.
+func (pc *ProjectController) Events(w http.ResponseWriter, r *http.Request) {
+	projectID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid Project ID")
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "Streaming is not supported")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	err = pc.collection.FindOne(ctx, bson.M{"_id": projectID}).Err()
+	cancel()
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			writeError(w, http.StatusNotFound, "Project not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "Failed to fetch project")
+		log.Println("MongoDB Find project error:", err)
+		return
+	}
+
+	stream, unsubscribe := events.Subscribe(projectID, events.DefaultBuffer)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// stop nginx buffering the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "retry: 3000\n\n")
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(eventStreamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case event := <-stream:
+			data, err := json.Marshal(event)
+			if err != nil {
+				log.Println("Error encoding event:", err)
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// projectStatus is the data of a project-status event
+type projectStatus struct {
+	// "updated", "closed", "reopened", "auto-closed" or "stage-cut"
+	Reason       string     `json:"reason"`
+	Closed       bool       `json:"closed"`
+	ClosedAt     *time.Time `json:"closedAt,omitempty"`
+	CurrentStage int        `json:"currentStage"`
+}
+
+// publish sends an event to the project's live listeners and queues it for the project's webhooks.
+// The deliveries are written before it returns, so webhooks don't miss events the way a slow
+// stream subscriber can.
+func publish(kind string, projectID primitive.ObjectID, data interface{}) {
+	event := events.Publish(kind, projectID, data)
+	if webhookOutbox != nil {
+		webhookOutbox.enqueueEvent(event)
+	}
+}
+
+func publishProjectStatus(project *models.Project, reason string) {
+	publish(events.ProjectStatus, project.ID, projectStatus{
+		Reason:       reason,
+		Closed:       project.Closed,
+		ClosedAt:     project.ClosedAt,
+		CurrentStage: project.CurrentStage,
+	})
+	if reason == "closed" || reason == "auto-closed" {
+		publish(events.ProjectClosed, project.ID, map[string]interface{}{"reason": reason, "closedAt": project.ClosedAt})
+	}
+}
+
+// publishRankingChanged tells listeners to refetch the ranking of a project stage
+func publishRankingChanged(projectID primitive.ObjectID, stage int) {
+	publish(events.RankingChanged, projectID, map[string]int{"stage": stage})
+}
@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"backend/events"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestProjectEventsStream(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("published event", func(mt *mtest.T) {
+		projectID := primitive.NewObjectID()
+		mt.AddMockResponses(mtest.CreateCursorResponse(1, "db.projects", mtest.FirstBatch, bson.D{{Key: "_id", Value: projectID}}))
+
+		pc := &ProjectController{collection: mt.Coll}
+		router := chi.NewRouter()
+		router.Get("/projects/{id}/events", pc.Events)
+		server := httptest.NewServer(router)
+		defer server.Close()
+
+		resp, err := http.Get(server.URL + "/projects/" + projectID.Hex() + "/events")
+		if err != nil {
+			mt.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+			mt.Fatalf("status = %d, content type %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+		}
+
+		// the handler subscribes before writing its headers, so nothing published now is missed
+		events.Publish(events.RankingChanged, primitive.NewObjectID(), map[string]int{"stage": 9})
+		events.Publish(events.RankingChanged, projectID, map[string]int{"stage": 1})
+
+		reader := bufio.NewReader(resp.Body)
+		var name, data string
+		for data == "" {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				mt.Fatalf("stream ended before an event: %v", err)
+			}
+			line = strings.TrimSuffix(line, "\n")
+			if v, ok := strings.CutPrefix(line, "event: "); ok {
+				name = v
+			}
+			if v, ok := strings.CutPrefix(line, "data: "); ok {
+				data = v
+			}
+		}
+
+		if name != events.RankingChanged {
+			mt.Errorf("event = %q, want %q", name, events.RankingChanged)
+		}
+		var event struct {
+			Type      string             `json:"type"`
+			ProjectID primitive.ObjectID `json:"projectId"`
+			Data      map[string]int     `json:"data"`
+		}
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			mt.Fatal(err)
+		}
+		if event.Type != events.RankingChanged || event.ProjectID != projectID || event.Data["stage"] != 1 {
+			mt.Errorf("event = %+v, want the stage 1 ranking change of project %s", event, projectID.Hex())
+		}
+	})
+
+	mt.Run("unknown project", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "db.projects", mtest.FirstBatch))
+		pc := &ProjectController{collection: mt.Coll}
+		r := withURLParams(httptest.NewRequest(http.MethodGet, "/", nil), map[string]string{"id": primitive.NewObjectID().Hex()})
+		w := httptest.NewRecorder()
+		pc.Events(w, r)
+		if w.Code != http.StatusNotFound {
+			mt.Fatalf("status = %d, want 404", w.Code)
+		}
+	})
+}
@@ -0,0 +1,341 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"backend/middleware"
+	"backend/models"
+	"backend/webhook"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Payloads larger than this are quarantined without their body
+const maxQuarantinePayload = 8 << 10
+
+// Each sender may quarantine at most quarantineRateLimit submissions per quarantineRateWindow
+const (
+	quarantineRateLimit  = 20
+	quarantineRateWindow = time.Hour
+)
+
+// quarantineLimiter counts the submissions each sender quarantined in the current window, so an
+// unauthenticated caller can't fill the collection
+type quarantineLimiter struct {
+	mu      sync.Mutex
+	windows map[string]*quarantineWindow
+}
+
+type quarantineWindow struct {
+	start time.Time
+	count int
+}
+
+func newQuarantineLimiter() *quarantineLimiter {
+	return &quarantineLimiter{windows: make(map[string]*quarantineWindow)}
+}
+
+// allow records a submission from sender and reports whether it is within the limit
+func (l *quarantineLimiter) allow(sender string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for key, window := range l.windows {
+		if now.Sub(window.start) >= quarantineRateWindow {
+			delete(l.windows, key)
+		}
+	}
+
+	window, ok := l.windows[sender]
+	if !ok {
+		window = &quarantineWindow{start: now}
+		l.windows[sender] = window
+	}
+	if window.count >= quarantineRateLimit {
+		return false
+	}
+	window.count++
+	return true
+}
+
+// senderHost is the address a webhook came from, without its port
+func senderHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// signingProject returns the project whose webhook secret the request's signature verifies
+// against, or NilObjectID. Only then is the payload from a sender we trust enough to keep, and
+// only that project's owners get to see it.
+func (fc *FormResponseController) signingProject(r *http.Request, source string, body []byte) primitive.ObjectID {
+	header := r.Header.Get(webhook.SignatureHeader)
+	if source == "typeform" {
+		header = r.Header.Get(webhook.TypeformSignatureHeader)
+	}
+	if header == "" {
+		return primitive.NilObjectID
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetProjection(bson.M{"webhookSecret": 1})
+	cursor, err := fc.projects.Find(ctx, bson.M{"webhookSecret": bson.M{"$nin": bson.A{"", nil}}}, opts)
+	if err != nil {
+		log.Println("MongoDB Find project secrets error:", err)
+		return primitive.NilObjectID
+	}
+	defer cursor.Close(ctx)
+
+	now := time.Now()
+	for cursor.Next(ctx) {
+		var project models.Project
+		if err := cursor.Decode(&project); err != nil {
+			continue
+		}
+		if source == "typeform" {
+			err = webhook.VerifyTypeform(project.WebhookSecret, header, body)
+		} else {
+			err = webhook.Verify(project.WebhookSecret, header, body, now, webhook.DefaultTolerance)
+		}
+		if err == nil {
+			return project.ID
+		}
+	}
+	return primitive.NilObjectID
+}
+
+func (fc *FormResponseController) quarantine(r *http.Request, source, formId string, body []byte, reason string) (primitive.ObjectID, error) {
+	entry := models.QuarantinedSubmission{
+		ID:         primitive.NewObjectID(),
+		Source:     source,
+		FormID:     formId,
+		Reason:     reason,
+		RemoteAddr: r.RemoteAddr,
+		ReceivedAt: time.Now(),
+		ProjectID:  fc.signingProject(r, source, body),
+	}
+	entry.Verified = !entry.ProjectID.IsZero()
+	// unsigned payloads are only recorded, their contents are never stored or ingested
+	if entry.Verified {
+		if len(body) <= maxQuarantinePayload {
+			entry.Payload = string(body)
+		} else {
+			entry.PayloadTruncated = true
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, err := fc.quarantined.InsertOne(ctx, entry); err != nil {
+		return primitive.NilObjectID, err
+	}
+	log.Printf("Quarantined %s submission %s for form %q: %s (verified: %t)", source, entry.ID.Hex(), formId, reason, entry.Verified)
+	return entry.ID, nil
+}
+
+// ownedProject loads a project and checks the caller owns it, answering the request itself when not
+func (fc *FormResponseController) ownedProject(ctx context.Context, w http.ResponseWriter, r *http.Request, projectID primitive.ObjectID) *models.Project {
+	var project models.Project
+	if err := fc.projects.FindOne(ctx, bson.M{"_id": projectID}).Decode(&project); err != nil {
+		if err == mongo.ErrNoDocuments {
+			http.Error(w, "Project not found", http.StatusNotFound)
+			return nil
+		}
+		http.Error(w, "Failed to fetch project", http.StatusInternalServerError)
+		log.Println("MongoDB Find project error:", err)
+		return nil
+	}
+	if len(project.OwnerIDs) > 0 && !project.IsOwner(middleware.UserID(r.Context())) {
+		http.Error(w, "Only project owners can manage quarantined submissions", http.StatusForbidden)
+		return nil
+	}
+	return &project
+}
+
+// findQuarantined loads the quarantined submission in the URL, provided the caller owns the project
+// that signed it. Unsigned submissions belong to no project and can't be managed.
+func (fc *FormResponseController) findQuarantined(ctx context.Context, w http.ResponseWriter, r *http.Request) *models.QuarantinedSubmission {
+	entryID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid quarantine ID", http.StatusBadRequest)
+		return nil
+	}
+
+	var entry models.QuarantinedSubmission
+	if err := fc.quarantined.FindOne(ctx, bson.M{"_id": entryID}).Decode(&entry); err != nil {
+		if err == mongo.ErrNoDocuments {
+			http.Error(w, "Quarantined submission not found", http.StatusNotFound)
+			return nil
+		}
+		http.Error(w, "Failed to fetch quarantined submission", http.StatusInternalServerError)
+		log.Println("MongoDB Find quarantine error:", err)
+		return nil
+	}
+	if entry.ProjectID.IsZero() {
+		http.Error(w, "Quarantined submission was not signed by any project", http.StatusForbidden)
+		return nil
+	}
+	if fc.ownedProject(ctx, w, r, entry.ProjectID) == nil {
+		return nil
+	}
+	return &entry
+}
+
+// ListQuarantine returns, without their payloads, the quarantined submissions signed with the
+// secret of the project in the projectId query parameter. Only its owners may list them.
+func (fc *FormResponseController) ListQuarantine(w http.ResponseWriter, r *http.Request) {
+	projectID, err := primitive.ObjectIDFromHex(r.URL.Query().Get("projectId"))
+	if err != nil {
+		http.Error(w, "Invalid Project ID", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if fc.ownedProject(ctx, w, r, projectID) == nil {
+		return
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "receivedAt", Value: -1}}).
+		SetProjection(bson.M{"payload": 0})
+	cursor, err := fc.quarantined.Find(ctx, bson.M{"project_id": projectID}, opts)
+	if err != nil {
+		http.Error(w, "Failed to fetch quarantined submissions", http.StatusInternalServerError)
+		log.Println("MongoDB Find quarantine error:", err)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	entries := []models.QuarantinedSubmission{}
+	if err := cursor.All(ctx, &entries); err != nil {
+		http.Error(w, "Error decoding quarantined submissions", http.StatusInternalServerError)
+		log.Println("Cursor decode error:", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
+
+// AssignQuarantined ingests a quarantined submission into the given project. With registerForm
+// set, the submission's form id is also registered on the project so later submissions route themselves.
+// The caller must own both the project that signed the submission and the one it is assigned to.
+func (fc *FormResponseController) AssignQuarantined(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		ProjectID    string `json:"projectId"`
+		RegisterForm bool   `json:"registerForm"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request payload: %v", err), http.StatusBadRequest)
+		return
+	}
+	projectID, err := primitive.ObjectIDFromHex(request.ProjectID)
+	if err != nil {
+		http.Error(w, "Invalid Project ID", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	entry := fc.findQuarantined(ctx, w, r)
+	if entry == nil {
+		return
+	}
+	if entry.Payload == "" {
+		http.Error(w, "Quarantined submission has no stored payload", http.StatusUnprocessableEntity)
+		return
+	}
+
+	project := fc.ownedProject(ctx, w, r, projectID)
+	if project == nil {
+		return
+	}
+
+	sub, err := submissionFromPayload(entry.Source, entry.FormID, []byte(entry.Payload))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	// the entry stays in quarantine until it has been ingested, so a failed attempt can be retried
+	if !fc.respondIngest(w, project, sub) {
+		return
+	}
+
+	if request.RegisterForm && entry.FormID != "" {
+		_, err := fc.projects.UpdateOne(ctx, bson.M{"_id": projectID}, bson.M{"$addToSet": bson.M{"externalFormIds": entry.FormID}})
+		if err != nil {
+			log.Println("MongoDB register external form error:", err)
+		}
+	}
+
+	if _, err := fc.quarantined.DeleteOne(ctx, bson.M{"_id": entry.ID}); err != nil {
+		log.Println("MongoDB Delete quarantine error:", err)
+	}
+}
+
+// DeleteQuarantined discards a quarantined submission signed by a project the caller owns
+func (fc *FormResponseController) DeleteQuarantined(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	entry := fc.findQuarantined(ctx, w, r)
+	if entry == nil {
+		return
+	}
+
+	result, err := fc.quarantined.DeleteOne(ctx, bson.M{"_id": entry.ID})
+	if err != nil {
+		http.Error(w, "Failed to delete quarantined submission", http.StatusInternalServerError)
+		return
+	}
+	if result.DeletedCount == 0 {
+		http.Error(w, "Quarantined submission not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// submissionFromPayload re-runs the adapter a quarantined payload originally arrived through
+func submissionFromPayload(source, formId string, body []byte) (*intakeSubmission, error) {
+	switch source {
+	case "form":
+		var data models.FormResponses
+		if err := json.Unmarshal(body, &data); err != nil {
+			return nil, fmt.Errorf("error parsing quarantined payload: %v", err)
+		}
+		return normaliseFormResponses(formId, data)
+	case "typeform":
+		var payload models.TypeformWebhook
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return nil, fmt.Errorf("error parsing quarantined payload: %v", err)
+		}
+		return normaliseTypeform(formId, payload.FormResponse), nil
+	case "google-forms":
+		var payload models.GoogleFormsSubmission
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return nil, fmt.Errorf("error parsing quarantined payload: %v", err)
+		}
+		return normaliseGoogleForms(formId, payload)
+	}
+	return nil, fmt.Errorf("unknown submission source %q", source)
+}
@@ -0,0 +1,274 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/middleware"
+	"backend/webhook"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestQuarantineLimiter(t *testing.T) {
+	l := newQuarantineLimiter()
+	now := time.Unix(1700000000, 0)
+
+	for i := 0; i < quarantineRateLimit; i++ {
+		if !l.allow("10.0.0.1", now) {
+			t.Fatalf("submission %d refused inside the limit", i+1)
+		}
+	}
+	if l.allow("10.0.0.1", now.Add(time.Minute)) {
+		t.Fatal("submission over the limit allowed")
+	}
+	if !l.allow("10.0.0.2", now) {
+		t.Fatal("another sender was limited")
+	}
+	if !l.allow("10.0.0.1", now.Add(quarantineRateWindow)) {
+		t.Fatal("sender still limited in the next window")
+	}
+}
+
+func TestQuarantineKeepsOnlySignedPayloads(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	body := `{"formId":"unknown-form","timestamp":"2026-01-01T00:00:00Z","itemResponses":[]}`
+	now := time.Now()
+	projectA, projectB := primitive.NewObjectID(), primitive.NewObjectID()
+
+	tests := []struct {
+		name        string
+		signature   string
+		secrets     []bson.D
+		wantPayload bool
+	}{
+		{"signed by a project", webhook.Sign("secret-b", now, []byte(body)), []bson.D{
+			{{Key: "_id", Value: projectA}, {Key: "webhookSecret", Value: "secret-a"}},
+			{{Key: "_id", Value: projectB}, {Key: "webhookSecret", Value: "secret-b"}},
+		}, true},
+		{"signed with an unknown secret", webhook.Sign("nobody", now, []byte(body)), []bson.D{{{Key: "_id", Value: projectA}, {Key: "webhookSecret", Value: "secret-a"}}}, false},
+		{"unsigned", "", nil, false},
+	}
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			fc := &FormResponseController{
+				collection:  mt.Coll,
+				projects:    mt.Coll,
+				quarantined: mt.Coll,
+				replays:     &replayStore{collection: mt.Coll},
+				quarantines: newQuarantineLimiter(),
+			}
+
+			// no project claims the form, then the secrets lookup when there is a signature, then the insert
+			mt.AddMockResponses(mtest.CreateCursorResponse(0, "db.projects", mtest.FirstBatch))
+			if tt.signature != "" {
+				mt.AddMockResponses(mtest.CreateCursorResponse(0, "db.projects", mtest.FirstBatch, tt.secrets...))
+			}
+			mt.AddMockResponses(mtest.CreateSuccessResponse())
+
+			r := httptest.NewRequest(http.MethodPost, "/api/formResponseListener/google-forms", strings.NewReader(body))
+			if tt.signature != "" {
+				r.Header.Set(webhook.SignatureHeader, tt.signature)
+			}
+			w := httptest.NewRecorder()
+			fc.HandleGoogleForms(w, r)
+			if w.Code != http.StatusNotFound {
+				mt.Fatalf("status = %d, want 404: %s", w.Code, w.Body)
+			}
+
+			entry := insertedDocument(mt)
+			payload, _ := entry.Lookup("payload").StringValueOK()
+			if verified := entry.Lookup("verified").Boolean(); verified != tt.wantPayload {
+				mt.Errorf("verified = %t, want %t", verified, tt.wantPayload)
+			}
+			if (payload == body) != tt.wantPayload {
+				mt.Errorf("stored payload %q, want kept = %t", payload, tt.wantPayload)
+			}
+			signer, _ := entry.Lookup("project_id").ObjectIDOK()
+			if (signer == projectB) != tt.wantPayload {
+				mt.Errorf("signing project = %s, want %s recorded = %t", signer.Hex(), projectB.Hex(), tt.wantPayload)
+			}
+		})
+	}
+}
+
+func TestQuarantineCapsPayload(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("oversized", func(mt *mtest.T) {
+		fc := &FormResponseController{projects: mt.Coll, quarantined: mt.Coll}
+		body := []byte(strings.Repeat("x", maxQuarantinePayload+1))
+
+		r := httptest.NewRequest(http.MethodPost, "/api/formResponseListener", nil)
+		r.Header.Set(webhook.SignatureHeader, webhook.Sign("secret", time.Now(), body))
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "db.projects", mtest.FirstBatch, bson.D{{Key: "_id", Value: primitive.NewObjectID()}, {Key: "webhookSecret", Value: "secret"}}),
+			mtest.CreateSuccessResponse(),
+		)
+		if _, err := fc.quarantine(r, "form", "f1", body, "test"); err != nil {
+			mt.Fatal(err)
+		}
+
+		entry := insertedDocument(mt)
+		if payload, _ := entry.Lookup("payload").StringValueOK(); payload != "" {
+			mt.Errorf("stored a %d byte payload over the cap", len(payload))
+		}
+		if !entry.Lookup("payloadTruncated").Boolean() {
+			mt.Error("payloadTruncated not set")
+		}
+	})
+}
+
+func TestQuarantineRateLimited(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("limit reached", func(mt *mtest.T) {
+		fc := &FormResponseController{projects: mt.Coll, quarantined: mt.Coll, quarantines: newQuarantineLimiter()}
+		for i := 0; i < quarantineRateLimit; i++ {
+			fc.quarantines.allow("192.0.2.1", time.Now())
+		}
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "db.projects", mtest.FirstBatch))
+		r := httptest.NewRequest(http.MethodPost, "/api/formResponseListener/google-forms", strings.NewReader(`{"formId":"f1"}`))
+		w := httptest.NewRecorder()
+		fc.HandleGoogleForms(w, r)
+		if w.Code != http.StatusTooManyRequests {
+			mt.Fatalf("status = %d, want 429", w.Code)
+		}
+		for _, event := range mt.GetAllStartedEvents() {
+			if event.CommandName == "insert" {
+				mt.Fatal("quarantined a submission over the rate limit")
+			}
+		}
+	})
+}
+
+func TestAssignQuarantinedKeepsEntryUntilIngested(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	entryID, projectID := primitive.NewObjectID(), primitive.NewObjectID()
+	payload := `{"formId":"f1","timestamp":"2026-01-01T00:00:00Z","itemResponses":[{"title":"First name","itemType":"TEXT","response":"Ada"}]}`
+
+	tests := []struct {
+		name       string
+		insert     bson.D
+		wantStatus int
+		wantDelete bool
+	}{
+		{"ingest fails", mtest.CreateWriteErrorsResponse(mtest.WriteError{Index: 0, Code: 2, Message: "boom"}), http.StatusInternalServerError, false},
+		{"ingested", mtest.CreateSuccessResponse(), http.StatusCreated, true},
+	}
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			fc := &FormResponseController{collection: mt.Coll, projects: mt.Coll, quarantined: mt.Coll}
+			mt.AddMockResponses(
+				mtest.CreateCursorResponse(0, "db.quarantine", mtest.FirstBatch, bson.D{
+					{Key: "_id", Value: entryID}, {Key: "source", Value: "google-forms"}, {Key: "formId", Value: "f1"},
+					{Key: "payload", Value: payload}, {Key: "verified", Value: true}, {Key: "project_id", Value: projectID},
+				}),
+				mtest.CreateCursorResponse(0, "db.projects", mtest.FirstBatch, bson.D{{Key: "_id", Value: projectID}}),
+				mtest.CreateCursorResponse(0, "db.projects", mtest.FirstBatch, bson.D{{Key: "_id", Value: projectID}}),
+				mtest.CreateCursorResponse(0, "db.applicants", mtest.FirstBatch),
+				tt.insert,
+				mtest.CreateSuccessResponse(),
+			)
+
+			r := httptest.NewRequest(http.MethodPost, "/api/quarantine/"+entryID.Hex()+"/assign", strings.NewReader(`{"projectId":"`+projectID.Hex()+`"}`))
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", entryID.Hex())
+			r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+			w := httptest.NewRecorder()
+			fc.AssignQuarantined(w, r)
+
+			if w.Code != tt.wantStatus {
+				mt.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			deleted := false
+			for _, event := range mt.GetAllStartedEvents() {
+				deleted = deleted || event.CommandName == "delete"
+			}
+			if deleted != tt.wantDelete {
+				mt.Fatalf("quarantine entry deleted = %t, want %t", deleted, tt.wantDelete)
+			}
+		})
+	}
+}
+
+func TestQuarantineRequiresProjectOwner(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	entryID, signer, destination := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	entry := bson.D{
+		{Key: "_id", Value: entryID}, {Key: "source", Value: "form"}, {Key: "formId", Value: "f1"},
+		{Key: "payload", Value: `{"responses":[]}`}, {Key: "verified", Value: true}, {Key: "project_id", Value: signer},
+	}
+	owned := func(id primitive.ObjectID, owner string) bson.D {
+		return mtest.CreateCursorResponse(0, "db.projects", mtest.FirstBatch, bson.D{{Key: "_id", Value: id}, {Key: "ownerIds", Value: bson.A{owner}}})
+	}
+	withEntry := func(r *http.Request) *http.Request {
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", entryID.Hex())
+		return r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+	}
+
+	tests := []struct {
+		name      string
+		responses []bson.D
+		request   *http.Request
+		handler   func(*FormResponseController) http.HandlerFunc
+	}{
+		{
+			"list another project's quarantine",
+			[]bson.D{owned(signer, "someone-else")},
+			httptest.NewRequest(http.MethodGet, "/api/quarantine?projectId="+signer.Hex(), nil),
+			func(fc *FormResponseController) http.HandlerFunc { return fc.ListQuarantine },
+		},
+		{
+			"assign a submission signed by another project",
+			[]bson.D{mtest.CreateCursorResponse(0, "db.quarantine", mtest.FirstBatch, entry), owned(signer, "someone-else")},
+			withEntry(httptest.NewRequest(http.MethodPost, "/api/quarantine/"+entryID.Hex()+"/assign", strings.NewReader(`{"projectId":"`+destination.Hex()+`"}`))),
+			func(fc *FormResponseController) http.HandlerFunc { return fc.AssignQuarantined },
+		},
+		{
+			"assign into a project owned by someone else",
+			[]bson.D{mtest.CreateCursorResponse(0, "db.quarantine", mtest.FirstBatch, entry), owned(signer, "user_1"), owned(destination, "someone-else")},
+			withEntry(httptest.NewRequest(http.MethodPost, "/api/quarantine/"+entryID.Hex()+"/assign", strings.NewReader(`{"projectId":"`+destination.Hex()+`"}`))),
+			func(fc *FormResponseController) http.HandlerFunc { return fc.AssignQuarantined },
+		},
+		{
+			"delete a submission signed by another project",
+			[]bson.D{mtest.CreateCursorResponse(0, "db.quarantine", mtest.FirstBatch, entry), owned(signer, "someone-else")},
+			withEntry(httptest.NewRequest(http.MethodDelete, "/api/quarantine/"+entryID.Hex(), nil)),
+			func(fc *FormResponseController) http.HandlerFunc { return fc.DeleteQuarantined },
+		},
+		{
+			"delete an unsigned submission",
+			[]bson.D{mtest.CreateCursorResponse(0, "db.quarantine", mtest.FirstBatch, bson.D{{Key: "_id", Value: entryID}, {Key: "verified", Value: false}})},
+			withEntry(httptest.NewRequest(http.MethodDelete, "/api/quarantine/"+entryID.Hex(), nil)),
+			func(fc *FormResponseController) http.HandlerFunc { return fc.DeleteQuarantined },
+		},
+	}
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			fc := &FormResponseController{collection: mt.Coll, projects: mt.Coll, quarantined: mt.Coll}
+			mt.AddMockResponses(tt.responses...)
+
+			r := tt.request.WithContext(middleware.WithUserID(tt.request.Context(), "user_1"))
+			w := httptest.NewRecorder()
+			tt.handler(fc)(w, r)
+			if w.Code != http.StatusForbidden {
+				mt.Fatalf("status = %d, want 403: %s", w.Code, w.Body)
+			}
+			for _, name := range commandNames(mt) {
+				if name != "find" {
+					mt.Fatalf("commands = %v, want nothing listed, ingested or deleted", commandNames(mt))
+				}
+			}
+		})
+	}
+}
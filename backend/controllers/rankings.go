@@ -0,0 +1,71 @@
+package controllers
+
+import (
+	"math"
+
+	"backend/ranking"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// rankedApplicant is an applicant's line in the rankings response
+type rankedApplicant struct {
+	ID         primitive.ObjectID `json:"id"`
+	FirstName  string             `json:"firstName"`
+	LastName   string             `json:"lastName"`
+	Major      string             `json:"major"`
+	Year       string             `json:"year"`
+	Status     string             `json:"status,omitempty"`
+	Anonymised bool               `json:"anonymised,omitempty"`
+	Elo        int                `json:"elo"`
+	Wins       int                `json:"wins"`
+	Losses     int                `json:"losses"`
+	// position and percentile among the ranked applicants; absent for applicants no longer in consideration
+	Rank       int     `json:"rank,omitempty"`
+	Tied       bool    `json:"tied,omitempty"`
+	Percentile float64 `json:"percentile,omitempty"`
+	Games      int     `json:"games"`
+	WinRate    float64 `json:"winRate"`
+	// 95% interval on the rating, null before the first comparison
+	Confidence *ratingInterval `json:"confidence"`
+	// too few comparisons for the rank to mean much, see ranking.MinGames
+	LowConfidence bool `json:"lowConfidence"`
+}
+
+type ratingInterval struct {
+	Low    int `json:"low"`
+	High   int `json:"high"`
+	Margin int `json:"margin"`
+}
+
+// rankedApplicants turns ranking entries into response lines, dropping rank and percentile when ranked is false
+func rankedApplicants(entries []ranking.Entry, ranked bool) []rankedApplicant {
+	lines := make([]rankedApplicant, 0, len(entries))
+	for _, entry := range entries {
+		a := entry.Applicant
+		line := rankedApplicant{
+			ID:            a.ID,
+			FirstName:     a.FirstName,
+			LastName:      a.LastName,
+			Major:         a.Major,
+			Year:          a.Year,
+			Status:        a.Status,
+			Anonymised:    a.Anonymised,
+			Elo:           a.Elo,
+			Wins:          a.Wins,
+			Losses:        a.Losses,
+			Games:         entry.Games,
+			WinRate:       math.Round(entry.WinRate*1000) / 1000,
+			LowConfidence: entry.LowConfidence,
+		}
+		if ranked {
+			line.Rank, line.Tied, line.Percentile = entry.Rank, entry.Tied, entry.Percentile
+		}
+		if !math.IsInf(entry.Margin, 1) {
+			margin := int(math.Round(entry.Margin))
+			line.Confidence = &ratingInterval{Low: a.Elo - margin, High: a.Elo + margin, Margin: margin}
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
@@ -0,0 +1,128 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"mime"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"backend/export"
+	"backend/models"
+	"backend/ranking"
+
+	"github.com/go-chi/chi/v5"
+)
+
+var unsafeFileNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// ExportRankings downloads the ranking of a project stage as a table for the deliberation deck:
+// rank, name, major, year, rating, wins, losses, win rate and the 95% confidence margin on the
+// rating. ?format= is csv (default), xlsx or pdf and ?stage= picks an earlier stage. Blind-review
+// projects export pseudonyms to anyone but the owners.
+func (ac *ApplicantController) ExportRankings(w http.ResponseWriter, r *http.Request) {
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	if format == "" {
+		format = export.CSV
+	}
+	contentType := export.ContentType(format)
+	if contentType == "" {
+		writeValidationError(w, map[string]string{"format": "must be csv, xlsx or pdf"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	project := ac.loadProject(ctx, w, chi.URLParam(r, "id"))
+	if project == nil {
+		return
+	}
+	stage, err := stageParam(r, project)
+	if err != nil {
+		writeValidationError(w, map[string]string{"stage": err.Error()})
+		return
+	}
+
+	applicants, err := ac.rankedApplicants(ctx, project, stage)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to fetch rankings")
+		log.Println("MongoDB Find rankings error:", err)
+		return
+	}
+	ac.prepareApplicants(ctx, r, applicantPtrs(applicants)...)
+
+	table := rankingsTable(project, stage, ranking.Rank(applicants))
+
+	// rendered in full first so a failure can still be reported as an error
+	var body bytes.Buffer
+	if err := export.Write(&body, format, table); err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to export rankings")
+		log.Println("Rankings export error:", err)
+		return
+	}
+
+	fileName := unsafeFileNameChars.ReplaceAllString(fmt.Sprintf("%s-%s-rankings", project.Name, stageName(project, stage)), "-")
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": fileName + "." + format}))
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.Write(body.Bytes())
+}
+
+// rankedApplicants returns the active applicants of a stage with the ratings they have, or had at the cut
+func (ac *ApplicantController) rankedApplicants(ctx context.Context, project *models.Project, stage int) ([]models.Applicant, error) {
+	if stage < project.CurrentStage {
+		ranked, _, err := ac.closedStageRankings(ctx, project.ID, stage)
+		return ranked, err
+	}
+
+	applicants, err := ac.stageApplicants(ctx, project)
+	if err != nil {
+		return nil, err
+	}
+	active := []models.Applicant{}
+	for _, applicant := range applicants {
+		if applicant.IsActive() {
+			active = append(active, applicant)
+		}
+	}
+	return active, nil
+}
+
+func rankingsTable(project *models.Project, stage int, entries []ranking.Entry) *export.Table {
+	table := &export.Table{
+		Title:  fmt.Sprintf("%s: %s rankings (%s)", project.Name, stageName(project, stage), time.Now().Format("Jan 2, 2006")),
+		Header: []string{"Rank", "Name", "Major", "Year", "Rating", "Wins", "Losses", "Win rate (%)", "Confidence (± rating, 95%)"},
+	}
+	for _, entry := range entries {
+		a := entry.Applicant
+		var confidence interface{} = "n/a"
+		if !math.IsInf(entry.Margin, 1) {
+			confidence = int(math.Round(entry.Margin))
+		}
+		table.Rows = append(table.Rows, []interface{}{
+			entry.Rank,
+			strings.TrimSpace(a.FirstName + " " + a.LastName),
+			a.Major,
+			a.Year,
+			a.Elo,
+			a.Wins,
+			a.Losses,
+			math.Round(entry.WinRate*1000) / 10,
+			confidence,
+		})
+	}
+	return table
+}
+
+func stageName(project *models.Project, stage int) string {
+	if stage < len(project.Stages) {
+		return project.Stages[stage].Name
+	}
+	return fmt.Sprintf("Stage %d", stage+1)
+}
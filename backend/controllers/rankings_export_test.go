@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"backend/blind"
+	"backend/middleware"
+	"backend/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestExportRankingsRedactsBlindReview(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	project := models.Project{
+		ID:          primitive.NewObjectID(),
+		Name:        "Fall",
+		OwnerIDs:    []string{"user_alice"},
+		BlindReview: true,
+		Stages:      []models.Stage{{Name: "Screen"}},
+	}
+	ada := models.Applicant{ID: primitive.NewObjectID(), ProjectID: project.ID, FirstName: "Ada", LastName: "Lovelace", Major: "Maths", Elo: 1100, Wins: 3, Losses: 1}
+	grace := models.Applicant{ID: primitive.NewObjectID(), ProjectID: project.ID, FirstName: "Grace", LastName: "Hopper", Major: "Physics", Elo: 900, Wins: 1, Losses: 3}
+
+	tests := []struct {
+		name      string
+		userID    string
+		format    string
+		wantNames []string
+	}{
+		{"owner", "user_alice", "csv", []string{"Ada Lovelace", "Grace Hopper"}},
+		{"reviewer", "user_bob", "csv", []string{blind.Pseudonym(project.ID, ada.ID), blind.Pseudonym(project.ID, grace.ID)}},
+		{"reviewer xlsx", "user_bob", "xlsx", []string{blind.Pseudonym(project.ID, ada.ID), blind.Pseudonym(project.ID, grace.ID)}},
+		{"reviewer pdf", "user_bob", "pdf", []string{blind.Pseudonym(project.ID, ada.ID), blind.Pseudonym(project.ID, grace.ID)}},
+	}
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			ac := testApplicantController(mt)
+			mt.AddMockResponses(
+				cursorOf(mt, "db.projects", project),
+				cursorOf(mt, "db.applicants", ada, grace),
+				cursorOf(mt, "db.projects", project),
+			)
+			r := httptest.NewRequest(http.MethodGet, "/?format="+tt.format, nil)
+			r = r.WithContext(middleware.WithUserID(r.Context(), tt.userID))
+			w := httptest.NewRecorder()
+			ac.ExportRankings(w, withURLParams(r, map[string]string{"id": project.ID.Hex()}))
+			if w.Code != http.StatusOK {
+				mt.Fatalf("status = %d: %s", w.Code, w.Body)
+			}
+
+			body := w.Body.String()
+			if tt.format == "xlsx" {
+				body = unzipText(mt, w.Body.Bytes())
+			}
+			for _, name := range tt.wantNames {
+				if !strings.Contains(body, name) {
+					mt.Errorf("export doesn't list %q", name)
+				}
+			}
+			if tt.userID != "user_alice" {
+				for _, name := range []string{"Ada", "Lovelace", "Grace", "Hopper"} {
+					if strings.Contains(body, name) {
+						mt.Errorf("blind review export shows %q to a reviewer", name)
+					}
+				}
+			}
+		})
+	}
+
+	mt.Run("csv rows", func(mt *mtest.T) {
+		ac := testApplicantController(mt)
+		mt.AddMockResponses(
+			cursorOf(mt, "db.projects", project),
+			cursorOf(mt, "db.applicants", ada, grace),
+			cursorOf(mt, "db.projects", project),
+		)
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r = r.WithContext(middleware.WithUserID(r.Context(), "user_alice"))
+		w := httptest.NewRecorder()
+		ac.ExportRankings(w, withURLParams(r, map[string]string{"id": project.ID.Hex()}))
+
+		if ct := w.Header().Get("Content-Type"); ct != "text/csv; charset=utf-8" {
+			mt.Errorf("Content-Type = %q, want CSV by default", ct)
+		}
+		if cd := w.Header().Get("Content-Disposition"); cd != `attachment; filename=Fall-Screen-rankings.csv` {
+			mt.Errorf("Content-Disposition = %q", cd)
+		}
+		records, err := csv.NewReader(w.Body).ReadAll()
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if len(records) != 3 || records[1][0] != "1" || records[1][1] != "Ada Lovelace" || records[1][4] != "1100" || records[2][1] != "Grace Hopper" {
+			mt.Errorf("rows = %q, want Ada ranked above Grace", records)
+		}
+	})
+
+	mt.Run("unknown format", func(mt *mtest.T) {
+		ac := testApplicantController(mt)
+		w := httptest.NewRecorder()
+		ac.ExportRankings(w, withURLParams(httptest.NewRequest(http.MethodGet, "/?format=docx", nil), map[string]string{"id": project.ID.Hex()}))
+		if w.Code != http.StatusBadRequest {
+			mt.Fatalf("status = %d, want 400", w.Code)
+		}
+	})
+}
+
+// unzipText returns every file in a zip archive, such as an XLSX workbook, joined together
+func unzipText(t testing.TB, data []byte) string {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var all strings.Builder
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		all.Write(body)
+	}
+	return all.String()
+}
@@ -0,0 +1,54 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"backend/webhook"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// errReplayCheck means a webhook could not be checked against earlier deliveries; the sender should retry
+var errReplayCheck = errors.New("could not check the webhook against earlier deliveries, try again")
+
+// replayStore remembers the webhook deliveries already accepted, in the database so every API
+// instance sees them and a restart forgets none. Entries expire once a replay could no longer
+// pass signature verification.
+type replayStore struct {
+	collection *mongo.Collection
+}
+
+type replayEntry struct {
+	Key       string    `bson:"_id"`
+	ExpiresAt time.Time `bson:"expiresAt"`
+}
+
+// claim records key until expires, returning webhook.ErrReplayed if a delivery already claimed it.
+// The unique _id makes this atomic, so concurrent replays can't both get through.
+func (s *replayStore) claim(key string, expires time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := s.collection.InsertOne(ctx, replayEntry{Key: key, ExpiresAt: expires}); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return webhook.ErrReplayed
+		}
+		log.Println("MongoDB Insert replay key error:", err)
+		return errReplayCheck
+	}
+	return nil
+}
+
+// release forgets key so the sender's retry of a delivery that failed is accepted
+func (s *replayStore) release(key string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := s.collection.DeleteOne(ctx, bson.M{"_id": key}); err != nil {
+		log.Println("MongoDB Delete replay key error:", err)
+	}
+}
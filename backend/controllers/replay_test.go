@@ -0,0 +1,200 @@
+package controllers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/webhook"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestReplayStoreClaim(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	tests := []struct {
+		name     string
+		response bson.D
+		want     error
+	}{
+		{"first delivery", mtest.CreateSuccessResponse(), nil},
+		{"already claimed", mtest.CreateWriteErrorsResponse(mtest.WriteError{Index: 0, Code: 11000, Message: "duplicate key"}), webhook.ErrReplayed},
+		{"database down", mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 91, Message: "shutting down"}), errReplayCheck},
+	}
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			mt.AddMockResponses(tt.response)
+			store := &replayStore{collection: mt.Coll}
+			if err := store.claim("1700000000.abc", time.Now().Add(time.Minute)); err != tt.want {
+				mt.Fatalf("claim() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// commandNames lists the commands mt's client sent, in order
+func commandNames(mt *mtest.T) []string {
+	var names []string
+	for _, e := range mt.GetAllStartedEvents() {
+		names = append(names, e.CommandName)
+	}
+	return names
+}
+
+func TestSignedWebhookRetryAfterFailedIngest(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	const secret = "s3cr3t"
+	projectID := primitive.NewObjectID()
+	project := bson.D{{Key: "_id", Value: projectID}, {Key: "webhookSecret", Value: secret}}
+	body := `{"formId":"` + projectID.Hex() + `","timestamp":"2026-01-01T00:00:00Z","responses":[{"question":"email","answer":"ada@example.com"}]}`
+	signature := webhook.Sign(secret, time.Now(), []byte(body))
+
+	deliver := func(fc *FormResponseController) int {
+		r := httptest.NewRequest(http.MethodPost, "/api/formResponseListener", strings.NewReader(body))
+		r.Header.Set(webhook.SignatureHeader, signature)
+		w := httptest.NewRecorder()
+		fc.HandleFormResponse(w, r)
+		return w.Code
+	}
+
+	mt.Run("ingest fails", func(mt *mtest.T) {
+		fc := &FormResponseController{collection: mt.Coll, projects: mt.Coll, replays: &replayStore{collection: mt.Coll}}
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "db.projects", mtest.FirstBatch, project),
+			mtest.CreateSuccessResponse(),
+			mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 91, Message: "shutting down"}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+		)
+		if code := deliver(fc); code != http.StatusInternalServerError {
+			mt.Fatalf("status = %d, want 500", code)
+		}
+
+		// the claim is released so the sender's retry is accepted
+		events := mt.GetAllStartedEvents()
+		last := events[len(events)-1]
+		if last.CommandName != "delete" {
+			mt.Fatalf("commands = %v, want the replay key deleted last", commandNames(mt))
+		}
+		key := last.Command.Lookup("deletes", "0", "q", "_id").StringValue()
+		claimed := events[1].Command.Lookup("documents", "0", "_id").StringValue()
+		if key == "" || key != claimed {
+			mt.Fatalf("deleted replay key %q, want the claimed %q", key, claimed)
+		}
+	})
+
+	mt.Run("retry is ingested", func(mt *mtest.T) {
+		fc := &FormResponseController{collection: mt.Coll, projects: mt.Coll, replays: &replayStore{collection: mt.Coll}}
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "db.projects", mtest.FirstBatch, project),
+			mtest.CreateSuccessResponse(),
+			mtest.CreateCursorResponse(0, "db.applicants", mtest.FirstBatch),
+			mtest.CreateSuccessResponse(),
+		)
+		if code := deliver(fc); code != http.StatusCreated {
+			mt.Fatalf("status = %d, want 201", code)
+		}
+		for _, name := range commandNames(mt) {
+			if name == "delete" {
+				mt.Fatal("the replay key of an ingested delivery was released")
+			}
+		}
+	})
+
+	mt.Run("replay is refused", func(mt *mtest.T) {
+		fc := &FormResponseController{collection: mt.Coll, projects: mt.Coll, replays: &replayStore{collection: mt.Coll}}
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "db.projects", mtest.FirstBatch, project),
+			mtest.CreateWriteErrorsResponse(mtest.WriteError{Index: 0, Code: 11000, Message: "duplicate key"}),
+		)
+		if code := deliver(fc); code != http.StatusUnauthorized {
+			mt.Fatalf("status = %d, want 401", code)
+		}
+		if names := commandNames(mt); len(names) != 2 {
+			mt.Fatalf("commands = %v, want nothing ingested after the refused claim", names)
+		}
+	})
+}
+
+func TestTypeformRetryAfterFailedIngest(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	const secret = "s3cr3t"
+	projectID := primitive.NewObjectID()
+	project := bson.D{{Key: "_id", Value: projectID}, {Key: "webhookSecret", Value: secret}}
+	body := `{"event_id":"01HX","event_type":"form_response","form_response":{"form_id":"tf1","submitted_at":"2026-01-01T00:00:00Z",` +
+		`"answers":[{"type":"email","field":{"id":"a","ref":"email"},"email":"ada@example.com"}]}}`
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	signature := "sha256=" + base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+	deliver := func(fc *FormResponseController) int {
+		r := httptest.NewRequest(http.MethodPost, "/api/formResponseListener/typeform?formId="+projectID.Hex(), strings.NewReader(body))
+		r.Header.Set(webhook.TypeformSignatureHeader, signature)
+		w := httptest.NewRecorder()
+		fc.HandleTypeform(w, r)
+		return w.Code
+	}
+
+	// the first delivery claims the event id, fails to ingest and releases it
+	mt.Run("ingest fails", func(mt *mtest.T) {
+		fc := &FormResponseController{collection: mt.Coll, projects: mt.Coll, replays: &replayStore{collection: mt.Coll}}
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "db.projects", mtest.FirstBatch, project),
+			mtest.CreateSuccessResponse(),
+			mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 91, Message: "shutting down"}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+		)
+		if code := deliver(fc); code != http.StatusInternalServerError {
+			mt.Fatalf("status = %d, want 500", code)
+		}
+
+		events := mt.GetAllStartedEvents()
+		claim := events[1].Command.Lookup("documents", "0").Document()
+		if key := claim.Lookup("_id").StringValue(); key != "typeform:01HX" {
+			mt.Fatalf("claimed %q, want the event id", key)
+		}
+		if expires := claim.Lookup("expiresAt").Time(); time.Until(expires) < typeformReplayWindow-time.Minute {
+			mt.Errorf("event id remembered until %v, want about %v", expires, typeformReplayWindow)
+		}
+		last := events[len(events)-1]
+		if last.CommandName != "delete" || last.Command.Lookup("deletes", "0", "q", "_id").StringValue() != "typeform:01HX" {
+			mt.Fatalf("commands = %v, want the event id released", commandNames(mt))
+		}
+	})
+
+	// Typeform's retry can claim it again and is ingested
+	mt.Run("retry is ingested", func(mt *mtest.T) {
+		fc := &FormResponseController{collection: mt.Coll, projects: mt.Coll, replays: &replayStore{collection: mt.Coll}}
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "db.projects", mtest.FirstBatch, project),
+			mtest.CreateSuccessResponse(),
+			mtest.CreateCursorResponse(0, "db.applicants", mtest.FirstBatch),
+			mtest.CreateSuccessResponse(),
+		)
+		if code := deliver(fc); code != http.StatusCreated {
+			mt.Fatalf("status = %d, want 201", code)
+		}
+	})
+
+	// once ingested, the event id stays claimed and a replay is refused
+	mt.Run("replay is refused", func(mt *mtest.T) {
+		fc := &FormResponseController{collection: mt.Coll, projects: mt.Coll, replays: &replayStore{collection: mt.Coll}}
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "db.projects", mtest.FirstBatch, project),
+			mtest.CreateWriteErrorsResponse(mtest.WriteError{Index: 0, Code: 11000, Message: "duplicate key"}),
+		)
+		if code := deliver(fc); code != http.StatusUnauthorized {
+			mt.Fatalf("status = %d, want 401", code)
+		}
+		if names := commandNames(mt); len(names) != 2 {
+			mt.Fatalf("commands = %v, want nothing ingested after the refused claim", names)
+		}
+	})
+}
@@ -0,0 +1,433 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"backend/models"
+	"backend/ranking"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var errNoSnapshot = errors.New("no snapshot")
+
+// snapshotRefError is a snapshot reference that is neither an id nor a time
+type snapshotRefError struct{ error }
+
+// buildSnapshot captures the ranking of the project's current stage without saving it
+func (ac *ApplicantController) buildSnapshot(ctx context.Context, project *models.Project, reason, label string) (*models.RankingSnapshot, error) {
+	applicants, err := ac.stageApplicants(ctx, project)
+	if err != nil {
+		return nil, err
+	}
+	comparisons, err := ac.comparisons.CountDocuments(ctx, bson.M{
+		"project_id": project.ID,
+		"stage":      stageFilter(project.CurrentStage),
+		"voided":     bson.M{"$ne": true},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	active := []models.Applicant{}
+	for _, applicant := range applicants {
+		if applicant.IsActive() {
+			active = append(active, applicant)
+		}
+	}
+	ranks := map[primitive.ObjectID]int{}
+	for _, entry := range ranking.Rank(active) {
+		ranks[entry.Applicant.ID] = entry.Rank
+	}
+
+	snapshot := &models.RankingSnapshot{
+		ProjectID:   project.ID,
+		Stage:       project.CurrentStage,
+		TakenAt:     time.Now(),
+		Reason:      reason,
+		Label:       label,
+		Comparisons: int(comparisons),
+		Entries:     make([]models.SnapshotEntry, 0, len(applicants)),
+	}
+	for _, a := range applicants {
+		snapshot.Entries = append(snapshot.Entries, models.SnapshotEntry{
+			ApplicantID: a.ID,
+			FirstName:   a.FirstName,
+			LastName:    a.LastName,
+			Major:       a.Major,
+			Year:        a.Year,
+			Status:      a.Status,
+			Elo:         a.Elo,
+			Wins:        a.Wins,
+			Losses:      a.Losses,
+			Rank:        ranks[a.ID],
+		})
+	}
+	return snapshot, nil
+}
+
+func (ac *ApplicantController) takeSnapshot(ctx context.Context, project *models.Project, reason, label string) (*models.RankingSnapshot, error) {
+	snapshot, err := ac.buildSnapshot(ctx, project, reason, label)
+	if err != nil {
+		return nil, err
+	}
+	snapshot.ID = primitive.NewObjectID()
+	if _, err := ac.snapshots.InsertOne(ctx, snapshot); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// CreateSnapshot saves the current ranking of a project, e.g. before a voting session. Body: {"label": "..."}, optional.
+func (ac *ApplicantController) CreateSnapshot(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Label string `json:"label"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid JSON input: "+err.Error())
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	project := ac.loadProject(ctx, w, chi.URLParam(r, "id"))
+	if project == nil {
+		return
+	}
+	snapshot, err := ac.takeSnapshot(ctx, project, models.SnapshotManual, request.Label)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to take snapshot")
+		log.Println("Ranking snapshot error:", err)
+		return
+	}
+
+	snapshot.Entries = nil
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(snapshot)
+}
+
+// ListSnapshots lists a project's snapshots newest first, without their entries
+func (ac *ApplicantController) ListSnapshots(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	project := ac.loadProject(ctx, w, chi.URLParam(r, "id"))
+	if project == nil {
+		return
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "takenAt", Value: -1}}).
+		SetProjection(bson.M{"entries": 0})
+	cursor, err := ac.snapshots.Find(ctx, bson.M{"project_id": project.ID}, opts)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to fetch snapshots")
+		log.Println("MongoDB Find snapshots error:", err)
+		return
+	}
+	snapshots := []models.RankingSnapshot{}
+	if err := cursor.All(ctx, &snapshots); err != nil {
+		writeError(w, http.StatusInternalServerError, "Error decoding snapshots")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(snapshots)
+}
+
+// findSnapshot resolves ref to a snapshot of the project: a snapshot id, or a time for the last
+// snapshot taken at or before it
+func (ac *ApplicantController) findSnapshot(ctx context.Context, projectID primitive.ObjectID, ref string) (*models.RankingSnapshot, error) {
+	filter := bson.M{"project_id": projectID}
+	opts := options.FindOne().SetSort(bson.D{{Key: "takenAt", Value: -1}})
+	if id, err := primitive.ObjectIDFromHex(ref); err == nil {
+		filter["_id"] = id
+	} else {
+		at, err := parseSnapshotTime(ref)
+		if err != nil {
+			return nil, snapshotRefError{err}
+		}
+		filter["takenAt"] = bson.M{"$lte": at}
+	}
+
+	var snapshot models.RankingSnapshot
+	if err := ac.snapshots.FindOne(ctx, filter, opts).Decode(&snapshot); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errNoSnapshot
+		}
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// parseSnapshotTime accepts RFC 3339 times, dates (midnight UTC) and unix seconds
+func parseSnapshotTime(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("2006-01-02", v); err == nil {
+		return t, nil
+	}
+	if seconds, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+	return time.Time{}, fmt.Errorf("%q is not a snapshot id, RFC 3339 time, date or unix timestamp", v)
+}
+
+// writeSnapshotError reports why findSnapshot failed
+func writeSnapshotError(w http.ResponseWriter, field string, err error) {
+	var refErr snapshotRefError
+	switch {
+	case err == errNoSnapshot:
+		writeError(w, http.StatusNotFound, "No snapshot of the project at that time")
+	case errors.As(err, &refErr):
+		writeValidationError(w, map[string]string{field: err.Error()})
+	default:
+		writeError(w, http.StatusInternalServerError, "Failed to fetch snapshot")
+		log.Println("MongoDB Find snapshot error:", err)
+	}
+}
+
+// snapshotApplicants rebuilds the applicants of a snapshot as they stood, ranked ones first
+func snapshotApplicants(snapshot *models.RankingSnapshot) (ranked, inactive []models.Applicant) {
+	ranked, inactive = []models.Applicant{}, []models.Applicant{}
+	for _, e := range snapshot.Entries {
+		applicant := models.Applicant{
+			ID:        e.ApplicantID,
+			ProjectID: snapshot.ProjectID,
+			FirstName: e.FirstName,
+			LastName:  e.LastName,
+			Major:     e.Major,
+			Year:      e.Year,
+			Status:    e.Status,
+			Elo:       e.Elo,
+			Wins:      e.Wins,
+			Losses:    e.Losses,
+			Stage:     snapshot.Stage,
+		}
+		if e.Rank > 0 {
+			ranked = append(ranked, applicant)
+		} else {
+			inactive = append(inactive, applicant)
+		}
+	}
+	return ranked, inactive
+}
+
+// snapshotRankings answers GetRankings?at= with the last snapshot taken at or before at
+func (ac *ApplicantController) snapshotRankings(ctx context.Context, w http.ResponseWriter, r *http.Request, project *models.Project, at string) {
+	snapshot, err := ac.findSnapshot(ctx, project.ID, at)
+	if err != nil {
+		writeSnapshotError(w, "at", err)
+		return
+	}
+	ranked, inactive := snapshotApplicants(snapshot)
+	ac.prepareApplicants(ctx, r, applicantPtrs(ranked)...)
+	ac.prepareApplicants(ctx, r, applicantPtrs(inactive)...)
+
+	snapshot.Entries = nil
+	response := struct {
+		Stage    int                     `json:"stage"`
+		MinGames int                     `json:"minGames"`
+		Snapshot *models.RankingSnapshot `json:"snapshot"`
+		Rankings []rankedApplicant       `json:"rankings"`
+		Inactive []rankedApplicant       `json:"inactive"`
+	}{
+		Stage:    snapshot.Stage,
+		MinGames: ranking.MinGames,
+		Snapshot: snapshot,
+		Rankings: rankedApplicants(ranking.Rank(ranked), true),
+		Inactive: rankedApplicants(ranking.Rank(inactive), false),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// rankMovement is an applicant's change between two snapshots; a rank of 0 means not ranked
+type rankMovement struct {
+	ApplicantID primitive.ObjectID `json:"applicantId"`
+	FirstName   string             `json:"firstName"`
+	LastName    string             `json:"lastName"`
+	FromRank    int                `json:"fromRank"`
+	ToRank      int                `json:"toRank"`
+	// places gained, negative when the applicant fell
+	Change  int `json:"change"`
+	FromElo int `json:"fromElo"`
+	ToElo   int `json:"toElo"`
+}
+
+// DiffSnapshots shows who moved up and down between two snapshots. ?from= and ?to= are snapshot ids
+// or times (for the last snapshot at or before them); without ?to= the live ranking is used.
+// Applicants ranked in only one of the two are listed as entered or left.
+func (ac *ApplicantController) DiffSnapshots(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	project := ac.loadProject(ctx, w, chi.URLParam(r, "id"))
+	if project == nil {
+		return
+	}
+	fromRef, toRef := r.URL.Query().Get("from"), r.URL.Query().Get("to")
+	if fromRef == "" {
+		writeValidationError(w, map[string]string{"from": "is required"})
+		return
+	}
+
+	from, err := ac.findSnapshot(ctx, project.ID, fromRef)
+	if err != nil {
+		writeSnapshotError(w, "from", err)
+		return
+	}
+	var to *models.RankingSnapshot
+	if toRef == "" {
+		to, err = ac.buildSnapshot(ctx, project, "live", "")
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "Failed to fetch rankings")
+			log.Println("Ranking snapshot error:", err)
+			return
+		}
+	} else if to, err = ac.findSnapshot(ctx, project.ID, toRef); err != nil {
+		writeSnapshotError(w, "to", err)
+		return
+	}
+
+	// names go through the same blind-review rules as any other applicant response
+	people := map[primitive.ObjectID]*models.Applicant{}
+	var applicants []*models.Applicant
+	for _, snapshot := range []*models.RankingSnapshot{to, from} {
+		ranked, inactive := snapshotApplicants(snapshot)
+		for _, a := range append(ranked, inactive...) {
+			if people[a.ID] == nil {
+				a := a
+				people[a.ID] = &a
+				applicants = append(applicants, &a)
+			}
+		}
+	}
+	ac.prepareApplicants(ctx, r, applicants...)
+
+	fromEntries := map[primitive.ObjectID]models.SnapshotEntry{}
+	for _, e := range from.Entries {
+		fromEntries[e.ApplicantID] = e
+	}
+	toEntries := map[primitive.ObjectID]models.SnapshotEntry{}
+	for _, e := range to.Entries {
+		toEntries[e.ApplicantID] = e
+	}
+
+	response := struct {
+		From      *models.RankingSnapshot `json:"from"`
+		To        *models.RankingSnapshot `json:"to"`
+		MovedUp   []rankMovement          `json:"movedUp"`
+		MovedDown []rankMovement          `json:"movedDown"`
+		Entered   []rankMovement          `json:"entered"`
+		Left      []rankMovement          `json:"left"`
+		Unchanged int                     `json:"unchanged"`
+	}{From: from, To: to, MovedUp: []rankMovement{}, MovedDown: []rankMovement{}, Entered: []rankMovement{}, Left: []rankMovement{}}
+
+	for _, a := range applicants {
+		before, after := fromEntries[a.ID], toEntries[a.ID]
+		movement := rankMovement{
+			ApplicantID: a.ID,
+			FirstName:   a.FirstName,
+			LastName:    a.LastName,
+			FromRank:    before.Rank,
+			ToRank:      after.Rank,
+			FromElo:     before.Elo,
+			ToElo:       after.Elo,
+		}
+		switch {
+		case before.Rank == 0 && after.Rank == 0:
+		case before.Rank == 0:
+			response.Entered = append(response.Entered, movement)
+		case after.Rank == 0:
+			response.Left = append(response.Left, movement)
+		default:
+			movement.Change = before.Rank - after.Rank
+			switch {
+			case movement.Change > 0:
+				response.MovedUp = append(response.MovedUp, movement)
+			case movement.Change < 0:
+				response.MovedDown = append(response.MovedDown, movement)
+			default:
+				response.Unchanged++
+			}
+		}
+	}
+	sort.SliceStable(response.MovedUp, func(i, j int) bool { return response.MovedUp[i].Change > response.MovedUp[j].Change })
+	sort.SliceStable(response.MovedDown, func(i, j int) bool { return response.MovedDown[i].Change < response.MovedDown[j].Change })
+	sort.SliceStable(response.Entered, func(i, j int) bool { return response.Entered[i].ToRank < response.Entered[j].ToRank })
+	sort.SliceStable(response.Left, func(i, j int) bool { return response.Left[i].FromRank < response.Left[j].FromRank })
+
+	from.Entries, to.Entries = nil, nil
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// ScheduleRankingSnapshots snapshots every project whose ranking has had votes since its last
+// snapshot, every interval. It runs until the process exits.
+func ScheduleRankingSnapshots(interval time.Duration) {
+	ac := NewApplicantController()
+	for range time.Tick(interval) {
+		ac.snapshotChangedProjects()
+	}
+}
+
+func (ac *ApplicantController) snapshotChangedProjects() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	cursor, err := ac.projects.Find(ctx, bson.M{})
+	if err != nil {
+		log.Println("MongoDB Find projects error:", err)
+		return
+	}
+	var projects []models.Project
+	if err := cursor.All(ctx, &projects); err != nil {
+		log.Println("Cursor decode error:", err)
+		return
+	}
+
+	for i := range projects {
+		project := &projects[i]
+		var latest models.RankingSnapshot
+		opts := options.FindOne().SetSort(bson.D{{Key: "takenAt", Value: -1}}).SetProjection(bson.M{"entries": 0})
+		err := ac.snapshots.FindOne(ctx, bson.M{"project_id": project.ID}, opts).Decode(&latest)
+		if err != nil && err != mongo.ErrNoDocuments {
+			log.Println("MongoDB Find snapshot error:", err)
+			continue
+		}
+
+		filter := bson.M{"project_id": project.ID}
+		if err == nil {
+			filter["createdAt"] = bson.M{"$gt": latest.TakenAt}
+		}
+		votes, err := ac.comparisons.CountDocuments(ctx, filter)
+		if err != nil {
+			log.Println("MongoDB Count comparisons error:", err)
+			continue
+		}
+		if votes == 0 {
+			continue
+		}
+		if _, err := ac.takeSnapshot(ctx, project, models.SnapshotScheduled, ""); err != nil {
+			log.Printf("Failed to snapshot project %s: %v", project.ID.Hex(), err)
+		}
+	}
+}
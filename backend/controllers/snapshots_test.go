@@ -0,0 +1,204 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestParseSnapshotTime(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Time
+		wantErr bool
+	}{
+		{"2026-03-01T12:30:00Z", time.Date(2026, 3, 1, 12, 30, 0, 0, time.UTC), false},
+		{"2026-03-01T12:30:00+02:00", time.Date(2026, 3, 1, 10, 30, 0, 0, time.UTC), false},
+		{"2026-03-01", time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), false},
+		{"1772368200", time.Unix(1772368200, 0), false},
+		{"last tuesday", time.Time{}, true},
+		{"2026-13-01", time.Time{}, true},
+	}
+	for _, tt := range tests {
+		got, err := parseSnapshotTime(tt.in)
+		if (err != nil) != tt.wantErr || !got.Equal(tt.want) {
+			t.Errorf("parseSnapshotTime(%q) = %v, %v; want %v, error %t", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestFindSnapshot(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	projectID := primitive.NewObjectID()
+	snapshot := models.RankingSnapshot{ID: primitive.NewObjectID(), ProjectID: projectID, TakenAt: time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)}
+
+	mt.Run("by id", func(mt *mtest.T) {
+		ac := testApplicantController(mt)
+		mt.AddMockResponses(cursorOf(mt, "db.ranking_snapshots", snapshot))
+		got, err := ac.findSnapshot(context.Background(), projectID, snapshot.ID.Hex())
+		if err != nil || got.ID != snapshot.ID {
+			mt.Fatalf("findSnapshot() = %v, %v", got, err)
+		}
+		filter := mt.GetStartedEvent().Command.Lookup("filter").Document()
+		if filter.Lookup("_id").ObjectID() != snapshot.ID || filter.Lookup("project_id").ObjectID() != projectID {
+			mt.Errorf("filter = %v, want the snapshot id within the project", filter)
+		}
+	})
+
+	mt.Run("by time", func(mt *mtest.T) {
+		ac := testApplicantController(mt)
+		mt.AddMockResponses(cursorOf(mt, "db.ranking_snapshots", snapshot))
+		if _, err := ac.findSnapshot(context.Background(), projectID, "2026-03-02"); err != nil {
+			mt.Fatal(err)
+		}
+		command := mt.GetStartedEvent().Command
+		at := command.Lookup("filter", "takenAt", "$lte").Time()
+		if !at.Equal(time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)) {
+			mt.Errorf("looked for snapshots up to %v, want the start of the day", at)
+		}
+		if order := command.Lookup("sort", "takenAt").AsInt64(); order != -1 {
+			mt.Errorf("sorted by takenAt %d, want the latest snapshot before the time", order)
+		}
+	})
+
+	mt.Run("none before the time", func(mt *mtest.T) {
+		ac := testApplicantController(mt)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "db.ranking_snapshots", mtest.FirstBatch))
+		if _, err := ac.findSnapshot(context.Background(), projectID, "2020-01-01"); err != errNoSnapshot {
+			mt.Fatalf("error = %v, want errNoSnapshot", err)
+		}
+	})
+
+	mt.Run("neither id nor time", func(mt *mtest.T) {
+		ac := testApplicantController(mt)
+		var refErr snapshotRefError
+		if _, err := ac.findSnapshot(context.Background(), projectID, "yesterday"); !errors.As(err, &refErr) {
+			mt.Fatalf("error = %v, want a snapshotRefError", err)
+		}
+		if names := commandNames(mt); len(names) > 0 {
+			mt.Errorf("commands = %v, want none for a bad reference", names)
+		}
+	})
+}
+
+func TestDiffSnapshotsAgainstLive(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	project := models.Project{ID: primitive.NewObjectID(), Name: "Fall", Stages: []models.Stage{{Name: "Screen"}}}
+	ids := map[string]primitive.ObjectID{}
+	for _, name := range []string{"Ada", "Brian", "Claude", "Dana", "Edsger"} {
+		ids[name] = primitive.NewObjectID()
+	}
+	from := models.RankingSnapshot{
+		ID:        primitive.NewObjectID(),
+		ProjectID: project.ID,
+		TakenAt:   time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC),
+		Entries: []models.SnapshotEntry{
+			{ApplicantID: ids["Ada"], FirstName: "Ada", Elo: 1200, Rank: 1},
+			{ApplicantID: ids["Brian"], FirstName: "Brian", Elo: 1100, Rank: 2},
+			{ApplicantID: ids["Claude"], FirstName: "Claude", Elo: 1000, Rank: 3},
+			{ApplicantID: ids["Dana"], FirstName: "Dana", Elo: 900, Rank: 4},
+		},
+	}
+	live := []interface{}{
+		models.Applicant{ID: ids["Brian"], ProjectID: project.ID, FirstName: "Brian", Elo: 1250},
+		models.Applicant{ID: ids["Ada"], ProjectID: project.ID, FirstName: "Ada", Elo: 1150},
+		models.Applicant{ID: ids["Claude"], ProjectID: project.ID, FirstName: "Claude", Elo: 1000},
+		models.Applicant{ID: ids["Edsger"], ProjectID: project.ID, FirstName: "Edsger", Elo: 950},
+		models.Applicant{ID: ids["Dana"], ProjectID: project.ID, FirstName: "Dana", Elo: 900, Status: models.ApplicantStatusWithdrawn},
+	}
+
+	mt.Run("live", func(mt *mtest.T) {
+		ac := testApplicantController(mt)
+		mt.AddMockResponses(
+			cursorOf(mt, "db.projects", project),
+			cursorOf(mt, "db.ranking_snapshots", from),
+			cursorOf(mt, "db.applicants", live...),
+			mtest.CreateCursorResponse(0, "db.comparisons", mtest.FirstBatch, bson.D{{Key: "n", Value: 12}}),
+			cursorOf(mt, "db.projects", project),
+		)
+		r := httptest.NewRequest(http.MethodGet, "/?from=2026-03-01T10:00:00Z", nil)
+		w := httptest.NewRecorder()
+		ac.DiffSnapshots(w, withURLParams(r, map[string]string{"id": project.ID.Hex()}))
+		if w.Code != http.StatusOK {
+			mt.Fatalf("status = %d: %s", w.Code, w.Body)
+		}
+
+		var diff struct {
+			From      models.RankingSnapshot `json:"from"`
+			To        models.RankingSnapshot `json:"to"`
+			MovedUp   []rankMovement         `json:"movedUp"`
+			MovedDown []rankMovement         `json:"movedDown"`
+			Entered   []rankMovement         `json:"entered"`
+			Left      []rankMovement         `json:"left"`
+			Unchanged int                    `json:"unchanged"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&diff); err != nil {
+			mt.Fatal(err)
+		}
+		if diff.From.ID != from.ID || diff.To.Reason != "live" || diff.To.Comparisons != 12 {
+			mt.Errorf("compared snapshot %s with %q (%d votes), want %s with the live ranking", diff.From.ID.Hex(), diff.To.Reason, diff.To.Comparisons, from.ID.Hex())
+		}
+		if len(diff.From.Entries) > 0 || len(diff.To.Entries) > 0 {
+			mt.Error("diff repeats the snapshots' entries")
+		}
+
+		moved := func(movements []rankMovement) map[string][3]int {
+			out := map[string][3]int{}
+			for _, m := range movements {
+				out[m.FirstName] = [3]int{m.FromRank, m.ToRank, m.Change}
+			}
+			return out
+		}
+		check := func(what string, got []rankMovement, want map[string][3]int) {
+			if g := moved(got); len(g) != len(want) {
+				mt.Errorf("%s = %v, want %v", what, g, want)
+			} else {
+				for name, w := range want {
+					if g[name] != w {
+						mt.Errorf("%s %s = %v, want %v", what, name, g[name], w)
+					}
+				}
+			}
+		}
+		check("movedUp", diff.MovedUp, map[string][3]int{"Brian": {2, 1, 1}})
+		check("movedDown", diff.MovedDown, map[string][3]int{"Ada": {1, 2, -1}})
+		check("entered", diff.Entered, map[string][3]int{"Edsger": {0, 4, 0}})
+		check("left", diff.Left, map[string][3]int{"Dana": {4, 0, 0}})
+		if diff.Unchanged != 1 {
+			mt.Errorf("unchanged = %d, want Claude alone", diff.Unchanged)
+		}
+	})
+
+	errorTests := []struct {
+		name       string
+		query      string
+		responses  []bson.D
+		wantStatus int
+	}{
+		{"without from", "", nil, http.StatusBadRequest},
+		{"from neither id nor time", "?from=soon", nil, http.StatusBadRequest},
+		{"nothing before from", "?from=2020-01-01", []bson.D{mtest.CreateCursorResponse(0, "db.ranking_snapshots", mtest.FirstBatch)}, http.StatusNotFound},
+		{"to neither id nor time", "?from=2026-03-02&to=later", []bson.D{cursorOf(mt, "db.ranking_snapshots", from)}, http.StatusBadRequest},
+	}
+	for _, tt := range errorTests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			ac := testApplicantController(mt)
+			mt.AddMockResponses(append([]bson.D{cursorOf(mt, "db.projects", project)}, tt.responses...)...)
+			w := httptest.NewRecorder()
+			ac.DiffSnapshots(w, withURLParams(httptest.NewRequest(http.MethodGet, "/"+tt.query, nil), map[string]string{"id": project.ID.Hex()}))
+			if w.Code != tt.wantStatus {
+				mt.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+		})
+	}
+}
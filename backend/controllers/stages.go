@@ -0,0 +1,325 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"backend/middleware"
+	"backend/models"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// stageFilter matches applicants in stage. Applicants from before stages existed have no
+// stage field and belong to the first one.
+func stageFilter(stage int) interface{} {
+	if stage == 0 {
+		return bson.M{"$in": []interface{}{nil, 0}}
+	}
+	return stage
+}
+
+// currentStageFilter narrows filter to the applicants in the stage each project is reviewing,
+// or in project's when one is given
+func (ac *ApplicantController) currentStageFilter(ctx context.Context, filter bson.M, project *models.Project) (bson.M, error) {
+	if project != nil {
+		filter["project_id"] = project.ID
+		filter["stage"] = stageFilter(project.CurrentStage)
+		return filter, nil
+	}
+
+	cursor, err := ac.projects.Find(ctx, bson.M{"closed": bson.M{"$ne": true}}, options.Find().SetProjection(bson.M{"currentStage": 1}))
+	if err != nil {
+		return nil, err
+	}
+	var projects []models.Project
+	if err := cursor.All(ctx, &projects); err != nil {
+		return nil, err
+	}
+
+	stages := []bson.M{}
+	for _, p := range projects {
+		stages = append(stages, bson.M{"project_id": p.ID, "stage": stageFilter(p.CurrentStage)})
+	}
+	if len(stages) == 0 {
+		// no projects, nothing to match
+		stages = append(stages, bson.M{"_id": primitive.NilObjectID})
+	}
+	filter["$or"] = stages
+	return filter, nil
+}
+
+// loadProject fetches the project in the URL, writing the error response when it can't
+func (ac *ApplicantController) loadProject(ctx context.Context, w http.ResponseWriter, projectIDStr string) *models.Project {
+	projectID, err := primitive.ObjectIDFromHex(projectIDStr)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid Project ID")
+		return nil
+	}
+	var project models.Project
+	if err := ac.projects.FindOne(ctx, bson.M{"_id": projectID}).Decode(&project); err != nil {
+		if err == mongo.ErrNoDocuments {
+			writeError(w, http.StatusNotFound, "Project not found")
+			return nil
+		}
+		writeError(w, http.StatusInternalServerError, "Failed to fetch project")
+		log.Println("MongoDB Find project error:", err)
+		return nil
+	}
+	return &project
+}
+
+// Cut closes the project's current stage and advances its best applicants to the next one.
+// Body: {"top": 20} or {"topPercent": 25}. Active applicants are taken in rating order, wins
+// breaking ties. Everyone in the stage has their final rating archived in stageResults; those
+// who advance start the next stage afresh with the starting rating and no comparisons, the
+// rest stay behind in the closed stage. With "notify": true, active applicants are emailed whether
+// they made it. Only project owners can cut.
+func (ac *ApplicantController) Cut(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Top        int     `json:"top"`
+		TopPercent float64 `json:"topPercent"`
+		Notify     bool    `json:"notify"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid JSON input: "+err.Error())
+		return
+	}
+	if (request.Top > 0) == (request.TopPercent > 0) {
+		writeValidationError(w, map[string]string{"top": "give either top or topPercent"})
+		return
+	}
+	if request.TopPercent > 100 {
+		writeValidationError(w, map[string]string{"topPercent": "must be at most 100"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	project := ac.loadProject(ctx, w, chi.URLParam(r, "id"))
+	if project == nil {
+		return
+	}
+	if len(project.OwnerIDs) > 0 && !project.IsOwner(middleware.UserID(r.Context())) {
+		writeError(w, http.StatusForbidden, "Only project owners can cut a stage")
+		return
+	}
+	next := project.CurrentStage + 1
+	if next >= len(project.Stages) {
+		writeError(w, http.StatusConflict, "Project has no stage after the current one, add one first")
+		return
+	}
+
+	filter, _ := ac.currentStageFilter(ctx, bson.M{}, project)
+	cursor, err := ac.collection.Find(ctx, filter)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to fetch applicants")
+		log.Println("MongoDB Find applicants error:", err)
+		return
+	}
+	var applicants []models.Applicant
+	if err := cursor.All(ctx, &applicants); err != nil {
+		writeError(w, http.StatusInternalServerError, "Error decoding applicants")
+		return
+	}
+
+	var active []*models.Applicant
+	for i := range applicants {
+		if applicants[i].IsActive() {
+			active = append(active, &applicants[i])
+		}
+	}
+	sort.SliceStable(active, func(i, j int) bool {
+		if active[i].Elo != active[j].Elo {
+			return active[i].Elo > active[j].Elo
+		}
+		return active[i].Wins > active[j].Wins
+	})
+
+	count := request.Top
+	if request.TopPercent > 0 {
+		count = int(math.Ceil(float64(len(active)) * request.TopPercent / 100))
+	}
+	count = min(count, len(active))
+
+	ranks := map[primitive.ObjectID]int{}
+	advancing := map[primitive.ObjectID]bool{}
+	for i, a := range active {
+		ranks[a.ID] = i + 1
+		advancing[a.ID] = i < count
+	}
+
+	var writes []mongo.WriteModel
+	for _, a := range applicants {
+		result := models.StageResult{
+			Stage:    project.CurrentStage,
+			Elo:      a.Elo,
+			Wins:     a.Wins,
+			Losses:   a.Losses,
+			Rank:     ranks[a.ID],
+			Advanced: advancing[a.ID],
+		}
+		update := bson.M{"$push": bson.M{"stageResults": result}}
+		if result.Advanced {
+			update["$set"] = bson.M{
+				"stage":          next,
+				"elo":            initialElo,
+				"wins":           0,
+				"losses":         0,
+				"matches_played": []primitive.ObjectID{},
+			}
+		}
+		writes = append(writes, mongo.NewUpdateOneModel().SetFilter(bson.M{"_id": a.ID}).SetUpdate(update))
+	}
+
+	now := time.Now()
+	// moving the project on first, and only from the stage that was read, makes sure two cuts
+	// racing each other can't both advance the same applicants. The next stage opens for review
+	// even if the last one had been closed.
+	result, err := ac.projects.UpdateOne(ctx, bson.M{"_id": project.ID, "currentStage": stageFilter(project.CurrentStage)}, bson.M{
+		"$set": bson.M{
+			"currentStage": next,
+			"closed":       false,
+			fmt.Sprintf("stages.%d.startedAt", next): now,
+		},
+		"$unset": bson.M{"closedAt": ""},
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to update project stage")
+		log.Println("MongoDB Update project stage error:", err)
+		return
+	}
+	if result.MatchedCount == 0 {
+		writeError(w, http.StatusConflict, "The stage has already been cut")
+		return
+	}
+
+	if len(writes) > 0 {
+		if _, err := ac.collection.BulkWrite(ctx, writes); err != nil {
+			writeError(w, http.StatusInternalServerError, "Failed to advance applicants")
+			log.Println("MongoDB advance applicants error:", err)
+			ac.reopenStage(ctx, project, next)
+			return
+		}
+	}
+	notified := 0
+	if request.Notify {
+		notified = sendDecisionEmails(project, next-1, active, advancing)
+	}
+	project.CurrentStage, project.Closed, project.ClosedAt = next, false, nil
+	publishProjectStatus(project, "stage-cut")
+	publishRankingChanged(project.ID, next)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"stage":    next,
+		"name":     project.Stages[next].Name,
+		"advanced": count,
+		"cut":      len(applicants) - count,
+		"notified": notified,
+	})
+}
+
+// reopenStage puts a project that Cut moved to stage next back where it was, so a cut whose
+// applicant writes failed can be retried
+func (ac *ApplicantController) reopenStage(ctx context.Context, project *models.Project, next int) {
+	set := bson.M{"currentStage": project.CurrentStage, "closed": project.Closed}
+	if project.ClosedAt != nil {
+		set["closedAt"] = project.ClosedAt
+	}
+	if _, err := ac.projects.UpdateOne(ctx, bson.M{"_id": project.ID, "currentStage": next}, bson.M{"$set": set}); err != nil {
+		log.Println("MongoDB reopen project stage error:", err)
+	}
+}
+
+// stageParam reads the stage query parameter, defaulting to the project's current stage
+func stageParam(r *http.Request, project *models.Project) (int, error) {
+	v := r.URL.Query().Get("stage")
+	if v == "" {
+		return project.CurrentStage, nil
+	}
+	stage, err := strconv.Atoi(v)
+	if err != nil || stage < 0 || stage > project.CurrentStage {
+		return 0, fmt.Errorf("stage must be between 0 and %d", project.CurrentStage)
+	}
+	return stage, nil
+}
+
+// closedStageRankings returns the applicants who took part in a stage that has been cut with
+// the ratings archived at the cut: those who were ranked in the order they finished, then the
+// ones who had already left the pool
+func (ac *ApplicantController) closedStageRankings(ctx context.Context, projectID primitive.ObjectID, stage int) ([]models.Applicant, []models.Applicant, error) {
+	cursor, err := ac.collection.Find(ctx, bson.M{"project_id": projectID, "stageResults.stage": stage})
+	if err != nil {
+		return nil, nil, err
+	}
+	var applicants []models.Applicant
+	if err := cursor.All(ctx, &applicants); err != nil {
+		return nil, nil, err
+	}
+
+	ranked, inactive := []models.Applicant{}, []models.Applicant{}
+	ranks := map[primitive.ObjectID]int{}
+	for _, applicant := range applicants {
+		for _, result := range applicant.StageResults {
+			if result.Stage != stage {
+				continue
+			}
+			applicant.Elo, applicant.Wins, applicant.Losses = result.Elo, result.Wins, result.Losses
+			ranks[applicant.ID] = result.Rank
+		}
+		if ranks[applicant.ID] > 0 {
+			ranked = append(ranked, applicant)
+		} else {
+			inactive = append(inactive, applicant)
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool { return ranks[ranked[i].ID] < ranks[ranked[j].ID] })
+	sort.SliceStable(inactive, func(i, j int) bool { return inactive[i].Elo > inactive[j].Elo })
+	return ranked, inactive, nil
+}
+
+// ListComparisons returns the votes cast in a stage of the project, newest first.
+// ?stage= picks an earlier stage, the current one by default.
+func (ac *ApplicantController) ListComparisons(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	project := ac.loadProject(ctx, w, chi.URLParam(r, "id"))
+	if project == nil {
+		return
+	}
+	stage, err := stageParam(r, project)
+	if err != nil {
+		writeValidationError(w, map[string]string{"stage": err.Error()})
+		return
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}})
+	cursor, err := ac.comparisons.Find(ctx, bson.M{"project_id": project.ID, "stage": stageFilter(stage)}, opts)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to fetch comparisons")
+		log.Println("MongoDB Find comparisons error:", err)
+		return
+	}
+	comparisons := []models.Comparison{}
+	if err := cursor.All(ctx, &comparisons); err != nil {
+		writeError(w, http.StatusInternalServerError, "Error decoding comparisons")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(comparisons)
+}
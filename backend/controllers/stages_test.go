@@ -0,0 +1,191 @@
+package controllers
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/mail"
+	"backend/middleware"
+	"backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestCutClaimsStageBeforeAdvancing(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	project := models.Project{
+		ID:     primitive.NewObjectID(),
+		Name:   "Recruiting",
+		Stages: []models.Stage{{Name: "Screen"}, {Name: "Interview"}},
+	}
+	applicants := []interface{}{
+		models.Applicant{ID: primitive.NewObjectID(), ProjectID: project.ID, Elo: 1100, Status: models.ApplicantStatusActive},
+		models.Applicant{ID: primitive.NewObjectID(), ProjectID: project.ID, Elo: 900, Status: models.ApplicantStatusActive},
+	}
+
+	tests := []struct {
+		name       string
+		matched    int
+		wantStatus int
+	}{
+		{"stage still open", 1, http.StatusOK},
+		{"cut by someone else first", 0, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			ac := testApplicantController(mt)
+			mt.AddMockResponses(
+				cursorOf(mt, "db.projects", project),
+				cursorOf(mt, "db.applicants", applicants...),
+				mtest.CreateSuccessResponse(bson.E{Key: "n", Value: tt.matched}, bson.E{Key: "nModified", Value: tt.matched}),
+				mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 2}, bson.E{Key: "nModified", Value: 2}),
+			)
+
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"top":1}`))
+			w := httptest.NewRecorder()
+			ac.Cut(w, withURLParams(r, map[string]string{"id": project.ID.Hex()}))
+			if w.Code != tt.wantStatus {
+				mt.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+
+			var updated []string
+			for _, event := range mt.GetAllStartedEvents() {
+				if event.CommandName != "update" {
+					continue
+				}
+				collection := event.Command.Lookup("update").StringValue()
+				updated = append(updated, collection)
+				if collection == "projects" {
+					if _, err := event.Command.Lookup("updates", "0", "q").Document().LookupErr("currentStage"); err != nil {
+						mt.Error("project update is not conditional on the stage that was read")
+					}
+				}
+			}
+			want := []string{"projects", mt.Coll.Name()}
+			if tt.wantStatus == http.StatusConflict {
+				want = want[:1]
+			}
+			if strings.Join(updated, ",") != strings.Join(want, ",") {
+				mt.Errorf("updated %v, want %v in that order", updated, want)
+			}
+		})
+	}
+}
+
+func TestCutRequiresOwner(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	project := models.Project{
+		ID:       primitive.NewObjectID(),
+		Name:     "Recruiting",
+		OwnerIDs: []string{"user_alice"},
+		Stages:   []models.Stage{{Name: "Screen"}, {Name: "Interview"}},
+	}
+
+	for _, userID := range []string{"user_bob", ""} {
+		mt.Run("user "+userID, func(mt *mtest.T) {
+			ac := testApplicantController(mt)
+			mt.AddMockResponses(cursorOf(mt, "db.projects", project))
+
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"top":1}`))
+			if userID != "" {
+				r = r.WithContext(middleware.WithUserID(r.Context(), userID))
+			}
+			w := httptest.NewRecorder()
+			ac.Cut(w, withURLParams(r, map[string]string{"id": project.ID.Hex()}))
+			if w.Code != http.StatusForbidden {
+				mt.Fatalf("status = %d, want 403: %s", w.Code, w.Body)
+			}
+			if names := commandNames(mt); len(names) != 1 {
+				mt.Errorf("commands = %v, want only the project read", names)
+			}
+		})
+	}
+}
+
+// A reviewer can't get decision emails sent by asking for them with a cut
+func TestCutNotifyRequiresOwner(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	connected := make(chan struct{}, 10)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+			connected <- struct{}{}
+		}
+	}()
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	smtp, err := mail.NewSMTP(host, port, "", "", "Swiperank <no-reply@example.com>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mail.SetDefault(smtp)
+	defer mail.SetDefault(nil)
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	project := models.Project{
+		ID:       primitive.NewObjectID(),
+		Name:     "Recruiting",
+		OwnerIDs: []string{"user_alice"},
+		Stages:   []models.Stage{{Name: "Screen"}, {Name: "Interview"}},
+	}
+	applicants := []interface{}{
+		models.Applicant{ID: primitive.NewObjectID(), ProjectID: project.ID, Email: "ada@example.com", Elo: 1100, Status: models.ApplicantStatusActive},
+		models.Applicant{ID: primitive.NewObjectID(), ProjectID: project.ID, Email: "grace@example.com", Elo: 900, Status: models.ApplicantStatusActive},
+	}
+
+	tests := []struct {
+		userID     string
+		wantStatus int
+		wantMail   bool
+	}{
+		{"user_bob", http.StatusForbidden, false},
+		{"user_alice", http.StatusOK, true},
+	}
+	for _, tt := range tests {
+		mt.Run(tt.userID, func(mt *mtest.T) {
+			ac := testApplicantController(mt)
+			mt.AddMockResponses(
+				cursorOf(mt, "db.projects", project),
+				cursorOf(mt, "db.applicants", applicants...),
+				mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+				mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 2}, bson.E{Key: "nModified", Value: 2}),
+			)
+
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"top":1,"notify":true}`))
+			r = r.WithContext(middleware.WithUserID(r.Context(), tt.userID))
+			w := httptest.NewRecorder()
+			ac.Cut(w, withURLParams(r, map[string]string{"id": project.ID.Hex()}))
+			if w.Code != tt.wantStatus {
+				mt.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+
+			// the emails go out one after another in the background, each over its own connection
+			for sent := 0; sent < len(applicants); sent++ {
+				select {
+				case <-connected:
+					if !tt.wantMail {
+						mt.Fatal("decision email sent for a cut the caller wasn't allowed to make")
+					}
+				case <-time.After(200 * time.Millisecond):
+					if tt.wantMail {
+						mt.Fatalf("%d of %d decision emails sent for the owner's cut", sent, len(applicants))
+					}
+					return
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,582 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"backend/db"
+	"backend/events"
+	"backend/middleware"
+	"backend/models"
+	"backend/webhook"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// deliveries the dispatcher works on at once
+	webhookWorkers = 4
+	// how often idle workers look for due deliveries
+	webhookPollInterval = 2 * time.Second
+	// a claimed delivery is tried again after this if its worker never finishes it
+	webhookLease       = 2 * time.Minute
+	maxDeliveryLogSize = 100
+)
+
+// WebhookController manages a project's outgoing webhooks and delivers their events
+type WebhookController struct {
+	webhooks   *mongo.Collection
+	deliveries *mongo.Collection
+	projects   *mongo.Collection
+}
+
+func NewWebhookController() *WebhookController {
+	return &WebhookController{
+		webhooks:   db.GetCollection("webhooks"),
+		deliveries: db.GetCollection("webhook_deliveries"),
+		projects:   db.GetCollection("projects"),
+	}
+}
+
+type webhookRequest struct {
+	URL         *string   `json:"url"`
+	Events      *[]string `json:"events"`
+	Format      *string   `json:"format"`
+	Description *string   `json:"description"`
+	Active      *bool     `json:"active"`
+}
+
+// validate checks the fields present, all of url and events being required when creating
+func (req *webhookRequest) validate(creating bool) map[string]string {
+	fields := map[string]string{}
+	if req.URL != nil || creating {
+		if req.URL == nil {
+			fields["url"] = "is required"
+		} else if webhook.ValidateURL(*req.URL) != nil {
+			fields["url"] = "must be an http or https URL of a public host"
+		}
+	}
+	if req.Events != nil || creating {
+		if req.Events == nil || len(*req.Events) == 0 {
+			fields["events"] = "subscribe to at least one event type, or \"*\""
+		} else {
+			for _, e := range *req.Events {
+				if !validEventType(e) {
+					fields["events"] = fmt.Sprintf("unknown event type %q", e)
+				}
+			}
+		}
+	}
+	if req.Format != nil && !models.ValidWebhookFormat(*req.Format) {
+		fields["format"] = "must be json, slack or discord"
+	}
+	return fields
+}
+
+func validEventType(kind string) bool {
+	if kind == "*" {
+		return true
+	}
+	for _, t := range events.Types {
+		if t == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// ownedProject loads the project in the URL, refusing callers who aren't among its owners when it has any
+func (wc *WebhookController) ownedProject(ctx context.Context, w http.ResponseWriter, r *http.Request) *models.Project {
+	projectID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid Project ID")
+		return nil
+	}
+	var project models.Project
+	if err := wc.projects.FindOne(ctx, bson.M{"_id": projectID}).Decode(&project); err != nil {
+		if err == mongo.ErrNoDocuments {
+			writeError(w, http.StatusNotFound, "Project not found")
+			return nil
+		}
+		writeError(w, http.StatusInternalServerError, "Failed to fetch project")
+		log.Println("MongoDB Find project error:", err)
+		return nil
+	}
+	if len(project.OwnerIDs) > 0 && !project.IsOwner(middleware.UserID(r.Context())) {
+		writeError(w, http.StatusForbidden, "Only project owners can manage webhooks")
+		return nil
+	}
+	return &project
+}
+
+// findWebhook loads the project's webhook in the URL
+func (wc *WebhookController) findWebhook(ctx context.Context, w http.ResponseWriter, r *http.Request, project *models.Project) *models.Webhook {
+	webhookID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "webhookId"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid Webhook ID")
+		return nil
+	}
+	var hook models.Webhook
+	if err := wc.webhooks.FindOne(ctx, bson.M{"_id": webhookID, "project_id": project.ID}).Decode(&hook); err != nil {
+		if err == mongo.ErrNoDocuments {
+			writeError(w, http.StatusNotFound, "Webhook not found")
+			return nil
+		}
+		writeError(w, http.StatusInternalServerError, "Failed to fetch webhook")
+		log.Println("MongoDB Find webhook error:", err)
+		return nil
+	}
+	return &hook
+}
+
+// List returns the project's webhooks
+func (wc *WebhookController) List(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	project := wc.ownedProject(ctx, w, r)
+	if project == nil {
+		return
+	}
+	cursor, err := wc.webhooks.Find(ctx, bson.M{"project_id": project.ID}, options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}}))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to fetch webhooks")
+		log.Println("MongoDB Find webhooks error:", err)
+		return
+	}
+	hooks := []models.Webhook{}
+	if err := cursor.All(ctx, &hooks); err != nil {
+		writeError(w, http.StatusInternalServerError, "Error decoding webhooks")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(hooks)
+}
+
+// Create adds a webhook. Body: {"url", "events": ["ranking-changed", "project-closed"], "format", "description"}.
+// The signing secret is only returned here.
+func (wc *WebhookController) Create(w http.ResponseWriter, r *http.Request) {
+	var request webhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid JSON input: "+err.Error())
+		return
+	}
+	if fields := request.validate(true); len(fields) > 0 {
+		writeValidationError(w, fields)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	project := wc.ownedProject(ctx, w, r)
+	if project == nil {
+		return
+	}
+	secret, err := webhook.GenerateSecret()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to create webhook")
+		log.Println(err)
+		return
+	}
+
+	hook := models.Webhook{
+		ID:        primitive.NewObjectID(),
+		ProjectID: project.ID,
+		URL:       *request.URL,
+		Events:    *request.Events,
+		Active:    request.Active == nil || *request.Active,
+		Secret:    secret,
+		CreatedAt: time.Now(),
+	}
+	if request.Format != nil {
+		hook.Format = *request.Format
+	}
+	if request.Description != nil {
+		hook.Description = *request.Description
+	}
+	if _, err := wc.webhooks.InsertOne(ctx, hook); err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to create webhook")
+		log.Println("MongoDB Insert webhook error:", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(struct {
+		models.Webhook
+		Secret string `json:"secret"`
+	}{hook, hook.Secret})
+}
+
+// Update changes the fields present in the body; {"active": false} pauses deliveries
+func (wc *WebhookController) Update(w http.ResponseWriter, r *http.Request) {
+	var request webhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid JSON input: "+err.Error())
+		return
+	}
+	if fields := request.validate(false); len(fields) > 0 {
+		writeValidationError(w, fields)
+		return
+	}
+
+	set := bson.M{}
+	if request.URL != nil {
+		set["url"] = *request.URL
+	}
+	if request.Events != nil {
+		set["events"] = *request.Events
+	}
+	if request.Format != nil {
+		set["format"] = *request.Format
+	}
+	if request.Description != nil {
+		set["description"] = *request.Description
+	}
+	if request.Active != nil {
+		set["active"] = *request.Active
+	}
+	if len(set) == 0 {
+		writeError(w, http.StatusBadRequest, "No fields to update")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	project := wc.ownedProject(ctx, w, r)
+	if project == nil {
+		return
+	}
+	hook := wc.findWebhook(ctx, w, r, project)
+	if hook == nil {
+		return
+	}
+
+	var updated models.Webhook
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	if err := wc.webhooks.FindOneAndUpdate(ctx, bson.M{"_id": hook.ID}, bson.M{"$set": set}, opts).Decode(&updated); err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to update webhook")
+		log.Println("MongoDB Update webhook error:", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+// Delete removes a webhook. Its pending deliveries fail when their turn comes; the log is kept.
+func (wc *WebhookController) Delete(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	project := wc.ownedProject(ctx, w, r)
+	if project == nil {
+		return
+	}
+	hook := wc.findWebhook(ctx, w, r, project)
+	if hook == nil {
+		return
+	}
+	if _, err := wc.webhooks.DeleteOne(ctx, bson.M{"_id": hook.ID}); err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to delete webhook")
+		log.Println("MongoDB Delete webhook error:", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Ping queues a "ping" event to the webhook so its endpoint can be checked
+func (wc *WebhookController) Ping(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	project := wc.ownedProject(ctx, w, r)
+	if project == nil {
+		return
+	}
+	hook := wc.findWebhook(ctx, w, r, project)
+	if hook == nil {
+		return
+	}
+
+	event := events.Event{Type: "ping", ProjectID: project.ID, At: time.Now(), Data: map[string]string{"webhookId": hook.ID.Hex()}}
+	delivery, err := wc.enqueue(ctx, hook, project, event)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to queue ping")
+		log.Println("Webhook enqueue error:", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(delivery)
+}
+
+// Deliveries is the webhook's delivery log, newest first. ?status= narrows it to pending, delivered or failed.
+func (wc *WebhookController) Deliveries(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	project := wc.ownedProject(ctx, w, r)
+	if project == nil {
+		return
+	}
+	hook := wc.findWebhook(ctx, w, r, project)
+	if hook == nil {
+		return
+	}
+
+	filter := bson.M{"webhookId": hook.ID}
+	if status := r.URL.Query().Get("status"); status != "" {
+		filter["status"] = status
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}).SetLimit(maxDeliveryLogSize)
+	cursor, err := wc.deliveries.Find(ctx, filter, opts)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to fetch deliveries")
+		log.Println("MongoDB Find deliveries error:", err)
+		return
+	}
+	deliveries := []models.WebhookDelivery{}
+	if err := cursor.All(ctx, &deliveries); err != nil {
+		writeError(w, http.StatusInternalServerError, "Error decoding deliveries")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(deliveries)
+}
+
+// Redeliver queues a failed delivery again with a fresh set of retries
+func (wc *WebhookController) Redeliver(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	project := wc.ownedProject(ctx, w, r)
+	if project == nil {
+		return
+	}
+	hook := wc.findWebhook(ctx, w, r, project)
+	if hook == nil {
+		return
+	}
+	deliveryID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "deliveryId"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid Delivery ID")
+		return
+	}
+
+	var delivery models.WebhookDelivery
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err = wc.deliveries.FindOneAndUpdate(ctx,
+		bson.M{"_id": deliveryID, "webhookId": hook.ID, "status": models.WebhookDeliveryFailed},
+		bson.M{"$set": bson.M{"status": models.WebhookDeliveryPending, "attempts": 0, "nextAttemptAt": time.Now()}},
+		opts).Decode(&delivery)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			writeError(w, http.StatusNotFound, "No failed delivery with that id")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "Failed to queue delivery")
+		log.Println("MongoDB Update delivery error:", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(delivery)
+}
+
+// webhookOutbox queues the deliveries of every published event; nil until RunWebhookDispatcher
+// has started, and in tests, when events queue nothing
+var webhookOutbox *WebhookController
+
+// RunWebhookDispatcher starts queueing published events for the webhooks subscribed to them and
+// delivers the queue, retrying failures with exponential backoff. The queue is the deliveries
+// collection, so deliveries pending when the server stops go out once it is back. It runs until
+// the process exits.
+func RunWebhookDispatcher() {
+	wc := NewWebhookController()
+	webhookOutbox = wc
+
+	for i := 0; i < webhookWorkers; i++ {
+		go func() {
+			for {
+				if !wc.deliverNext() {
+					time.Sleep(webhookPollInterval)
+				}
+			}
+		}()
+	}
+}
+
+// enqueueEvent queues event for every active webhook of its project subscribed to its type
+func (wc *WebhookController) enqueueEvent(event events.Event) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := wc.webhooks.Find(ctx, bson.M{"project_id": event.ProjectID, "active": true})
+	if err != nil {
+		log.Println("MongoDB Find webhooks error:", err)
+		return
+	}
+	var hooks []models.Webhook
+	if err := cursor.All(ctx, &hooks); err != nil {
+		log.Println("Cursor decode error:", err)
+		return
+	}
+
+	var project *models.Project
+	for i := range hooks {
+		if !hooks[i].Subscribes(event.Type) {
+			continue
+		}
+		if project == nil {
+			project = &models.Project{ID: event.ProjectID}
+			if err := wc.projects.FindOne(ctx, bson.M{"_id": event.ProjectID}).Decode(project); err != nil {
+				log.Println("MongoDB Find project error:", err)
+			}
+		}
+		if _, err := wc.enqueue(ctx, &hooks[i], project, event); err != nil {
+			log.Println("Webhook enqueue error:", err)
+		}
+	}
+}
+
+// enqueue renders event for hook and adds it to the queue, due now
+func (wc *WebhookController) enqueue(ctx context.Context, hook *models.Webhook, project *models.Project, event events.Event) (*models.WebhookDelivery, error) {
+	payload, err := webhookPayload(hook, project, event)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	delivery := &models.WebhookDelivery{
+		ID:            primitive.NewObjectID(),
+		WebhookID:     hook.ID,
+		ProjectID:     project.ID,
+		EventType:     event.Type,
+		Payload:       string(payload),
+		Status:        models.WebhookDeliveryPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}
+	if _, err := wc.deliveries.InsertOne(ctx, delivery); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// webhookPayload is the body sent for event: the event itself with the project's name, or for
+// Slack and Discord a one-line summary in the field their incoming webhooks read
+func webhookPayload(hook *models.Webhook, project *models.Project, event events.Event) ([]byte, error) {
+	switch hook.Format {
+	case models.WebhookFormatSlack:
+		return json.Marshal(map[string]string{"text": eventSummary(project, event)})
+	case models.WebhookFormatDiscord:
+		return json.Marshal(map[string]string{"content": eventSummary(project, event)})
+	}
+	return json.Marshal(struct {
+		events.Event
+		ProjectName string `json:"projectName"`
+	}{event, project.Name})
+}
+
+// eventSummary describes event in a line, without naming applicants so blind review holds
+func eventSummary(project *models.Project, event events.Event) string {
+	name := project.Name
+	if name == "" {
+		name = project.ID.Hex()
+	}
+	switch event.Type {
+	case events.VoteRecorded:
+		return fmt.Sprintf("%s: a vote was recorded", name)
+	case events.RankingChanged:
+		return fmt.Sprintf("%s: the ranking changed", name)
+	case events.ApplicantAdded:
+		return fmt.Sprintf("%s: a new application came in", name)
+	case events.ProjectClosed:
+		return fmt.Sprintf("%s is closed for review", name)
+	case events.ProjectStatus:
+		if status, ok := event.Data.(projectStatus); ok {
+			return fmt.Sprintf("%s was %s", name, status.Reason)
+		}
+		return fmt.Sprintf("%s was updated", name)
+	case "ping":
+		return fmt.Sprintf("%s: webhook test", name)
+	}
+	return fmt.Sprintf("%s: %s", name, event.Type)
+}
+
+// deliverNext claims the delivery due soonest and tries it once. It reports whether there was one.
+func (wc *WebhookController) deliverNext() bool {
+	ctx, cancel := context.WithTimeout(context.Background(), webhookLease)
+	defer cancel()
+
+	// claiming pushes the next attempt past the lease, so a worker that dies mid-delivery only delays it
+	now := time.Now()
+	var delivery models.WebhookDelivery
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "nextAttemptAt", Value: 1}}).
+		SetReturnDocument(options.After)
+	err := wc.deliveries.FindOneAndUpdate(ctx,
+		bson.M{"status": models.WebhookDeliveryPending, "nextAttemptAt": bson.M{"$lte": now}},
+		bson.M{"$set": bson.M{"nextAttemptAt": now.Add(webhookLease)}},
+		opts).Decode(&delivery)
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			log.Println("MongoDB claim delivery error:", err)
+		}
+		return false
+	}
+
+	var hook models.Webhook
+	if err := wc.webhooks.FindOne(ctx, bson.M{"_id": delivery.WebhookID}).Decode(&hook); err != nil {
+		if err == mongo.ErrNoDocuments {
+			wc.finishDelivery(ctx, &delivery, 0, fmt.Errorf("webhook was deleted"), true)
+		} else {
+			log.Println("MongoDB Find webhook error:", err)
+		}
+		return true
+	}
+
+	status, sendErr := webhook.Send(ctx, hook.URL, hook.Secret, delivery.EventType, delivery.ID.Hex(), []byte(delivery.Payload))
+	// an internal address won't become deliverable by retrying
+	wc.finishDelivery(ctx, &delivery, status, sendErr, errors.Is(sendErr, webhook.ErrPrivateAddress))
+	return true
+}
+
+// finishDelivery records an attempt: delivered, retried later, or failed for good
+func (wc *WebhookController) finishDelivery(ctx context.Context, delivery *models.WebhookDelivery, status int, sendErr error, giveUp bool) {
+	now := time.Now()
+	set := bson.M{"attempts": delivery.Attempts + 1, "responseStatus": status}
+	switch {
+	case sendErr == nil:
+		set["status"] = models.WebhookDeliveryDelivered
+		set["deliveredAt"] = now
+		set["lastError"] = ""
+	case giveUp || delivery.Attempts+1 >= webhook.MaxAttempts:
+		set["status"] = models.WebhookDeliveryFailed
+		set["lastError"] = sendErr.Error()
+	default:
+		set["nextAttemptAt"] = now.Add(webhook.Backoff(delivery.Attempts + 1))
+		set["lastError"] = sendErr.Error()
+	}
+	if _, err := wc.deliveries.UpdateOne(ctx, bson.M{"_id": delivery.ID}, bson.M{"$set": set}); err != nil {
+		log.Println("MongoDB Update delivery error:", err)
+	}
+	if sendErr != nil {
+		log.Printf("Webhook delivery %s attempt %d failed: %v", delivery.ID.Hex(), delivery.Attempts+1, sendErr)
+	}
+}
@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"encoding/json"
+	"testing"
+
+	"backend/events"
+	"backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestPublishQueuesWebhookDeliveries(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("outbox", func(mt *mtest.T) {
+		outbox := webhookOutbox
+		webhookOutbox = &WebhookController{
+			webhooks:   mt.DB.Collection("webhooks"),
+			deliveries: mt.DB.Collection("webhook_deliveries"),
+			projects:   mt.DB.Collection("projects"),
+		}
+		defer func() { webhookOutbox = outbox }()
+
+		project := models.Project{ID: primitive.NewObjectID(), Name: "Fall recruiting"}
+		subscribed := models.Webhook{ID: primitive.NewObjectID(), ProjectID: project.ID, URL: "https://hooks.example.com/a", Events: []string{events.RankingChanged}, Active: true}
+		other := models.Webhook{ID: primitive.NewObjectID(), ProjectID: project.ID, URL: "https://hooks.example.com/b", Events: []string{events.VoteRecorded}, Active: true}
+		mt.AddMockResponses(
+			cursorOf(mt, "db.webhooks", subscribed, other),
+			cursorOf(mt, "db.projects", project),
+			mtest.CreateSuccessResponse(),
+		)
+
+		// no dispatcher or stream subscriber is running; the delivery must still be queued
+		publishRankingChanged(project.ID, 2)
+
+		var inserts []bson.Raw
+		for _, e := range mt.GetAllStartedEvents() {
+			if e.CommandName == "insert" {
+				inserts = append(inserts, e.Command)
+			}
+		}
+		if len(inserts) != 1 {
+			mt.Fatalf("%d deliveries inserted, want 1 for the subscribed webhook", len(inserts))
+		}
+		if coll := inserts[0].Lookup("insert").StringValue(); coll != "webhook_deliveries" {
+			mt.Fatalf("inserted into %q, want webhook_deliveries", coll)
+		}
+		var delivery models.WebhookDelivery
+		if err := bson.Unmarshal(inserts[0].Lookup("documents", "0").Document(), &delivery); err != nil {
+			mt.Fatal(err)
+		}
+		if delivery.WebhookID != subscribed.ID || delivery.EventType != events.RankingChanged || delivery.Status != models.WebhookDeliveryPending {
+			mt.Errorf("delivery = %+v, want a pending ranking-changed delivery to %s", delivery, subscribed.ID.Hex())
+		}
+		var payload struct {
+			Type        string         `json:"type"`
+			ProjectName string         `json:"projectName"`
+			Data        map[string]int `json:"data"`
+		}
+		if err := json.Unmarshal([]byte(delivery.Payload), &payload); err != nil {
+			mt.Fatal(err)
+		}
+		if payload.Type != events.RankingChanged || payload.ProjectName != project.Name || payload.Data["stage"] != 2 {
+			mt.Errorf("payload = %s", delivery.Payload)
+		}
+	})
+}
@@ -0,0 +1,121 @@
+package db
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// EnsureIndexes creates the indexes the API relies on. Creating an index that already exists is a no-op.
+func EnsureIndexes() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// full-text search over applicants; a collection can only have one text index
+	_, err := GetCollection("applicants").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "firstName", Value: "text"},
+			{Key: "lastName", Value: "text"},
+			{Key: "major", Value: "text"},
+			{Key: "resumeText", Value: "text"},
+			{Key: "coverLetterText", Value: "text"},
+			{Key: "responses.answer", Value: "text"},
+		},
+		Options: options.Index().
+			SetName("applicant_text").
+			SetWeights(bson.D{
+				{Key: "firstName", Value: 10},
+				{Key: "lastName", Value: 10},
+				{Key: "major", Value: 5},
+			}),
+	})
+	if err != nil {
+		log.Println("Failed to create applicant text index:", err)
+	}
+
+	// duplicate detection at intake: case-insensitive email lookups, and name scans within a project
+	_, err = GetCollection("applicants").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "project_id", Value: 1}, {Key: "email", Value: 1}},
+			Options: options.Index().
+				SetName("applicant_email").
+				SetCollation(&options.Collation{Locale: "en", Strength: 2}),
+		},
+		{Keys: bson.D{{Key: "project_id", Value: 1}, {Key: "lastName", Value: 1}, {Key: "firstName", Value: 1}}},
+	})
+	if err != nil {
+		log.Println("Failed to create applicant duplicate indexes:", err)
+	}
+
+	_, err = GetCollection("comparisons").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "project_id", Value: 1}, {Key: "createdAt", Value: 1}}},
+		{Keys: bson.D{{Key: "winnerId", Value: 1}}},
+		{Keys: bson.D{{Key: "loserId", Value: 1}}},
+	})
+	if err != nil {
+		log.Println("Failed to create comparison indexes:", err)
+	}
+
+	_, err = GetCollection("ranking_snapshots").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "project_id", Value: 1}, {Key: "takenAt", Value: -1}},
+	})
+	if err != nil {
+		log.Println("Failed to create ranking snapshot index:", err)
+	}
+
+	_, err = GetCollection("webhooks").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "project_id", Value: 1}},
+	})
+	if err != nil {
+		log.Println("Failed to create webhook index:", err)
+	}
+
+	// the dispatcher's queue, each webhook's delivery log, and a month's retention of it
+	_, err = GetCollection("webhook_deliveries").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "nextAttemptAt", Value: 1}}},
+		{Keys: bson.D{{Key: "webhookId", Value: 1}, {Key: "createdAt", Value: -1}}},
+		{Keys: bson.D{{Key: "createdAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(30 * 24 * 60 * 60)},
+	})
+	if err != nil {
+		log.Println("Failed to create webhook delivery indexes:", err)
+	}
+
+	// accepted form webhook deliveries, forgotten once a replay could no longer verify
+	_, err = GetCollection("webhook_replays").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		log.Println("Failed to create webhook replay index:", err)
+	}
+
+	// each project's quarantined submissions, newest first
+	_, err = GetCollection("quarantine").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "project_id", Value: 1}, {Key: "receivedAt", Value: -1}},
+	})
+	if err != nil {
+		log.Println("Failed to create quarantine index:", err)
+	}
+
+	// only applications waiting for enrichment carry enrichmentDueAt
+	_, err = GetCollection("applicants").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "enrichmentDueAt", Value: 1}},
+		Options: options.Index().SetSparse(true),
+	})
+	if err != nil {
+		log.Println("Failed to create applicant enrichment index:", err)
+	}
+
+	// and only those waiting for thumbnails and previews carry previewsDueAt
+	_, err = GetCollection("applicants").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "previewsDueAt", Value: 1}},
+		Options: options.Index().SetSparse(true),
+	})
+	if err != nil {
+		log.Println("Failed to create applicant preview index:", err)
+	}
+}
@@ -31,6 +31,10 @@ func ConnectMongoDB(uri string) {
 	Client = client
 }
 
+func GetDatabase() *mongo.Database {
+	return Client.Database("akpsi-ucsb")
+}
+
 func GetCollection(collectionName string) *mongo.Collection {
-	return Client.Database("akpsi-ucsb").Collection(collectionName)
+	return GetDatabase().Collection(collectionName)
 }
\ No newline at end of file
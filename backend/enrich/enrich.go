@@ -0,0 +1,77 @@
+// Package enrich adds machine-written notes to applications after intake, such as a short summary of
+// the resume and essay answers for reviewers skimming a pair.
+package enrich
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"backend/blind"
+	"backend/models"
+)
+
+// Enricher produces one note about an applicant. The pipeline runs every configured enricher on each
+// new or resubmitted application and stores the result under the enricher's name.
+type Enricher interface {
+	Name() string
+	Enrich(ctx context.Context, applicant *models.Applicant) (models.Enrichment, error)
+}
+
+var enrichers []Enricher
+
+// Setup configures the enrichers from the environment. ENRICH_LLM_URL is the base URL of an
+// OpenAI-compatible API (https://api.openai.com/v1, a local Ollama or vLLM server, ...), with
+// ENRICH_LLM_API_KEY, ENRICH_LLM_MODEL and ENRICH_LLM_TIMEOUT. Without it nothing is enriched.
+func Setup() {
+	enrichers = nil
+	if url := os.Getenv("ENRICH_LLM_URL"); url != "" {
+		timeout, err := time.ParseDuration(os.Getenv("ENRICH_LLM_TIMEOUT"))
+		if err != nil || timeout <= 0 {
+			timeout = DefaultLLMTimeout
+		}
+		enrichers = append(enrichers, NewLLMSummariser(url, os.Getenv("ENRICH_LLM_API_KEY"), os.Getenv("ENRICH_LLM_MODEL"), timeout))
+	}
+	if len(enrichers) == 0 {
+		log.Println("Warning: ENRICH_LLM_URL is not set, applications will not be summarised")
+		return
+	}
+	for _, e := range enrichers {
+		log.Println("Enriching applications with", e.Name())
+	}
+}
+
+// Enrichers returns the configured enrichers, none when enrichment is off
+func Enrichers() []Enricher {
+	return enrichers
+}
+
+// Input is the application text enrichers work from: free-text answers, the resume and the cover
+// letter, cut to about limit characters. Names, contact details and profile links are redacted first,
+// so they aren't sent to outside services and can't turn up in what reviewers of a blind project read.
+func Input(applicant *models.Applicant, limit int) string {
+	names := []string{applicant.FirstName, applicant.LastName}
+	var b strings.Builder
+	for _, resp := range applicant.Responses {
+		answer, ok := resp.Answer.(string)
+		if !ok || strings.TrimSpace(answer) == "" {
+			continue
+		}
+		fmt.Fprintf(&b, "Q: %s\nA: %s\n\n", resp.Question, blind.Redact(answer, names, "the applicant"))
+	}
+	if applicant.ResumeText != "" {
+		fmt.Fprintf(&b, "Resume:\n%s\n\n", blind.Redact(applicant.ResumeText, names, "the applicant"))
+	}
+	if applicant.CoverLetterText != "" {
+		fmt.Fprintf(&b, "Cover letter:\n%s\n", blind.Redact(applicant.CoverLetterText, names, "the applicant"))
+	}
+
+	text := strings.TrimSpace(b.String())
+	if runes := []rune(text); len(runes) > limit {
+		text = string(runes[:limit]) + "\n[truncated]"
+	}
+	return text
+}
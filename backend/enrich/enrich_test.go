@@ -0,0 +1,53 @@
+package enrich
+
+import (
+	"strings"
+	"testing"
+
+	"backend/models"
+)
+
+func TestInputRedactsIdentity(t *testing.T) {
+	applicant := &models.Applicant{
+		FirstName: "Ada",
+		LastName:  "Lovelace",
+		Responses: []models.Response{
+			{Question: "Why do you want to join?", Answer: "I'm Ada Lovelace and I love engines. Reach me at ada@example.com."},
+			{Question: "Anything else?", Answer: "   "},
+			{Question: "Year", Answer: 3},
+		},
+		ResumeText:      "ADA LOVELACE\n(555) 123-4567 | linkedin.com/in/adalovelace\nAnalyst, Analytical Engine",
+		CoverLetterText: "Dear committee, lovelace here. See github.com/ada.",
+	}
+
+	got := Input(applicant, maxInputChars)
+	for _, leaked := range []string{"Ada", "ADA", "Lovelace", "LOVELACE", "lovelace", "ada@example.com", "555", "linkedin.com", "github.com"} {
+		if strings.Contains(got, leaked) {
+			t.Errorf("Input() contains %q:\n%s", leaked, got)
+		}
+	}
+	for _, kept := range []string{
+		"Q: Why do you want to join?\nA: I'm the applicant and I love engines. Reach me at [email].",
+		"Resume:\nthe applicant\n[phone] | [link]\nAnalyst, Analytical Engine",
+		"Cover letter:\nDear committee, the applicant here. See [link]",
+	} {
+		if !strings.Contains(got, kept) {
+			t.Errorf("Input() is missing %q:\n%s", kept, got)
+		}
+	}
+	// blank and non-text answers are left out
+	if strings.Contains(got, "Anything else?") || strings.Contains(got, "Year") {
+		t.Errorf("Input() kept an answer without text:\n%s", got)
+	}
+}
+
+func TestInputTruncates(t *testing.T) {
+	applicant := &models.Applicant{ResumeText: strings.Repeat("é", 100)}
+	got := Input(applicant, 20)
+	if want := "Resume:\n" + strings.Repeat("é", 12) + "\n[truncated]"; got != want {
+		t.Fatalf("Input() = %q, want %q", got, want)
+	}
+	if got := Input(&models.Applicant{}, 20); got != "" {
+		t.Fatalf("Input() of an empty application = %q", got)
+	}
+}
@@ -0,0 +1,132 @@
+package enrich
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"backend/models"
+)
+
+const (
+	DefaultLLMTimeout = time.Minute
+	DefaultLLMModel   = "gpt-4o-mini"
+	// how much application text is sent, roughly 6k tokens
+	maxInputChars = 24000
+)
+
+const summaryPrompt = `You help a student organisation review applications. Summarise the application below for a reviewer
+in at most 80 words: relevant experience, skills, what the applicant wants to do and anything notable in
+their answers. Stick to what the application says, don't judge or score it, and never guess at a name,
+gender, ethnicity or age.`
+
+// LLMSummariser writes a short summary of the resume and essay answers with a chat model served over
+// an OpenAI-compatible /chat/completions endpoint
+type LLMSummariser struct {
+	url    string
+	apiKey string
+	model  string
+	client *http.Client
+}
+
+func NewLLMSummariser(baseURL, apiKey, model string, timeout time.Duration) *LLMSummariser {
+	if model == "" {
+		model = DefaultLLMModel
+	}
+	return &LLMSummariser{
+		url:    strings.TrimRight(baseURL, "/") + "/chat/completions",
+		apiKey: apiKey,
+		model:  model,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+func (s *LLMSummariser) Name() string {
+	return "summary"
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatRequest struct {
+	Model       string        `json:"model"`
+	Messages    []chatMessage `json:"messages"`
+	Temperature float64       `json:"temperature"`
+	MaxTokens   int           `json:"max_tokens"`
+}
+
+type chatResponse struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (s *LLMSummariser) Enrich(ctx context.Context, applicant *models.Applicant) (models.Enrichment, error) {
+	input := Input(applicant, maxInputChars)
+	if input == "" {
+		return models.Enrichment{}, errors.New("application has no text to summarise")
+	}
+
+	body, err := json.Marshal(chatRequest{
+		Model: s.model,
+		Messages: []chatMessage{
+			{Role: "system", Content: summaryPrompt},
+			{Role: "user", Content: input},
+		},
+		Temperature: 0.2,
+		MaxTokens:   300,
+	})
+	if err != nil {
+		return models.Enrichment{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return models.Enrichment{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return models.Enrichment{}, fmt.Errorf("error calling model: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return models.Enrichment{}, fmt.Errorf("error reading model response: %w", err)
+	}
+
+	var completion chatResponse
+	if err := json.Unmarshal(data, &completion); err != nil {
+		return models.Enrichment{}, fmt.Errorf("model answered %s with an unreadable body", resp.Status)
+	}
+	if resp.StatusCode != http.StatusOK {
+		if completion.Error != nil {
+			return models.Enrichment{}, fmt.Errorf("model answered %s: %s", resp.Status, completion.Error.Message)
+		}
+		return models.Enrichment{}, fmt.Errorf("model answered %s", resp.Status)
+	}
+	if len(completion.Choices) == 0 || strings.TrimSpace(completion.Choices[0].Message.Content) == "" {
+		return models.Enrichment{}, errors.New("model returned no summary")
+	}
+
+	model := completion.Model
+	if model == "" {
+		model = s.model
+	}
+	return models.Enrichment{Summary: strings.TrimSpace(completion.Choices[0].Message.Content), Model: model}, nil
+}
@@ -0,0 +1,111 @@
+package enrich
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/models"
+)
+
+// chatServer answers /chat/completions with status and body, recording the request it got
+func chatServer(t *testing.T, status int, body string, got *chatRequest, auth *string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("request = %s %s", r.Method, r.URL.Path)
+		}
+		if got != nil {
+			if err := json.NewDecoder(r.Body).Decode(got); err != nil {
+				t.Error(err)
+			}
+		}
+		if auth != nil {
+			*auth = r.Header.Get("Authorization")
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func testApplicant() *models.Applicant {
+	return &models.Applicant{
+		FirstName:  "Ada",
+		LastName:   "Lovelace",
+		ResumeText: "Ada Lovelace, analyst. Wrote the first published algorithm.",
+	}
+}
+
+func TestLLMSummariserEnrich(t *testing.T) {
+	var request chatRequest
+	var auth string
+	server := chatServer(t, http.StatusOK,
+		`{"model":"gpt-4o-mini-2024-07-18","choices":[{"message":{"role":"assistant","content":"  Analyst who wrote the first published algorithm.\n"}}]}`,
+		&request, &auth)
+
+	s := NewLLMSummariser(server.URL+"/v1/", "sk-test", "", time.Second)
+	got, err := s.Enrich(context.Background(), testApplicant())
+	if err != nil {
+		t.Fatalf("Enrich() error = %v", err)
+	}
+	if got.Summary != "Analyst who wrote the first published algorithm." || got.Model != "gpt-4o-mini-2024-07-18" {
+		t.Errorf("Enrich() = %+v", got)
+	}
+
+	if auth != "Bearer sk-test" {
+		t.Errorf("Authorization = %q", auth)
+	}
+	if request.Model != DefaultLLMModel || len(request.Messages) != 2 || request.Messages[0].Role != "system" {
+		t.Fatalf("request = %+v", request)
+	}
+	user := request.Messages[1]
+	if user.Role != "user" || !strings.Contains(user.Content, "the applicant, analyst") || strings.Contains(user.Content, "Lovelace") {
+		t.Errorf("user message = %q, want the redacted resume", user.Content)
+	}
+}
+
+func TestLLMSummariserEnrichErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr string
+	}{
+		{"error body", http.StatusTooManyRequests, `{"error":{"message":"Rate limit reached"}}`, "model answered 429 Too Many Requests: Rate limit reached"},
+		{"status only", http.StatusInternalServerError, `{}`, "model answered 500 Internal Server Error"},
+		{"not json", http.StatusBadGateway, `<html>bad gateway</html>`, "model answered 502 Bad Gateway with an unreadable body"},
+		{"no choices", http.StatusOK, `{"model":"m","choices":[]}`, "model returned no summary"},
+		{"blank summary", http.StatusOK, `{"choices":[{"message":{"role":"assistant","content":"  "}}]}`, "model returned no summary"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := chatServer(t, tt.status, tt.body, nil, nil)
+			s := NewLLMSummariser(server.URL+"/v1", "", "local-model", time.Second)
+			got, err := s.Enrich(context.Background(), testApplicant())
+			if err == nil || err.Error() != tt.wantErr {
+				t.Fatalf("Enrich() error = %v, want %q", err, tt.wantErr)
+			}
+			if got != (models.Enrichment{}) {
+				t.Errorf("Enrich() = %+v alongside an error", got)
+			}
+		})
+	}
+}
+
+func TestLLMSummariserNoText(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("the model was called for an application without text")
+	}))
+	defer server.Close()
+
+	s := NewLLMSummariser(server.URL, "", "", time.Second)
+	if _, err := s.Enrich(context.Background(), &models.Applicant{FirstName: "Ada"}); err == nil {
+		t.Fatal("Enrich() of an application without text succeeded")
+	}
+}
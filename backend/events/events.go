@@ -0,0 +1,113 @@
+// Package events is an in-process bus carrying project activity to whoever is listening:
+// the SSE stream the results page follows, and anything else that reacts to votes and intake.
+package events
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Event types
+const (
+	// a reviewer's vote was applied to the ratings
+	VoteRecorded = "vote-recorded"
+	// the order of a project's ranking changed
+	RankingChanged = "ranking-changed"
+	// a new applicant came in through intake
+	ApplicantAdded = "applicant-added"
+	// a project was closed, reopened, cut to its next stage or had its settings changed
+	ProjectStatus = "project-status"
+	// a project was closed for review, by hand or by auto-close; sent along with its project-status
+	ProjectClosed = "project-closed"
+)
+
+// Types lists every event type
+var Types = []string{VoteRecorded, RankingChanged, ApplicantAdded, ProjectStatus, ProjectClosed}
+
+// Event is one thing that happened in a project. ID increases with every event published.
+type Event struct {
+	ID        uint64             `json:"id"`
+	Type      string             `json:"type"`
+	ProjectID primitive.ObjectID `json:"projectId"`
+	At        time.Time          `json:"at"`
+	Data      interface{}        `json:"data,omitempty"`
+}
+
+// DefaultBuffer is how many events a subscriber may fall behind by before it starts missing them
+const DefaultBuffer = 64
+
+type subscriber struct {
+	projectID primitive.ObjectID
+	events    chan Event
+}
+
+// Bus fans published events out to subscribers. Publishing never blocks: a subscriber
+// too slow to keep up misses events rather than holding up the request that published them.
+type Bus struct {
+	mu          sync.RWMutex
+	subscribers map[*subscriber]struct{}
+	sequence    atomic.Uint64
+}
+
+func NewBus() *Bus {
+	return &Bus{subscribers: map[*subscriber]struct{}{}}
+}
+
+// Publish sends an event of type kind about projectID to its subscribers and returns it
+func (b *Bus) Publish(kind string, projectID primitive.ObjectID, data interface{}) Event {
+	event := Event{
+		ID:        b.sequence.Add(1),
+		Type:      kind,
+		ProjectID: projectID,
+		At:        time.Now(),
+		Data:      data,
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for s := range b.subscribers {
+		if !s.projectID.IsZero() && s.projectID != projectID {
+			continue
+		}
+		select {
+		case s.events <- event:
+		default:
+		}
+	}
+	return event
+}
+
+// Subscribe returns the events published about projectID, or about every project when it is
+// the zero id, and a function that ends the subscription and closes the channel. The channel
+// holds up to buffer events the subscriber hasn't read yet.
+func (b *Bus) Subscribe(projectID primitive.ObjectID, buffer int) (<-chan Event, func()) {
+	s := &subscriber{projectID: projectID, events: make(chan Event, buffer)}
+	b.mu.Lock()
+	b.subscribers[s] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return s.events, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subscribers, s)
+			b.mu.Unlock()
+			close(s.events)
+		})
+	}
+}
+
+var defaultBus = NewBus()
+
+// Publish publishes on the process-wide bus
+func Publish(kind string, projectID primitive.ObjectID, data interface{}) Event {
+	return defaultBus.Publish(kind, projectID, data)
+}
+
+// Subscribe subscribes to the process-wide bus
+func Subscribe(projectID primitive.ObjectID, buffer int) (<-chan Event, func()) {
+	return defaultBus.Subscribe(projectID, buffer)
+}
@@ -0,0 +1,35 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+)
+
+func writeCSV(w io.Writer, table *Table) error {
+	out := csv.NewWriter(w)
+	if err := out.Write(table.Header); err != nil {
+		return err
+	}
+	for _, row := range table.Rows {
+		record := make([]string, len(row))
+		for i, cell := range row {
+			record[i] = csvSafe(text(cell))
+		}
+		if err := out.Write(record); err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// csvSafe stops spreadsheets from running applicant supplied text as a formula
+func csvSafe(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		if _, isNumber := parseNumber(s); !isNumber {
+			return "'" + s
+		}
+	}
+	return s
+}
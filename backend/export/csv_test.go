@@ -0,0 +1,51 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"reflect"
+	"testing"
+)
+
+func TestCSVEscapesFormulas(t *testing.T) {
+	tests := []struct {
+		cell interface{}
+		want string
+	}{
+		{"Ada Lovelace", "Ada Lovelace"},
+		{`=HYPERLINK("http://evil.example","click")`, `'=HYPERLINK("http://evil.example","click")`},
+		{"+cmd|' /C calc'!A0", "'+cmd|' /C calc'!A0"},
+		{"-2+3", "'-2+3"},
+		{"@SUM(A1:A9)", "'@SUM(A1:A9)"},
+		{"\t=1+1", "'\t=1+1"},
+		{"\r=1+1", "'\r=1+1"},
+		{"a=b", "a=b"},
+		{"-12.5", "-12.5"},
+		{"+44", "+44"},
+		{-3, "-3"},
+		{-0.25, "-0.25"},
+		{"", ""},
+		{nil, ""},
+	}
+
+	table := &Table{Header: []string{"Value", "Row"}}
+	for i, tt := range tests {
+		table.Rows = append(table.Rows, []interface{}{tt.cell, i})
+	}
+	var out bytes.Buffer
+	if err := Write(&out, CSV, table); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&out).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(records[0], table.Header) {
+		t.Errorf("header = %q, want %q", records[0], table.Header)
+	}
+	for i, tt := range tests {
+		if got := records[i+1][0]; got != tt.want {
+			t.Errorf("cell %#v written as %q, want %q", tt.cell, got, tt.want)
+		}
+	}
+}
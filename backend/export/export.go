@@ -0,0 +1,68 @@
+// Package export writes tables as CSV, XLSX or PDF for people who want rankings outside the app.
+package export
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+)
+
+const (
+	CSV  = "csv"
+	XLSX = "xlsx"
+	PDF  = "pdf"
+)
+
+// Table is what gets exported. Cells hold strings, ints or float64s; numbers stay numeric in XLSX.
+type Table struct {
+	Title  string
+	Header []string
+	Rows   [][]interface{}
+}
+
+// ContentType returns the MIME type for format, or "" when the format isn't supported
+func ContentType(format string) string {
+	switch format {
+	case CSV:
+		return "text/csv; charset=utf-8"
+	case XLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	case PDF:
+		return "application/pdf"
+	}
+	return ""
+}
+
+// Write renders table to w in format
+func Write(w io.Writer, format string, table *Table) error {
+	switch format {
+	case CSV:
+		return writeCSV(w, table)
+	case XLSX:
+		return writeXLSX(w, table)
+	case PDF:
+		return writePDF(w, table)
+	}
+	return fmt.Errorf("unsupported export format %q", format)
+}
+
+// text formats a cell for the text based formats
+func text(cell interface{}) string {
+	switch v := cell.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func parseNumber(s string) (float64, bool) {
+	v, err := strconv.ParseFloat(s, 64)
+	return v, err == nil
+}
@@ -0,0 +1,160 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// Page layout in points, US Letter landscape
+const (
+	pageWidth    = 792
+	pageHeight   = 612
+	pageMargin   = 40
+	titleSize    = 16
+	fontSize     = 9
+	rowHeight    = 14
+	avgCharWidth = 0.52 // Helvetica's average glyph width as a fraction of the font size
+)
+
+// writePDF lays the table out over as many pages as it needs with the built-in Helvetica fonts,
+// repeating the header on every page. Text outside Windows-1252 is replaced with "?".
+func writePDF(w io.Writer, table *Table) error {
+	widths := columnWidths(table)
+
+	top := float64(pageHeight - pageMargin)
+	firstRowY := top - titleSize - 2*rowHeight
+	rowsPerPage := int((firstRowY - pageMargin) / rowHeight)
+	if rowsPerPage < 1 {
+		rowsPerPage = 1
+	}
+
+	var pages []string
+	for start := 0; start == 0 || start < len(table.Rows); start += rowsPerPage {
+		end := min(start+rowsPerPage, len(table.Rows))
+
+		var content strings.Builder
+		fmt.Fprintf(&content, "BT /F2 %d Tf %d %.1f Td (%s) Tj ET\n", titleSize, pageMargin, top-titleSize, pdfString(table.Title))
+		fmt.Fprintf(&content, "BT /F1 %d Tf %d %d Td (%s) Tj ET\n", fontSize, pageWidth-pageMargin-60, pageMargin-20,
+			pdfString(fmt.Sprintf("Page %d of %d", len(pages)+1, pageCount(len(table.Rows), rowsPerPage))))
+
+		y := top - titleSize - rowHeight
+		drawRow(&content, "F2", stringCells(table.Header), widths, y)
+		fmt.Fprintf(&content, "%d %.1f m %d %.1f l S\n", pageMargin, y-4, pageWidth-pageMargin, y-4)
+		for _, row := range table.Rows[start:end] {
+			y -= rowHeight
+			drawRow(&content, "F1", row, widths, y)
+		}
+		pages = append(pages, content.String())
+	}
+
+	return writePDFObjects(w, pages)
+}
+
+func pageCount(rows, perPage int) int {
+	if rows == 0 {
+		return 1
+	}
+	return (rows + perPage - 1) / perPage
+}
+
+// columnWidths shares the printable width between columns in proportion to their longest cell
+func columnWidths(table *Table) []float64 {
+	longest := make([]int, len(table.Header))
+	for i, h := range table.Header {
+		longest[i] = utf8.RuneCountInString(h)
+	}
+	for _, row := range table.Rows {
+		for i, cell := range row {
+			if i < len(longest) {
+				longest[i] = max(longest[i], min(utf8.RuneCountInString(text(cell)), 40))
+			}
+		}
+	}
+
+	total := 0
+	for _, l := range longest {
+		total += l + 2
+	}
+	widths := make([]float64, len(longest))
+	for i, l := range longest {
+		widths[i] = float64(pageWidth-2*pageMargin) * float64(l+2) / float64(total)
+	}
+	return widths
+}
+
+func drawRow(b *strings.Builder, font string, cells []interface{}, widths []float64, y float64) {
+	x := float64(pageMargin)
+	for i, cell := range cells {
+		if i >= len(widths) {
+			break
+		}
+		maxChars := int(widths[i] / (fontSize * avgCharWidth))
+		value := []rune(text(cell))
+		if len(value) > maxChars && maxChars > 1 {
+			value = append(value[:maxChars-1], '…')
+		}
+		fmt.Fprintf(b, "BT /%s %d Tf %.1f %.1f Td (%s) Tj ET\n", font, fontSize, x, y, pdfString(string(value)))
+		x += widths[i]
+	}
+}
+
+// pdfString encodes s as Windows-1252 and escapes it for a PDF literal string
+func pdfString(s string) string {
+	encoder := charmap.Windows1252.NewEncoder()
+	var b strings.Builder
+	for _, r := range s {
+		encoded, err := encoder.String(string(r))
+		if err != nil || r < ' ' {
+			encoded = "?"
+		}
+		switch encoded {
+		case "(", ")", `\`:
+			b.WriteString(`\` + encoded)
+		default:
+			b.WriteString(encoded)
+		}
+	}
+	return b.String()
+}
+
+// writePDFObjects writes the document structure around the page content streams
+func writePDFObjects(w io.Writer, pages []string) error {
+	var buf bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// 1 catalog, 2 page tree, 3 and 4 fonts, then a page and a content stream per page
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	for i, content := range pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			pageWidth, pageHeight, 6+2*i))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", len(content), content))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	_, err := buf.WriteTo(w)
+	return err
+}
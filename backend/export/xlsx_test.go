@@ -0,0 +1,127 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+)
+
+// xlsxSheet is the part of a worksheet the tests read back
+type xlsxSheet struct {
+	Rows []struct {
+		R     int `xml:"r,attr"`
+		Cells []struct {
+			Ref    string `xml:"r,attr"`
+			Type   string `xml:"t,attr"`
+			Style  string `xml:"s,attr"`
+			Value  string `xml:"v"`
+			Inline string `xml:"is>t"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+func readXLSX(t *testing.T, data []byte) map[string]string {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts[f.Name] = string(body)
+	}
+	return parts
+}
+
+func TestWriteXLSX(t *testing.T) {
+	table := &Table{
+		Title:  "Fall: Screen rankings [draft]",
+		Header: []string{"Rank", "Name", "Win rate (%)"},
+		Rows: [][]interface{}{
+			{1, `Ada <b>"Countess"</b> & co`, 66.7},
+			{2, "=1+1", "n/a"},
+		},
+	}
+	var out bytes.Buffer
+	if err := Write(&out, XLSX, table); err != nil {
+		t.Fatal(err)
+	}
+	parts := readXLSX(t, out.Bytes())
+
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/styles.xml", "xl/worksheets/sheet1.xml"} {
+		if _, ok := parts[name]; !ok {
+			t.Fatalf("workbook has no %s", name)
+		}
+		if err := xml.Unmarshal([]byte(parts[name]), new(struct{})); err != nil {
+			t.Errorf("%s is not well-formed XML: %v", name, err)
+		}
+	}
+	if !strings.Contains(parts["xl/workbook.xml"], `name="Fall  Screen rankings  draft"`) {
+		t.Errorf("workbook.xml = %s, want the title cleaned up as the sheet name", parts["xl/workbook.xml"])
+	}
+
+	var sheet xlsxSheet
+	if err := xml.Unmarshal([]byte(parts["xl/worksheets/sheet1.xml"]), &sheet); err != nil {
+		t.Fatal(err)
+	}
+	if len(sheet.Rows) != 3 {
+		t.Fatalf("%d rows, want the header and 2", len(sheet.Rows))
+	}
+	for _, c := range sheet.Rows[0].Cells {
+		if c.Style != "1" || c.Type != "inlineStr" {
+			t.Errorf("header cell %s is style %q type %q, want bold inline text", c.Ref, c.Style, c.Type)
+		}
+	}
+
+	row := sheet.Rows[1].Cells
+	if row[0].Ref != "A2" || row[0].Type != "" || row[0].Value != "1" {
+		t.Errorf("rank cell = %+v, want the number 1 in A2", row[0])
+	}
+	if row[1].Type != "inlineStr" || row[1].Inline != `Ada <b>"Countess"</b> & co` {
+		t.Errorf("name cell = %+v, want the text as given", row[1])
+	}
+	if row[2].Ref != "C2" || row[2].Type != "" || row[2].Value != "66.7" {
+		t.Errorf("win rate cell = %+v, want the number 66.7 in C2", row[2])
+	}
+	// XLSX stores text as text, so there's no formula to escape
+	if formula := sheet.Rows[2].Cells[1]; formula.Type != "inlineStr" || formula.Inline != "=1+1" {
+		t.Errorf("formula-like cell = %+v, want inline text", formula)
+	}
+}
+
+func TestColumnName(t *testing.T) {
+	tests := map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 51: "AZ", 52: "BA", 701: "ZZ", 702: "AAA"}
+	for col, want := range tests {
+		if got := columnName(col); got != want {
+			t.Errorf("columnName(%d) = %q, want %q", col, got, want)
+		}
+	}
+}
+
+func TestSheetName(t *testing.T) {
+	tests := []struct {
+		title, want string
+	}{
+		{"Fall recruiting", "Fall recruiting"},
+		{"a/b\\c?d*e:f", "a b c d e f"},
+		{"[]", "Sheet1"},
+		{"", "Sheet1"},
+		{strings.Repeat("é", 40), strings.Repeat("é", 31)},
+	}
+	for _, tt := range tests {
+		if got := sheetName(tt.title); got != tt.want {
+			t.Errorf("sheetName(%q) = %q, want %q", tt.title, got, tt.want)
+		}
+	}
+}
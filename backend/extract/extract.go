@@ -0,0 +1,158 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+
+	"github.com/ledongthuc/pdf"
+)
+
+const (
+	PDFMimeType  = "application/pdf"
+	DOCXMimeType = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+)
+
+// Extracted text is cut off at this many bytes so one applicant can't blow up the search index
+const MaxTextBytes = 256 << 10
+
+var ErrUnsupported = errors.New("unsupported document type")
+
+// Text returns the plain text of a PDF or DOCX document with whitespace collapsed
+func Text(mimeType string, data []byte) (string, error) {
+	var text string
+	var err error
+	switch {
+	case mimeType == PDFMimeType || bytes.HasPrefix(data, []byte("%PDF-")):
+		text, err = pdfText(data)
+	case mimeType == DOCXMimeType || (bytes.HasPrefix(data, []byte("PK\x03\x04")) && strings.HasSuffix(mimeType, "zip")):
+		text, err = docxText(data)
+	default:
+		return "", ErrUnsupported
+	}
+	if err != nil {
+		return "", err
+	}
+
+	text = collapseWhitespace(text)
+	if len(text) > MaxTextBytes {
+		text = strings.ToValidUTF8(text[:MaxTextBytes], "")
+	}
+	return text, nil
+}
+
+func pdfText(data []byte) (text string, err error) {
+	// the pdf package panics on some malformed files rather than returning an error
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("error reading pdf: %v", r)
+		}
+	}()
+
+	reader, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("error reading pdf: %v", err)
+	}
+	plain, err := reader.GetPlainText()
+	if err != nil {
+		return "", fmt.Errorf("error extracting pdf text: %v", err)
+	}
+	out, err := io.ReadAll(io.LimitReader(plain, 4*MaxTextBytes))
+	if err != nil {
+		return "", fmt.Errorf("error extracting pdf text: %v", err)
+	}
+	return string(out), nil
+}
+
+// docxText walks word/document.xml collecting the text runs (w:t), with paragraphs,
+// breaks and tabs turned into whitespace
+func docxText(data []byte) (string, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("error reading docx: %v", err)
+	}
+
+	var document *zip.File
+	for _, file := range archive.File {
+		if file.Name == "word/document.xml" {
+			document = file
+			break
+		}
+	}
+	if document == nil {
+		return "", fmt.Errorf("error reading docx: no word/document.xml")
+	}
+
+	rc, err := document.Open()
+	if err != nil {
+		return "", fmt.Errorf("error reading docx: %v", err)
+	}
+	defer rc.Close()
+
+	var text strings.Builder
+	decoder := xml.NewDecoder(io.LimitReader(rc, 16*MaxTextBytes))
+	inText := false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("error parsing docx: %v", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "t":
+				inText = true
+			case "tab":
+				text.WriteByte('\t')
+			case "br", "cr":
+				text.WriteByte('\n')
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				text.WriteByte('\n')
+			}
+		case xml.CharData:
+			if inText {
+				text.Write(t)
+			}
+		}
+	}
+	return text.String(), nil
+}
+
+// collapseWhitespace keeps line breaks but squeezes runs of other whitespace into one space
+func collapseWhitespace(s string) string {
+	var out strings.Builder
+	space, newline := false, false
+	for _, r := range s {
+		switch {
+		case r == '\n':
+			newline = true
+		case unicode.IsSpace(r):
+			space = true
+		case unicode.IsPrint(r):
+			if out.Len() > 0 {
+				if newline {
+					out.WriteByte('\n')
+				} else if space {
+					out.WriteByte(' ')
+				}
+			}
+			space, newline = false, false
+			out.WriteRune(r)
+		}
+	}
+	return out.String()
+}
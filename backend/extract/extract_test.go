@@ -0,0 +1,126 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func readFixture(t *testing.T, name string) []byte {
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestText(t *testing.T) {
+	tests := []struct {
+		name     string
+		fixture  string
+		mimeType string
+		want     []string
+		wantErr  error
+	}{
+		{"pdf", "resume.pdf", PDFMimeType, []string{"Ada Lovelace", "Analytical Engine programmer"}, nil},
+		{"pdf sniffed from its header", "resume.pdf", "application/octet-stream", []string{"Ada Lovelace"}, nil},
+		{"docx", "resume.docx", DOCXMimeType, []string{"Grace Hopper", "Compilers COBOL\nRear Admiral, US Navy"}, nil},
+		{"docx reported as zip", "resume.docx", "application/zip", []string{"Grace Hopper"}, nil},
+		{"plain text", "resume.txt", "text/plain", nil, ErrUnsupported},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, err := Text(tt.mimeType, readFixture(t, tt.fixture))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Text() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(text, want) {
+					t.Errorf("Text() = %q, want it to contain %q", text, want)
+				}
+			}
+		})
+	}
+}
+
+func TestTextMalformed(t *testing.T) {
+	tests := []struct {
+		name     string
+		mimeType string
+		data     []byte
+	}{
+		{"truncated pdf", PDFMimeType, readFixture(t, "resume.pdf")[:200]},
+		{"pdf header only", PDFMimeType, []byte("%PDF-1.4\n")},
+		{"docx that isn't a zip", DOCXMimeType, []byte("not a zip")},
+		{"docx without a document", DOCXMimeType, zipOf(t, map[string]string{"[Content_Types].xml": "<Types/>"})},
+		{"docx with broken xml", DOCXMimeType, zipOf(t, map[string]string{"word/document.xml": "<w:document><w:p>"})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Text(tt.mimeType, tt.data); err == nil {
+				t.Fatal("Text() succeeded on a malformed document")
+			}
+		})
+	}
+}
+
+func TestTextTruncatesLongDocuments(t *testing.T) {
+	// a multi-byte rune straddles the cut, which must not leave invalid UTF-8 behind
+	words := strings.Repeat("é", MaxTextBytes/2) + "x" + strings.Repeat(" more", 1000)
+	doc := `<w:document xmlns:w="w"><w:body><w:p><w:r><w:t>` + words + `</w:t></w:r></w:p></w:body></w:document>`
+
+	text, err := Text(DOCXMimeType, zipOf(t, map[string]string{"word/document.xml": doc}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(text) > MaxTextBytes {
+		t.Errorf("len(text) = %d, want at most %d", len(text), MaxTextBytes)
+	}
+	if !strings.HasPrefix(text, "éé") || strings.ContainsRune(text, '�') {
+		t.Errorf("truncated text is not valid UTF-8: ...%q", text[len(text)-8:])
+	}
+}
+
+func TestCollapseWhitespace(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"  a   b\t\tc  ", "a b c"},
+		{"line one\n\n\n  line two", "line one\nline two"},
+		{"a \n b", "a\nb"},
+		{"\n\nleading", "leading"},
+		{"ctrl\x00\x07chars", "ctrlchars"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := collapseWhitespace(tt.in); got != tt.want {
+			t.Errorf("collapseWhitespace(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func zipOf(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,84 @@
+package fileurl
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignVerify(t *testing.T) {
+	Init("test-key", 10*time.Minute)
+	now := time.Unix(1700000000, 0)
+
+	signed := func(fileID, projectID, reviewerID string) url.Values {
+		u, err := url.Parse(Sign("/api/files/"+fileID, fileID, projectID, reviewerID, now))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return u.Query()
+	}
+	with := func(q url.Values, key, value string) url.Values {
+		q.Set(key, value)
+		return q
+	}
+	without := func(q url.Values, key string) url.Values {
+		q.Del(key)
+		return q
+	}
+
+	tests := []struct {
+		name   string
+		fileID string
+		query  url.Values
+		now    time.Time
+		want   error
+	}{
+		{"valid", "f1", signed("f1", "p1", "user_a"), now, nil},
+		{"valid until expiry", "f1", signed("f1", "p1", "user_a"), now.Add(10 * time.Minute), nil},
+		{"expired", "f1", signed("f1", "p1", "user_a"), now.Add(10*time.Minute + time.Second), ErrExpired},
+		{"missing signature", "f1", without(signed("f1", "p1", "user_a"), "sig"), now, ErrMissingSignature},
+		{"missing expiry", "f1", without(signed("f1", "p1", "user_a"), "exp"), now, ErrInvalidSignature},
+		{"other file", "f2", signed("f1", "p1", "user_a"), now, ErrInvalidSignature},
+		{"project changed", "f1", with(signed("f1", "p1", "user_a"), "p", "p2"), now, ErrInvalidSignature},
+		{"reviewer changed", "f1", with(signed("f1", "p1", "user_a"), "r", "user_b"), now, ErrInvalidSignature},
+		{"reviewer dropped", "f1", without(signed("f1", "p1", "user_a"), "r"), now, ErrInvalidSignature},
+		{"expiry extended", "f1", with(signed("f1", "p1", "user_a"), "exp", "9999999999"), now, ErrInvalidSignature},
+		{"signature tampered", "f1", with(signed("f1", "p1", "user_a"), "sig", strings.Repeat("0", 64)), now, ErrInvalidSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			grant, err := Verify(tt.fileID, tt.query, tt.now)
+			if err != tt.want {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.want)
+			}
+			if err == nil && (grant.FileID != "f1" || grant.ProjectID != "p1" || grant.ReviewerID != "user_a" || !grant.Expires.Equal(now.Add(10*time.Minute))) {
+				t.Errorf("Verify() grant = %+v", grant)
+			}
+		})
+	}
+}
+
+func TestVerifyOtherKey(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	Init("key-one", time.Minute)
+	u, _ := url.Parse(Sign("/api/files/f1", "f1", "p1", "user_a", now))
+
+	Init("key-two", time.Minute)
+	if _, err := Verify("f1", u.Query(), now); err != ErrInvalidSignature {
+		t.Fatalf("Verify() with a rotated key error = %v, want ErrInvalidSignature", err)
+	}
+}
+
+func TestSignWithoutReviewer(t *testing.T) {
+	Init("test-key", time.Minute)
+	now := time.Unix(1700000000, 0)
+	u, _ := url.Parse(Sign("/api/files/f1", "f1", "p1", "", now))
+	if u.Query().Has("r") {
+		t.Errorf("url %q carries an empty reviewer", u)
+	}
+	grant, err := Verify("f1", u.Query(), now)
+	if err != nil || grant.ReviewerID != "" {
+		t.Fatalf("Verify() = %+v, %v", grant, err)
+	}
+}
@@ -0,0 +1,171 @@
+// Package mail sends the plain-text notification emails: intake receipts, reviewer reminders and
+// stage decisions.
+package mail
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	netmail "net/mail"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+)
+
+// how long one message may take, connection included
+const sendTimeout = 30 * time.Second
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// SMTP delivers messages through an SMTP server, upgrading to TLS when the server offers STARTTLS.
+// Without a username it sends unauthenticated, as local sinks like MailHog or Mailpit expect.
+type SMTP struct {
+	host     string
+	port     string
+	username string
+	password string
+	from     *netmail.Address
+}
+
+func NewSMTP(host, port, username, password, from string) (*SMTP, error) {
+	address, err := netmail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender address %q: %v", from, err)
+	}
+	return &SMTP{host: host, port: port, username: username, password: password, from: address}, nil
+}
+
+func (s *SMTP) Name() string {
+	return "smtp (" + net.JoinHostPort(s.host, s.port) + ")"
+}
+
+func (s *SMTP) Send(msg Message) error {
+	to, err := netmail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("invalid recipient %q: %v", msg.To, err)
+	}
+	data, err := s.compose(to, msg)
+	if err != nil {
+		return err
+	}
+
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(s.host, s.port), sendTimeout)
+	if err != nil {
+		return fmt.Errorf("error connecting to SMTP server: %w", err)
+	}
+	conn.SetDeadline(time.Now().Add(sendTimeout))
+	client, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("error greeting SMTP server: %w", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: s.host}); err != nil {
+			return fmt.Errorf("error starting TLS: %w", err)
+		}
+	}
+	if s.username != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.username, s.password, s.host)); err != nil {
+			return fmt.Errorf("error authenticating: %w", err)
+		}
+	}
+	if err := client.Mail(s.from.Address); err != nil {
+		return fmt.Errorf("error sending MAIL FROM: %w", err)
+	}
+	if err := client.Rcpt(to.Address); err != nil {
+		return fmt.Errorf("recipient %s refused: %w", to.Address, err)
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("error sending DATA: %w", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("error writing message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("message refused: %w", err)
+	}
+	return client.Quit()
+}
+
+// compose renders the message as a quoted-printable UTF-8 text email
+func (s *SMTP) compose(to *netmail.Address, msg Message) ([]byte, error) {
+	id := make([]byte, 12)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	domain := s.from.Address[strings.LastIndex(s.from.Address, "@")+1:]
+	// a template could put a line break in the subject; it must not start new headers
+	subject := strings.Join(strings.Fields(msg.Subject), " ")
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", s.from.String())
+	fmt.Fprintf(&buf, "To: %s\r\n", to.String())
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "Message-ID: <%s@%s>\r\n", hex.EncodeToString(id), domain)
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+	qp := quotedprintable.NewWriter(&buf)
+	body := strings.ReplaceAll(msg.Body, "\r\n", "\n")
+	if _, err := qp.Write([]byte(strings.ReplaceAll(body, "\n", "\r\n"))); err != nil {
+		return nil, err
+	}
+	if err := qp.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+var defaultSMTP *SMTP
+
+// Setup configures delivery from SMTP_HOST, SMTP_PORT (587 by default), SMTP_USERNAME, SMTP_PASSWORD
+// and SMTP_FROM. Without SMTP_HOST no email is sent.
+func Setup() {
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		log.Println("Warning: SMTP_HOST is not set, notification emails are turned off")
+		return
+	}
+	port := os.Getenv("SMTP_PORT")
+	if port == "" {
+		port = "587"
+	}
+	from := os.Getenv("SMTP_FROM")
+	if from == "" {
+		from = "Swiperank <no-reply@" + host + ">"
+	}
+
+	s, err := NewSMTP(host, port, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"), from)
+	if err != nil {
+		log.Println("Warning: notification emails are turned off:", err)
+		return
+	}
+	defaultSMTP = s
+	log.Println("Sending notification emails with", s.Name())
+}
+
+// Default returns the configured server, or nil when email is off
+func Default() *SMTP {
+	return defaultSMTP
+}
+
+// SetDefault replaces the configured server; nil turns email off
+func SetDefault(s *SMTP) {
+	defaultSMTP = s
+}
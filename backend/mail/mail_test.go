@@ -0,0 +1,227 @@
+package mail
+
+import (
+	"bufio"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	netmail "net/mail"
+	"net/textproto"
+	"strings"
+	"testing"
+)
+
+// smtpStub is a one-connection SMTP server recording what it was sent
+type smtpStub struct {
+	listener   net.Listener
+	rejectRcpt bool
+	done       chan struct{}
+
+	// filled in by serve
+	auth     string
+	mailFrom string
+	rcptTo   string
+	data     string
+}
+
+func newSMTPStub(t *testing.T, rejectRcpt bool) *smtpStub {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &smtpStub{listener: listener, rejectRcpt: rejectRcpt, done: make(chan struct{})}
+	go s.serve()
+	t.Cleanup(func() { listener.Close() })
+	return s
+}
+
+func (s *smtpStub) hostPort() (string, string) {
+	host, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	return host, port
+}
+
+func (s *smtpStub) serve() {
+	defer close(s.done)
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	text := textproto.NewConn(conn)
+	text.PrintfLine("220 stub ESMTP")
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			text.PrintfLine("250-stub")
+			text.PrintfLine("250 AUTH PLAIN")
+		case "AUTH":
+			s.auth = strings.TrimPrefix(arg, "PLAIN ")
+			text.PrintfLine("235 ok")
+		case "MAIL":
+			s.mailFrom = arg
+			text.PrintfLine("250 ok")
+		case "RCPT":
+			s.rcptTo = arg
+			if s.rejectRcpt {
+				text.PrintfLine("550 no such user")
+			} else {
+				text.PrintfLine("250 ok")
+			}
+		case "DATA":
+			text.PrintfLine("354 go ahead")
+			data, err := io.ReadAll(text.DotReader())
+			if err != nil {
+				return
+			}
+			s.data = string(data)
+			text.PrintfLine("250 queued")
+		case "QUIT":
+			text.PrintfLine("221 bye")
+			return
+		default:
+			text.PrintfLine("502 not implemented")
+		}
+	}
+}
+
+func TestSMTPSend(t *testing.T) {
+	stub := newSMTPStub(t, false)
+	host, port := stub.hostPort()
+	s, err := NewSMTP(host, port, "mailer", "hunter2", "Swiperank <no-reply@swiperank.test>")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.Send(Message{To: "Ada Lovelace <ada@example.com>", Subject: "Hello", Body: "Line one\nLine two\n"})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	<-stub.done
+
+	if stub.mailFrom != "FROM:<no-reply@swiperank.test>" || stub.rcptTo != "TO:<ada@example.com>" {
+		t.Errorf("envelope = %q, %q", stub.mailFrom, stub.rcptTo)
+	}
+	creds, _ := base64.StdEncoding.DecodeString(stub.auth)
+	if string(creds) != "\x00mailer\x00hunter2" {
+		t.Errorf("AUTH PLAIN credentials = %q", creds)
+	}
+	msg, err := netmail.ReadMessage(strings.NewReader(stub.data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := msg.Header.Get("Subject"); got != "Hello" {
+		t.Errorf("Subject = %q", got)
+	}
+	body, _ := io.ReadAll(msg.Body)
+	if string(body) != "Line one\nLine two\n" {
+		t.Errorf("body = %q", body)
+	}
+}
+
+func TestSMTPSendRecipientRefused(t *testing.T) {
+	stub := newSMTPStub(t, true)
+	host, port := stub.hostPort()
+	s, _ := NewSMTP(host, port, "", "", "no-reply@swiperank.test")
+
+	err := s.Send(Message{To: "ada@example.com", Subject: "Hello", Body: "Hi"})
+	if err == nil || !strings.Contains(err.Error(), "recipient ada@example.com refused") {
+		t.Fatalf("Send() error = %v, want the recipient refused", err)
+	}
+	<-stub.done
+	if stub.auth != "" {
+		t.Error("authenticated without a username")
+	}
+}
+
+func TestSMTPSendInvalidRecipient(t *testing.T) {
+	s, _ := NewSMTP("127.0.0.1", "1", "", "", "no-reply@swiperank.test")
+	if err := s.Send(Message{To: "not an address", Subject: "Hello", Body: "Hi"}); err == nil {
+		t.Fatal("Send() to an invalid address succeeded")
+	}
+}
+
+func TestNewSMTPInvalidSender(t *testing.T) {
+	if _, err := NewSMTP("localhost", "25", "", "", "Swiperank"); err == nil {
+		t.Fatal("NewSMTP() accepted a sender without an address")
+	}
+}
+
+func TestCompose(t *testing.T) {
+	s, err := NewSMTP("localhost", "25", "", "", "Swiperank <no-reply@swiperank.test>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	to := &netmail.Address{Name: "Zoë Müller", Address: "zoe@example.com"}
+	long := strings.Repeat("déjà vu ", 20)
+	data, err := s.compose(to, Message{
+		Subject: "Welcome to Fall recruiting,\r\nBcc: victim@example.com",
+		Body:    "Héllo Zoë,\n\n" + long + "\nx=1\r\n",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := string(data)
+
+	msg, err := netmail.ReadMessage(strings.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Header.Get("Bcc") != "" || strings.Contains(raw, "\r\nBcc:") {
+		t.Fatal("a line break in the subject started a new header")
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if subject != "Welcome to Fall recruiting, Bcc: victim@example.com" {
+		t.Errorf("Subject = %q", subject)
+	}
+	from, err := msg.Header.AddressList("From")
+	if err != nil || from[0].Address != "no-reply@swiperank.test" || from[0].Name != "Swiperank" {
+		t.Errorf("From = %v, %v", from, err)
+	}
+	recipients, err := msg.Header.AddressList("To")
+	if err != nil || recipients[0].Address != "zoe@example.com" || recipients[0].Name != "Zoë Müller" {
+		t.Errorf("To = %v, %v", recipients, err)
+	}
+	if id := msg.Header.Get("Message-Id"); !strings.HasPrefix(id, "<") || !strings.HasSuffix(id, "@swiperank.test>") {
+		t.Errorf("Message-ID = %q", id)
+	}
+	if _, err := msg.Header.Date(); err != nil {
+		t.Errorf("Date header: %v", err)
+	}
+	for header, want := range map[string]string{
+		"Mime-Version":              "1.0",
+		"Content-Type":              "text/plain; charset=UTF-8",
+		"Content-Transfer-Encoding": "quoted-printable",
+	} {
+		if got := msg.Header.Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+
+	encoded, _ := io.ReadAll(msg.Body)
+	scanner := bufio.NewScanner(strings.NewReader(string(encoded)))
+	for scanner.Scan() {
+		if len(scanner.Text()) > 76 {
+			t.Errorf("encoded line longer than 76 characters: %q", scanner.Text())
+		}
+	}
+	if !strings.Contains(string(encoded), "H=C3=A9llo") || !strings.Contains(string(encoded), "x=3D1") {
+		t.Errorf("body not quoted-printable: %q", encoded)
+	}
+	body, err := io.ReadAll(quotedprintable.NewReader(strings.NewReader(string(encoded))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Héllo Zoë,\r\n\r\n" + long + "\r\nx=1\r\n"; string(body) != want {
+		t.Errorf("decoded body = %q, want %q", body, want)
+	}
+}
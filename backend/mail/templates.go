@@ -0,0 +1,161 @@
+package mail
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"text/template"
+
+	"backend/models"
+)
+
+// The emails a project can customise. Each is rendered with the matching data type.
+const (
+	// to an applicant whose application was accepted, ReceiptData
+	TemplateReceipt = "receipt"
+	// to a reviewer behind their vote quota, ReminderData
+	TemplateReminder = "reminder"
+	// to applicants advanced by a stage cut, DecisionData
+	TemplateAdvanced = "advanced"
+	// to applicants left behind by a stage cut, DecisionData
+	TemplateNotAdvanced = "not-advanced"
+)
+
+var Templates = []string{TemplateReceipt, TemplateReminder, TemplateAdvanced, TemplateNotAdvanced}
+
+type ReceiptData struct {
+	FirstName   string
+	LastName    string
+	ProjectName string
+}
+
+type ReminderData struct {
+	ReviewerName string
+	ProjectName  string
+	StageName    string
+	Votes        int
+	Quota        int
+	Remaining    int
+	// where to go and vote, empty when FRONTEND_URL isn't set
+	ReviewURL string
+}
+
+type DecisionData struct {
+	FirstName   string
+	LastName    string
+	ProjectName string
+	// the stage that was cut, and the one advanced applicants move on to
+	StageName     string
+	NextStageName string
+}
+
+// Defaults are sent for templates a project hasn't customised
+var Defaults = map[string]models.EmailTemplate{
+	TemplateReceipt: {
+		Subject: "We received your application to {{.ProjectName}}",
+		Body: `Hi {{.FirstName}},
+
+Thanks for applying to {{.ProjectName}}. Your application has been received and will be reviewed soon.
+We'll be in touch once a decision has been made.
+`,
+	},
+	TemplateReminder: {
+		Subject: "{{.ProjectName}}: {{.Remaining}} more votes to go",
+		Body: `Hi {{if .ReviewerName}}{{.ReviewerName}}{{else}}there{{end}},
+
+You've cast {{.Votes}} of your {{.Quota}} votes in {{.StageName}} for {{.ProjectName}}.
+{{- if .ReviewURL}}
+Pick up where you left off at {{.ReviewURL}}
+{{- end}}
+`,
+	},
+	TemplateAdvanced: {
+		Subject: "{{.ProjectName}}: you're through to {{.NextStageName}}",
+		Body: `Hi {{.FirstName}},
+
+Congratulations, your application to {{.ProjectName}} has made it through {{.StageName}} and on to {{.NextStageName}}.
+We'll be in touch with next steps soon.
+`,
+	},
+	TemplateNotAdvanced: {
+		Subject: "Your application to {{.ProjectName}}",
+		Body: `Hi {{.FirstName}},
+
+Thank you for applying to {{.ProjectName}}. We had a lot of strong applications, and unfortunately
+we won't be moving yours forward this time.
+`,
+	},
+}
+
+// dataFor is the zero value of the data a template is rendered with
+func dataFor(name string) interface{} {
+	switch name {
+	case TemplateReceipt:
+		return ReceiptData{}
+	case TemplateReminder:
+		return ReminderData{}
+	case TemplateAdvanced, TemplateNotAdvanced:
+		return DecisionData{}
+	}
+	return nil
+}
+
+// Variables lists the fields a template can use, as {{.Name}}
+func Variables(name string) []string {
+	data := dataFor(name)
+	if data == nil {
+		return nil
+	}
+	t := reflect.TypeOf(data)
+	names := make([]string, t.NumField())
+	for i := range names {
+		names[i] = t.Field(i).Name
+	}
+	return names
+}
+
+// Lookup returns the project's version of a template, or the default
+func Lookup(project *models.Project, name string) models.EmailTemplate {
+	if t, ok := project.EmailTemplates[name]; ok {
+		return t
+	}
+	return Defaults[name]
+}
+
+// Validate checks that t parses and only uses the variables of the template it replaces
+func Validate(name string, t models.EmailTemplate) error {
+	data := dataFor(name)
+	if data == nil {
+		return fmt.Errorf("unknown template %q", name)
+	}
+	if t.Subject == "" || t.Body == "" {
+		return fmt.Errorf("subject and body are required")
+	}
+	_, err := Render(t, data)
+	return err
+}
+
+// Render fills in t for one recipient; To is left to the caller
+func Render(t models.EmailTemplate, data interface{}) (Message, error) {
+	subject, err := execute("subject", t.Subject, data)
+	if err != nil {
+		return Message{}, err
+	}
+	body, err := execute("body", t.Body, data)
+	if err != nil {
+		return Message{}, err
+	}
+	return Message{Subject: subject, Body: body}, nil
+}
+
+func execute(name, text string, data interface{}) (string, error) {
+	tmpl, err := template.New(name).Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,116 @@
+package mail
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"backend/models"
+)
+
+func TestDefaultsValidate(t *testing.T) {
+	for _, name := range Templates {
+		if err := Validate(name, Defaults[name]); err != nil {
+			t.Errorf("default %s template: %v", name, err)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		subject  string
+		body     string
+		wantErr  string
+	}{
+		{"custom receipt", TemplateReceipt, "Thanks {{.FirstName}}", "{{.ProjectName}} got it", ""},
+		{"reminder fields", TemplateReminder, "{{.Remaining}} left", "{{.Votes}}/{{.Quota}} in {{.StageName}} {{.ReviewURL}}", ""},
+		{"unknown template", "welcome", "Hi", "Hi", "unknown template"},
+		{"empty subject", TemplateReceipt, "", "Hi", "required"},
+		{"empty body", TemplateReceipt, "Hi", "", "required"},
+		{"unclosed action", TemplateReceipt, "Hi {{.FirstName", "Hi", "subject"},
+		{"field of another template", TemplateReceipt, "Hi", "{{.NextStageName}}", "NextStageName"},
+		{"unknown field", TemplateAdvanced, "{{.Email}}", "Hi", "Email"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.template, models.EmailTemplate{Subject: tt.subject, Body: tt.body})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Validate() error = %v, want one mentioning %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	msg, err := Render(Defaults[TemplateReminder], ReminderData{
+		ReviewerName: "Grace",
+		ProjectName:  "Fall recruiting",
+		StageName:    "Round 1",
+		Votes:        12,
+		Quota:        30,
+		Remaining:    18,
+		ReviewURL:    "https://swiperank.test/review",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Subject != "Fall recruiting: 18 more votes to go" {
+		t.Errorf("Subject = %q", msg.Subject)
+	}
+	want := "Hi Grace,\n\nYou've cast 12 of your 30 votes in Round 1 for Fall recruiting.\nPick up where you left off at https://swiperank.test/review\n"
+	if msg.Body != want {
+		t.Errorf("Body = %q, want %q", msg.Body, want)
+	}
+	if msg.To != "" {
+		t.Errorf("To = %q, want it left to the caller", msg.To)
+	}
+
+	// optional parts drop out
+	msg, err = Render(Defaults[TemplateReminder], ReminderData{ProjectName: "P", StageName: "S", Votes: 1, Quota: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Hi there,\n\nYou've cast 1 of your 2 votes in S for P.\n"; msg.Body != want {
+		t.Errorf("Body = %q, want %q", msg.Body, want)
+	}
+
+	// template text goes into plain-text email as is
+	msg, err = Render(models.EmailTemplate{Subject: "{{.FirstName}}", Body: "{{.LastName}}"}, ReceiptData{FirstName: "<b>Ada</b>", LastName: "O'Hara & Co"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Subject != "<b>Ada</b>" || msg.Body != "O'Hara & Co" {
+		t.Errorf("Render() = %+v, want the data unescaped", msg)
+	}
+}
+
+func TestVariables(t *testing.T) {
+	if got, want := Variables(TemplateReceipt), []string{"FirstName", "LastName", "ProjectName"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Variables(receipt) = %v, want %v", got, want)
+	}
+	if got := Variables(TemplateNotAdvanced); len(got) != 5 || got[4] != "NextStageName" {
+		t.Errorf("Variables(not-advanced) = %v", got)
+	}
+	if got := Variables("welcome"); got != nil {
+		t.Errorf("Variables(unknown) = %v, want nil", got)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	custom := models.EmailTemplate{Subject: "Got it", Body: "Thanks"}
+	project := &models.Project{EmailTemplates: map[string]models.EmailTemplate{TemplateReceipt: custom}}
+	if got := Lookup(project, TemplateReceipt); got != custom {
+		t.Errorf("Lookup(receipt) = %+v, want the project's", got)
+	}
+	if got := Lookup(project, TemplateAdvanced); got != Defaults[TemplateAdvanced] {
+		t.Errorf("Lookup(advanced) = %+v, want the default", got)
+	}
+}
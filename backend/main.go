@@ -4,11 +4,17 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"time"
 
+	"backend/controllers"
 	"backend/db"
+	"backend/enrich"
+	"backend/fileurl"
+	"backend/mail"
+	"backend/middleware"
 	"backend/routes"
-
-	// "backend/middleware"
+	"backend/scan"
+	"backend/storage"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/joho/godotenv"
@@ -25,14 +31,46 @@ func main() {
 		log.Fatal("No MONGODB_URI found in .env")
 	}
 
-	// clerkAPIkey := os.Getenv("CLERK_API_KEY")
-	// if clerkAPIkey == "" {
-	// 	log.Fatal("No CLERK_API_KEY found in .env")
-	// }
+	clerkSecretKey := os.Getenv("CLERK_SECRET_KEY")
+	if clerkSecretKey == "" {
+		log.Fatal("No CLERK_SECRET_KEY found in .env")
+	}
 
-	// middleware.InitClerk(clerkAPIkey)
+	middleware.InitClerk(clerkSecretKey)
 
 	db.ConnectMongoDB(mongoURI)
+	db.EnsureIndexes()
+
+	if err := storage.Setup(db.GetDatabase()); err != nil {
+		log.Fatal("Failed to set up file storage: ", err)
+	}
+	scan.Setup()
+	mail.Setup()
+	enrich.Setup()
+
+	fileURLTTL, _ := time.ParseDuration(os.Getenv("FILE_URL_TTL"))
+	fileurl.Init(os.Getenv("FILE_URL_SECRET"), fileURLTTL)
+
+	// RANKING_SNAPSHOT_INTERVAL=0 turns scheduled snapshots off
+	snapshotInterval := time.Hour
+	if v := os.Getenv("RANKING_SNAPSHOT_INTERVAL"); v != "" {
+		snapshotInterval, _ = time.ParseDuration(v)
+	}
+	if snapshotInterval > 0 {
+		go controllers.ScheduleRankingSnapshots(snapshotInterval)
+	}
+	controllers.RunWebhookDispatcher()
+	controllers.RunEnrichment()
+	controllers.RunPreviewWorkers()
+
+	// REVIEWER_REMINDER_INTERVAL=0 turns scheduled reminders off
+	reminderInterval := 24 * time.Hour
+	if v := os.Getenv("REVIEWER_REMINDER_INTERVAL"); v != "" {
+		reminderInterval, _ = time.ParseDuration(v)
+	}
+	if reminderInterval > 0 {
+		go controllers.ScheduleReviewerReminders(reminderInterval)
+	}
 
 	router := chi.NewRouter()
 	routes.SetupRoutes(router)
@@ -4,10 +4,10 @@ import (
 	"context"
 	"log"
 	"net/http"
+	"strings"
 
 	"github.com/clerk/clerk-sdk-go/v2"
 	clerkhttp "github.com/clerk/clerk-sdk-go/v2/http"
-	"github.com/clerk/clerk-sdk-go/v2/user"
 )
 
 // Define custom context key types to prevent collisions
@@ -15,54 +15,58 @@ type contextKey string
 
 const (
 	userIDKey contextKey = "user_id"
-	userKey   contextKey = "user"
 )
 
+// Clerk keeps the session token in this cookie on the frontend's site
+const sessionCookie = "__session"
+
 // Initialize Clerk globally with an API key
 func InitClerk(apiKey string) {
 	clerk.SetKey(apiKey) // Set Clerk API key globally
 	log.Println("✅ Clerk API Key initialized")
 }
 
-// Middleware to handle authentication using Clerk
-func AuthMiddleware(next http.Handler) http.Handler {
-	return clerkhttp.WithHeaderAuthorization()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		log.Println("🟢 AuthMiddleware: Middleware is executing!")
-
-		// Log Authorization header
-		authHeader := r.Header.Get("Authorization")
-		log.Println("🔹 Received Authorization Header:", authHeader)
-
-		ctx := r.Context()
+// sessionToken reads the Clerk session token from the Authorization header, falling back to the
+// __session cookie for EventSource, WebSocket and <img> requests, which can't set headers
+func sessionToken(r *http.Request) string {
+	if authorization := strings.TrimSpace(r.Header.Get("Authorization")); authorization != "" {
+		return strings.TrimPrefix(authorization, "Bearer ")
+	}
+	if cookie, err := r.Cookie(sessionCookie); err == nil {
+		return cookie.Value
+	}
+	return ""
+}
 
-		// Extract Clerk session claims
-		claims, ok := clerk.SessionClaimsFromContext(ctx)
-		if !ok {
-			log.Println("❌ AuthMiddleware: No valid Clerk session found")
-			http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
-			return
+// withSession verifies the session token when there is one and attaches its user id to the context
+func withSession(next http.Handler) http.Handler {
+	return clerkhttp.WithHeaderAuthorization(clerkhttp.AuthorizationJWTExtractor(sessionToken))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if claims, ok := clerk.SessionClaimsFromContext(r.Context()); ok && claims.Subject != "" {
+			r = r.WithContext(WithUserID(r.Context(), claims.Subject))
 		}
+		next.ServeHTTP(w, r)
+	}))
+}
 
-		log.Println("✅ AuthMiddleware: Clerk session found for user:", claims.Subject)
-
-		// Fetch user details from Clerk using their ID (claims.Subject)
-		usr, err := user.Get(ctx, claims.Subject)
-		if err != nil {
-			log.Println("❌ AuthMiddleware: Error fetching user from Clerk:", err)
-			http.Error(w, `{"error": "Failed to retrieve user"}`, http.StatusInternalServerError)
-			return
-		}
-		if usr == nil {
-			log.Println("❌ AuthMiddleware: User does not exist")
-			http.Error(w, `{"error": "User does not exist"}`, http.StatusNotFound)
+// Middleware to handle authentication using Clerk. Requests without a valid session token are
+// refused with 401.
+func AuthMiddleware(next http.Handler) http.Handler {
+	return withSession(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if UserID(r.Context()) == "" {
+			http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
 			return
 		}
+		next.ServeHTTP(w, r)
+	}))
+}
 
-		log.Println("✅ AuthMiddleware: Authenticated User ID:", claims.Subject)
+// WithUserID returns ctx carrying userID as the authenticated user, as AuthMiddleware does
+func WithUserID(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, userIDKey, userID)
+}
 
-		// Attach the user ID and user object to the request context
-		ctx = context.WithValue(ctx, userIDKey, claims.Subject)
-		ctx = context.WithValue(ctx, userKey, usr)
-		next.ServeHTTP(w, r.WithContext(ctx))
-	}))
+// UserID returns the Clerk user id AuthMiddleware attached to the context, or "" when the request was not authenticated
+func UserID(ctx context.Context) string {
+	id, _ := ctx.Value(userIDKey).(string)
+	return id
 }
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSessionToken(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		cookie string
+		want   string
+	}{
+		{"none", "", "", ""},
+		{"bearer header", "Bearer abc", "", "abc"},
+		{"header wins over cookie", "Bearer abc", "def", "abc"},
+		{"session cookie", "", "def", "def"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+			if tt.cookie != "" {
+				r.AddCookie(&http.Cookie{Name: sessionCookie, Value: tt.cookie})
+			}
+			if got := sessionToken(r); got != tt.want {
+				t.Errorf("sessionToken() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	reached := false
+	handler := AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		name   string
+		header string
+		cookie string
+	}{
+		{"no token", "", ""},
+		{"malformed bearer token", "Bearer not-a-jwt", ""},
+		{"malformed session cookie", "", "not-a-jwt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reached = false
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+			if tt.cookie != "" {
+				r.AddCookie(&http.Cookie{Name: sessionCookie, Value: tt.cookie})
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if w.Code != http.StatusUnauthorized {
+				t.Fatalf("status = %d, want 401", w.Code)
+			}
+			if reached {
+				t.Error("unauthenticated request reached the handler")
+			}
+		})
+	}
+}
+
+func TestWithUserID(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	if got := UserID(r.Context()); got != "" {
+		t.Fatalf("UserID of a bare context = %q", got)
+	}
+	if got := UserID(WithUserID(r.Context(), "user_1")); got != "user_1" {
+		t.Errorf("UserID() = %q, want user_1", got)
+	}
+}
@@ -12,15 +12,69 @@ type Applicant struct {
 	LastName      string              `json:"lastName" bson:"lastName"`
 	Major         string              `json:"major" bson:"major"`
 	Year          string              `json:"year" bson:"year"`
+	Email         string              `json:"email,omitempty" bson:"email,omitempty"`
+	Phone         string              `json:"phone,omitempty" bson:"phone,omitempty"`
 	Timestamp     string              `json:"timestamp" bson:"timestamp"`
 	ProjectID     primitive.ObjectID   `json:"project_id" bson:"project_id"`
 	Wins          int                 `json:"wins" bson:"wins"`
 	Losses        int                 `json:"losses" bson:"losses"`
 	Elo           int                 `json:"elo" bson:"elo"`
 	MatchesPlayed []primitive.ObjectID `json:"matches_played" bson:"matches_played"`
+	// index of the project stage the applicant has reached, see Project.Stages
+	Stage         int                 `json:"stage" bson:"stage"`
+	// ratings from the earlier stages the applicant went through, archived at each cut
+	StageResults  []StageResult       `json:"stageResults,omitempty" bson:"stageResults,omitempty"`
+	// one of the ApplicantStatus values; empty on applicants from before statuses and treated as active
+	Status        string              `json:"status,omitempty" bson:"status,omitempty"`
 	Resume        *FileInfo           `json:"resume,omitempty" bson:"resume,omitempty"`
 	CoverLetter   *FileInfo           `json:"coverLetter,omitempty" bson:"coverLetter,omitempty"`
 	Image         *FileInfo           `json:"image,omitempty" bson:"image,omitempty"`
+	// plain text extracted from the resume and cover letter at intake, indexed for search
+	ResumeText      string `json:"resumeText,omitempty" bson:"resumeText,omitempty"`
+	CoverLetterText string `json:"coverLetterText,omitempty" bson:"coverLetterText,omitempty"`
+	// what the enrichment pipeline made of the application, keyed by enricher name
+	Enrichments map[string]Enrichment `json:"enrichments,omitempty" bson:"enrichments,omitempty"`
+	// when the enrichment workers should next pick the application up; unset once it is enriched
+	EnrichmentDueAt *time.Time `json:"-" bson:"enrichmentDueAt,omitempty"`
+	// when the preview workers should next generate thumbnails and previews; unset once they have
+	PreviewsDueAt *time.Time `json:"-" bson:"previewsDueAt,omitempty"`
+	// set on responses when identities were hidden for a blind-review project
+	Anonymised bool `json:"anonymised,omitempty" bson:"-"`
+	Source        string              `json:"source,omitempty" bson:"source,omitempty"`
+	Responses     []Response          `json:"responses,omitempty" bson:"responses,omitempty"`
+	// applicants with a similar name, flagged at intake for a reviewer to merge
+	PossibleDuplicates []primitive.ObjectID `json:"possibleDuplicates,omitempty" bson:"possibleDuplicates,omitempty"`
+}
+
+// StageResult is an applicant's final standing in a stage that has been cut
+type StageResult struct {
+	Stage    int  `json:"stage" bson:"stage"`
+	Elo      int  `json:"elo" bson:"elo"`
+	Wins     int  `json:"wins" bson:"wins"`
+	Losses   int  `json:"losses" bson:"losses"`
+	Rank     int  `json:"rank,omitempty" bson:"rank,omitempty"`
+	Advanced bool `json:"advanced" bson:"advanced"`
+}
+
+// Where an applicant stands in the project. Only active applicants are paired for comparison.
+const (
+	ApplicantStatusActive       = "active"
+	ApplicantStatusWithdrawn    = "withdrawn"
+	ApplicantStatusDisqualified = "disqualified"
+	// moved on to the next round
+	ApplicantStatusAdvanced = "advanced"
+)
+
+func ValidApplicantStatus(status string) bool {
+	switch status {
+	case ApplicantStatusActive, ApplicantStatusWithdrawn, ApplicantStatusDisqualified, ApplicantStatusAdvanced:
+		return true
+	}
+	return false
+}
+
+func (a *Applicant) IsActive() bool {
+	return a.Status == "" || a.Status == ApplicantStatusActive
 }
 
 type FileInfo struct {
@@ -30,7 +84,16 @@ type FileInfo struct {
 	DriveFileID string    `json:"driveFileId" bson:"driveFileId"`
 	UniqueName  string    `json:"uniqueName" bson:"uniqueName"`
 	UploadedAt  time.Time `json:"uploadedAt" bson:"uploadedAt"`
-	Data        string    `json:"data,omitempty" bson:"-"`
+	// backend holding FileID (see storage package), empty for files stored before backends were recorded
+	Storage     string    `json:"storage,omitempty" bson:"storage,omitempty"`
+	URL         string    `json:"url,omitempty" bson:"-"`
+	// malware scan verdict (see scan package); infected files are never parsed or served
+	ScanStatus    string     `json:"scanStatus,omitempty" bson:"scanStatus,omitempty"`
+	ScanSignature string     `json:"scanSignature,omitempty" bson:"scanSignature,omitempty"`
+	ScannedAt     *time.Time `json:"scannedAt,omitempty" bson:"scannedAt,omitempty"`
+	// derived copies generated at intake: a small JPEG of a headshot, a PNG of a PDF's first page
+	Thumbnail *FileInfo `json:"thumbnail,omitempty" bson:"thumbnail,omitempty"`
+	Preview   *FileInfo `json:"preview,omitempty" bson:"preview,omitempty"`
 }
 
 type FormResponses struct {
@@ -40,6 +103,6 @@ type FormResponses struct {
 }
 
 type Response struct {
-	Question string      `json:"question"`
-	Answer   interface{} `json:"answer"`
+	Question string      `json:"question" bson:"question"`
+	Answer   interface{} `json:"answer" bson:"answer"`
 }
\ No newline at end of file
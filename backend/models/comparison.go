@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Comparison records one reviewer vote and the ratings it moved, so ratings can be replayed
+type Comparison struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	ProjectID  primitive.ObjectID `json:"projectId" bson:"project_id"`
+	WinnerID   primitive.ObjectID `json:"winnerId" bson:"winnerId"`
+	LoserID    primitive.ObjectID `json:"loserId" bson:"loserId"`
+	ReviewerID string             `json:"reviewerId,omitempty" bson:"reviewerId,omitempty"`
+	// project stage the vote was cast in
+	Stage int `json:"stage" bson:"stage"`
+	// ratings either side of the vote
+	WinnerEloBefore int       `json:"winnerEloBefore" bson:"winnerEloBefore"`
+	WinnerEloAfter  int       `json:"winnerEloAfter" bson:"winnerEloAfter"`
+	LoserEloBefore  int       `json:"loserEloBefore" bson:"loserEloBefore"`
+	LoserEloAfter   int       `json:"loserEloAfter" bson:"loserEloAfter"`
+	CreatedAt       time.Time `json:"createdAt" bson:"createdAt"`
+	// voided comparisons were taken out of the ratings by a replay
+	Voided bool `json:"voided,omitempty" bson:"voided,omitempty"`
+	// the applicant on one side was deleted; replays skip the game but still count it as logged
+	ApplicantDeleted bool `json:"applicantDeleted,omitempty" bson:"applicantDeleted,omitempty"`
+}
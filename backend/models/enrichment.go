@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// Enrichment is what one enricher made of an applicant after intake
+type Enrichment struct {
+	Status  string `json:"status" bson:"status"`
+	Summary string `json:"summary,omitempty" bson:"summary,omitempty"`
+	// the model or service that wrote the summary
+	Model     string    `json:"model,omitempty" bson:"model,omitempty"`
+	Error     string    `json:"error,omitempty" bson:"error,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt" bson:"updatedAt"`
+}
+
+const (
+	EnrichmentPending = "pending"
+	EnrichmentDone    = "done"
+	EnrichmentFailed  = "failed"
+)
@@ -0,0 +1,83 @@
+package models
+
+// Native webhook payloads accepted by the form adapters in addition to FormResponses
+
+// TypeformWebhook is the body Typeform posts for a "form_response" event
+type TypeformWebhook struct {
+	EventID      string           `json:"event_id"`
+	EventType    string           `json:"event_type"`
+	FormResponse TypeformResponse `json:"form_response"`
+}
+
+type TypeformResponse struct {
+	FormID      string             `json:"form_id"`
+	Token       string             `json:"token"`
+	SubmittedAt string             `json:"submitted_at"`
+	Hidden      map[string]string  `json:"hidden"`
+	Definition  TypeformDefinition `json:"definition"`
+	Answers     []TypeformAnswer   `json:"answers"`
+}
+
+type TypeformDefinition struct {
+	ID     string          `json:"id"`
+	Title  string          `json:"title"`
+	Fields []TypeformField `json:"fields"`
+}
+
+type TypeformField struct {
+	ID    string `json:"id"`
+	Ref   string `json:"ref"`
+	Type  string `json:"type"`
+	Title string `json:"title"`
+}
+
+type TypeformAnswer struct {
+	Type        string           `json:"type"`
+	Field       TypeformField    `json:"field"`
+	Text        string           `json:"text,omitempty"`
+	Email       string           `json:"email,omitempty"`
+	PhoneNumber string           `json:"phone_number,omitempty"`
+	URL         string           `json:"url,omitempty"`
+	FileURL     string           `json:"file_url,omitempty"`
+	Date        string           `json:"date,omitempty"`
+	Number      *float64         `json:"number,omitempty"`
+	Boolean     *bool            `json:"boolean,omitempty"`
+	Choice      *TypeformChoice  `json:"choice,omitempty"`
+	Choices     *TypeformChoices `json:"choices,omitempty"`
+}
+
+type TypeformChoice struct {
+	Label string `json:"label"`
+	Other string `json:"other,omitempty"`
+}
+
+type TypeformChoices struct {
+	Labels []string `json:"labels"`
+	Other  string   `json:"other,omitempty"`
+}
+
+// GoogleFormsSubmission is what the Apps Script onFormSubmit trigger posts, one entry per
+// FormResponse.getItemResponses() item
+type GoogleFormsSubmission struct {
+	FormID          string                   `json:"formId"`
+	ResponseID      string                   `json:"responseId"`
+	Timestamp       string                   `json:"timestamp"`
+	RespondentEmail string                   `json:"respondentEmail"`
+	ItemResponses   []GoogleFormItemResponse `json:"itemResponses"`
+}
+
+type GoogleFormItemResponse struct {
+	Title    string `json:"title"`
+	ItemType string `json:"itemType"`
+	// string for text items, []string for checkbox and FILE_UPLOAD (Drive file ids)
+	Response interface{}      `json:"response"`
+	Files    []GoogleFormFile `json:"files,omitempty"`
+}
+
+// GoogleFormFile is a Drive upload attached by the script via DriveApp.getFileById(id).getBlob()
+type GoogleFormFile struct {
+	DriveFileID string `json:"driveFileId"`
+	FileName    string `json:"fileName"`
+	MimeType    string `json:"mimeType"`
+	Data        string `json:"data"`
+}
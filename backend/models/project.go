@@ -1,6 +1,10 @@
 package models
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
 
 type Project struct {
 	ID                   primitive.ObjectID `bson:"_id,omitempty" json:"id"`
@@ -8,4 +12,85 @@ type Project struct {
 	TotalApplicants      int                `bson:"totalApplicants" json:"totalApplicants"`
 	CompletedComparisons int                `bson:"completedComparisons" json:"completedComparisons"`
 	TotalComparisons     int                `bson:"totalComparisons" json:"totalComparisons"`
+	WebhookSecret        string             `bson:"webhookSecret,omitempty" json:"-"`
+	DuplicatePolicy      string             `bson:"duplicatePolicy,omitempty" json:"duplicatePolicy,omitempty"`
+	// Typeform / Google Forms ids whose submissions are routed to this project
+	ExternalFormIDs []string `bson:"externalFormIds,omitempty" json:"externalFormIds,omitempty"`
+	// in blind review, reviewers see pseudonyms instead of names, photos and contact details
+	BlindReview bool `bson:"blindReview,omitempty" json:"blindReview"`
+	// Clerk user ids of the people who run the project; they always see identities
+	OwnerIDs []string `bson:"ownerIds,omitempty" json:"ownerIds,omitempty"`
+	// recruitment rounds in order; a project without stages has a single implicit one
+	Stages []Stage `bson:"stages,omitempty" json:"stages,omitempty"`
+	// index into Stages of the round being reviewed
+	CurrentStage int `bson:"currentStage" json:"currentStage"`
+	// closed projects take no more votes until reopened or cut to the next stage
+	Closed   bool       `bson:"closed,omitempty" json:"closed"`
+	ClosedAt *time.Time `bson:"closedAt,omitempty" json:"closedAt,omitempty"`
+	// close the project once its ranking has settled, nil to leave it open
+	AutoClose *AutoClose `bson:"autoClose,omitempty" json:"autoClose,omitempty"`
+	// the people voting on the project, reminded by email when behind VoteQuota
+	Reviewers []Reviewer `bson:"reviewers,omitempty" json:"reviewers,omitempty"`
+	// votes each reviewer is expected to cast per stage, 0 for none
+	VoteQuota int `bson:"voteQuota,omitempty" json:"voteQuota,omitempty"`
+	// email applicants a receipt when the form webhook accepts their application
+	IntakeReceipts bool `bson:"intakeReceipts,omitempty" json:"intakeReceipts"`
+	// the project's own versions of the notification emails, keyed by template name
+	EmailTemplates map[string]EmailTemplate `bson:"emailTemplates,omitempty" json:"emailTemplates,omitempty"`
+}
+
+// AutoClose closes a project when its top TopK applicants have not changed for Window votes
+type AutoClose struct {
+	TopK   int `bson:"topK" json:"topK"`
+	Window int `bson:"window" json:"window"`
+}
+
+type Reviewer struct {
+	// Clerk user id, matched against the reviewer of each vote
+	UserID string `bson:"userId" json:"userId"`
+	Name   string `bson:"name,omitempty" json:"name,omitempty"`
+	Email  string `bson:"email" json:"email"`
+}
+
+// EmailTemplate is a text/template subject and plain-text body
+type EmailTemplate struct {
+	Subject string `bson:"subject" json:"subject"`
+	Body    string `bson:"body" json:"body"`
+}
+
+type Stage struct {
+	Name string `bson:"name" json:"name"`
+	// when the cut into this stage was made, zero for the first stage
+	StartedAt *time.Time `bson:"startedAt,omitempty" json:"startedAt,omitempty"`
+}
+
+// IsOwner reports whether userID is one of the project's owners. Unauthenticated callers never are.
+func (p *Project) IsOwner(userID string) bool {
+	if userID == "" {
+		return false
+	}
+	for _, id := range p.OwnerIDs {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// How intake handles a resubmission from an applicant already in the project
+const (
+	// reject the resubmission with 409 Conflict
+	DuplicatePolicyReject = "reject"
+	// overwrite the existing application with the resubmission
+	DuplicatePolicyReplace = "replace"
+	// keep whichever submission has the later timestamp (default)
+	DuplicatePolicyKeepLatest = "keep_latest"
+)
+
+func ValidDuplicatePolicy(policy string) bool {
+	switch policy {
+	case "", DuplicatePolicyReject, DuplicatePolicyReplace, DuplicatePolicyKeepLatest:
+		return true
+	}
+	return false
 }
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// QuarantinedSubmission is a form webhook that could not be routed to a project. When its signature
+// verified against some project's secret the raw payload is kept so it can be ingested once someone
+// assigns it by hand; otherwise only the metadata is recorded.
+type QuarantinedSubmission struct {
+	ID               primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Source           string             `json:"source" bson:"source"`
+	FormID           string             `json:"formId" bson:"formId"`
+	Reason           string             `json:"reason" bson:"reason"`
+	RemoteAddr       string             `json:"remoteAddr" bson:"remoteAddr"`
+	ReceivedAt       time.Time          `json:"receivedAt" bson:"receivedAt"`
+	Payload          string             `json:"payload,omitempty" bson:"payload"`
+	PayloadTruncated bool               `json:"payloadTruncated,omitempty" bson:"payloadTruncated,omitempty"`
+	Verified         bool               `json:"verified" bson:"verified"`
+	// ProjectID is the project whose secret the signature verified against; only its owners see the entry
+	ProjectID primitive.ObjectID `json:"projectId,omitempty" bson:"project_id,omitempty"`
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RankingSnapshot freezes the ranking of a project stage so it can be looked at again later
+type RankingSnapshot struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	ProjectID primitive.ObjectID `json:"projectId" bson:"project_id"`
+	Stage     int                `json:"stage" bson:"stage"`
+	TakenAt   time.Time          `json:"takenAt" bson:"takenAt"`
+	// SnapshotScheduled or SnapshotManual
+	Reason string `json:"reason" bson:"reason"`
+	Label  string `json:"label,omitempty" bson:"label,omitempty"`
+	// votes in the stage's comparison log when the snapshot was taken
+	Comparisons int             `json:"comparisons" bson:"comparisons"`
+	Entries     []SnapshotEntry `json:"entries,omitempty" bson:"entries"`
+}
+
+// SnapshotEntry is an applicant as they stood in a snapshot; Rank is 0 for those no longer in consideration
+type SnapshotEntry struct {
+	ApplicantID primitive.ObjectID `json:"applicantId" bson:"applicantId"`
+	FirstName   string             `json:"firstName" bson:"firstName"`
+	LastName    string             `json:"lastName" bson:"lastName"`
+	Major       string             `json:"major" bson:"major"`
+	Year        string             `json:"year" bson:"year"`
+	Status      string             `json:"status,omitempty" bson:"status,omitempty"`
+	Elo         int                `json:"elo" bson:"elo"`
+	Wins        int                `json:"wins" bson:"wins"`
+	Losses      int                `json:"losses" bson:"losses"`
+	Rank        int                `json:"rank,omitempty" bson:"rank,omitempty"`
+}
+
+const (
+	SnapshotScheduled = "scheduled"
+	SnapshotManual    = "manual"
+)
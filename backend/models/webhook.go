@@ -0,0 +1,76 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Webhook is an outgoing webhook: project events of the subscribed types are POSTed to URL
+type Webhook struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	ProjectID primitive.ObjectID `json:"projectId" bson:"project_id"`
+	URL       string             `json:"url" bson:"url"`
+	// event types from the events package, or "*" for all of them
+	Events []string `json:"events" bson:"events"`
+	// WebhookFormatJSON (default), WebhookFormatSlack or WebhookFormatDiscord
+	Format      string    `json:"format,omitempty" bson:"format,omitempty"`
+	Description string    `json:"description,omitempty" bson:"description,omitempty"`
+	Active      bool      `json:"active" bson:"active"`
+	Secret      string    `json:"-" bson:"secret"`
+	CreatedAt   time.Time `json:"createdAt" bson:"createdAt"`
+}
+
+// Payload formats of outgoing webhooks
+const (
+	// the event as JSON, signed
+	WebhookFormatJSON = "json"
+	// a one-line summary for Slack and Discord incoming webhooks
+	WebhookFormatSlack   = "slack"
+	WebhookFormatDiscord = "discord"
+)
+
+func ValidWebhookFormat(format string) bool {
+	switch format {
+	case "", WebhookFormatJSON, WebhookFormatSlack, WebhookFormatDiscord:
+		return true
+	}
+	return false
+}
+
+// Subscribes reports whether the webhook wants events of type kind
+func (w *Webhook) Subscribes(kind string) bool {
+	for _, e := range w.Events {
+		if e == "*" || e == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery is one event queued for, or delivered to, a webhook. The deliveries collection
+// is both the queue the dispatcher works through and the delivery log.
+type WebhookDelivery struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	WebhookID primitive.ObjectID `json:"webhookId" bson:"webhookId"`
+	ProjectID primitive.ObjectID `json:"projectId" bson:"project_id"`
+	EventType string             `json:"eventType" bson:"eventType"`
+	// the exact body sent on every attempt
+	Payload string `json:"payload" bson:"payload"`
+	// one of the WebhookDelivery statuses
+	Status   string `json:"status" bson:"status"`
+	Attempts int    `json:"attempts" bson:"attempts"`
+	// when a pending delivery is next tried
+	NextAttemptAt  time.Time  `json:"nextAttemptAt" bson:"nextAttemptAt"`
+	ResponseStatus int        `json:"responseStatus,omitempty" bson:"responseStatus,omitempty"`
+	LastError      string     `json:"lastError,omitempty" bson:"lastError,omitempty"`
+	CreatedAt      time.Time  `json:"createdAt" bson:"createdAt"`
+	DeliveredAt    *time.Time `json:"deliveredAt,omitempty" bson:"deliveredAt,omitempty"`
+}
+
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	// gave up after the last retry, or the webhook was removed
+	WebhookDeliveryFailed = "failed"
+)
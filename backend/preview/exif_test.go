@@ -0,0 +1,270 @@
+package preview
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// exifSegment builds an APP1 segment whose first IFD holds an orientation tag
+func exifSegment(order binary.ByteOrder, orientation uint16) []byte {
+	tiff := make([]byte, 8+2+12+4)
+	if order == binary.LittleEndian {
+		copy(tiff, "II")
+	} else {
+		copy(tiff, "MM")
+	}
+	order.PutUint16(tiff[2:], 42)
+	order.PutUint32(tiff[4:], 8)
+	order.PutUint16(tiff[8:], 1)
+	entry := tiff[10:]
+	order.PutUint16(entry[0:], 0x0112)
+	order.PutUint16(entry[2:], 3) // SHORT
+	order.PutUint32(entry[4:], 1)
+	order.PutUint16(entry[8:], orientation)
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+// withSegments inserts segments straight after a JPEG's SOI marker
+func withSegments(jpg []byte, segments ...[]byte) []byte {
+	out := append([]byte{}, jpg[:2]...)
+	for _, segment := range segments {
+		out = append(out, segment...)
+	}
+	return append(out, jpg[2:]...)
+}
+
+func encodeJPEG(t *testing.T, img image.Image) []byte {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestJPEGOrientation(t *testing.T) {
+	plain := encodeJPEG(t, image.NewGray(image.Rect(0, 0, 4, 4)))
+	comment := []byte{0xFF, 0xFE, 0x00, 0x07, 'h', 'e', 'l', 'l', 'o'}
+	truncated := exifSegment(binary.BigEndian, 6)
+	binary.BigEndian.PutUint16(truncated[2:], 400)
+	badOrder := exifSegment(binary.BigEndian, 6)
+	copy(badOrder[10:], "XX")
+
+	tests := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{"no exif", plain, 1},
+		{"not a jpeg", []byte("\x89PNG\r\n\x1a\n"), 1},
+		{"empty", nil, 1},
+		{"big endian 6", withSegments(plain, exifSegment(binary.BigEndian, 6)), 6},
+		{"little endian 8", withSegments(plain, exifSegment(binary.LittleEndian, 8)), 8},
+		{"after another segment", withSegments(plain, comment, exifSegment(binary.LittleEndian, 3)), 3},
+		{"out of range", withSegments(plain, exifSegment(binary.BigEndian, 9)), 1},
+		{"zero", withSegments(plain, exifSegment(binary.BigEndian, 0)), 1},
+		{"segment longer than file", withSegments(plain[:2], truncated), 1},
+		{"unknown byte order", withSegments(plain, badOrder), 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := jpegOrientation(tt.data); got != tt.want {
+				t.Errorf("jpegOrientation() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+	for orientation := 1; orientation <= 8; orientation++ {
+		data := withSegments(plain, exifSegment(binary.LittleEndian, uint16(orientation)))
+		if got := jpegOrientation(data); got != orientation {
+			t.Errorf("jpegOrientation() = %d, want %d", got, orientation)
+		}
+	}
+}
+
+// pixel gives every position of the upright test image its own colour
+func pixel(x, y int) color.RGBA {
+	return color.RGBA{R: uint8(x * 40), G: uint8(y * 40), B: 255, A: 255}
+}
+
+func TestOrient(t *testing.T) {
+	const w, h = 3, 2
+	upright := func(x, y int) color.RGBA { return pixel(x, y) }
+
+	// how a camera stores the upright image for each orientation, from the EXIF definitions
+	tests := []struct {
+		orientation int
+		stored      func(x, y int) color.RGBA
+		swap        bool
+	}{
+		{1, func(x, y int) color.RGBA { return upright(x, y) }, false},
+		{2, func(x, y int) color.RGBA { return upright(w-1-x, y) }, false},
+		{3, func(x, y int) color.RGBA { return upright(w-1-x, h-1-y) }, false},
+		{4, func(x, y int) color.RGBA { return upright(x, h-1-y) }, false},
+		{5, func(x, y int) color.RGBA { return upright(y, x) }, true},
+		{6, func(x, y int) color.RGBA { return upright(w-1-y, x) }, true},
+		{7, func(x, y int) color.RGBA { return upright(w-1-y, h-1-x) }, true},
+		{8, func(x, y int) color.RGBA { return upright(y, h-1-x) }, true},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.orientation), func(t *testing.T) {
+			sw, sh := w, h
+			if tt.swap {
+				sw, sh = h, w
+			}
+			// offset bounds to check orient doesn't assume a zero origin
+			stored := image.NewRGBA(image.Rect(5, 5, 5+sw, 5+sh))
+			for y := 0; y < sh; y++ {
+				for x := 0; x < sw; x++ {
+					stored.Set(5+x, 5+y, tt.stored(x, y))
+				}
+			}
+
+			got := orient(stored, tt.orientation)
+			if b := got.Bounds(); b.Dx() != w || b.Dy() != h {
+				t.Fatalf("oriented image is %dx%d, want %dx%d", b.Dx(), b.Dy(), w, h)
+			}
+			for y := 0; y < h; y++ {
+				for x := 0; x < w; x++ {
+					b := got.Bounds()
+					if c := color.RGBAModel.Convert(got.At(b.Min.X+x, b.Min.Y+y)); c != upright(x, y) {
+						t.Errorf("pixel (%d,%d) = %v, want %v", x, y, c, upright(x, y))
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestThumbnailAppliesOrientationAndDropsExif(t *testing.T) {
+	// stored landscape, displayed portrait
+	src := encodeJPEG(t, image.NewRGBA(image.Rect(0, 0, 40, 20)))
+	data := withSegments(src, exifSegment(binary.BigEndian, 6))
+
+	thumb, mimeType, err := Thumbnail(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mimeType != ThumbnailMimeType {
+		t.Errorf("thumbnail type = %q, want JPEG", mimeType)
+	}
+	config, err := jpeg.DecodeConfig(bytes.NewReader(thumb))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Width != 20 || config.Height != 40 {
+		t.Errorf("thumbnail is %dx%d, want 20x40", config.Width, config.Height)
+	}
+	if bytes.Contains(thumb, []byte("Exif\x00\x00")) {
+		t.Error("thumbnail still carries EXIF")
+	}
+}
+
+func TestThumbnailScalesBeforeOrienting(t *testing.T) {
+	// a large sideways photo: the limit applies to the upright image, and the top-left of the
+	// stored image ends up top-right once rotated
+	stored := image.NewRGBA(image.Rect(0, 0, 2048, 1024))
+	for y := 0; y < 1024; y++ {
+		for x := 0; x < 2048; x++ {
+			stored.Set(x, y, color.White)
+		}
+	}
+	for y := 0; y < 256; y++ {
+		for x := 0; x < 256; x++ {
+			stored.Set(x, y, color.Black)
+		}
+	}
+	data := withSegments(encodeJPEG(t, stored), exifSegment(binary.LittleEndian, 6))
+
+	thumb, _, err := Thumbnail(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := jpeg.Decode(bytes.NewReader(thumb))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != 256 || b.Dy() != 512 {
+		t.Fatalf("thumbnail is %dx%d, want 256x512", b.Dx(), b.Dy())
+	}
+	if r, _, _, _ := img.At(240, 10).RGBA(); r > 0x4000 {
+		t.Errorf("top-right pixel is light, want the dark corner rotated there")
+	}
+	if r, _, _, _ := img.At(10, 10).RGBA(); r < 0xC000 {
+		t.Errorf("top-left pixel is dark, want it white")
+	}
+}
+
+func TestThumbnailKeepsTransparency(t *testing.T) {
+	encodePNG := func(img image.Image) []byte {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+
+	translucent := image.NewNRGBA(image.Rect(0, 0, 1024, 1024))
+	for y := 0; y < 1024; y++ {
+		for x := 512; x < 1024; x++ {
+			translucent.Set(x, y, color.NRGBA{R: 200, A: 255})
+		}
+	}
+	opaque := image.NewNRGBA(image.Rect(0, 0, 64, 64))
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			opaque.Set(x, y, color.NRGBA{G: 200, A: 255})
+		}
+	}
+
+	thumb, mimeType, err := Thumbnail(encodePNG(translucent))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mimeType != ThumbnailAlphaMimeType {
+		t.Fatalf("thumbnail type = %q, want PNG for a transparent source", mimeType)
+	}
+	img, err := png.Decode(bytes.NewReader(thumb))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != ThumbnailSize || b.Dy() != ThumbnailSize {
+		t.Errorf("thumbnail is %dx%d, want %dx%d", b.Dx(), b.Dy(), ThumbnailSize, ThumbnailSize)
+	}
+	if _, _, _, a := img.At(10, 10).RGBA(); a != 0 {
+		t.Errorf("transparent area has alpha %d, want 0", a)
+	}
+	if _, _, _, a := img.At(500, 10).RGBA(); a != 0xFFFF {
+		t.Errorf("opaque area has alpha %d, want opaque", a)
+	}
+
+	if _, mimeType, err := Thumbnail(encodePNG(opaque)); err != nil || mimeType != ThumbnailMimeType {
+		t.Errorf("opaque PNG thumbnail type = %q, %v, want JPEG", mimeType, err)
+	}
+}
+
+func TestFit(t *testing.T) {
+	tests := []struct {
+		w, h, size   int
+		wantW, wantH int
+	}{
+		{100, 50, 512, 100, 50},
+		{1024, 512, 512, 512, 256},
+		{512, 1024, 512, 256, 512},
+		{2000, 2000, 512, 512, 512},
+		{10000, 1, 512, 512, 1},
+	}
+	for _, tt := range tests {
+		if w, h := fit(tt.w, tt.h, tt.size); w != tt.wantW || h != tt.wantH {
+			t.Errorf("fit(%d, %d, %d) = %d, %d, want %d, %d", tt.w, tt.h, tt.size, w, h, tt.wantW, tt.wantH)
+		}
+	}
+}
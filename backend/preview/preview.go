@@ -0,0 +1,148 @@
+// Package preview derives display-sized copies of uploaded files: thumbnails of headshots and a
+// rendering of the first page of PDF documents.
+package preview
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	// decoders for every image type intake accepts
+	_ "image/gif"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	// ThumbnailSize is the longest side of a thumbnail in pixels
+	ThumbnailSize = 512
+	// PreviewWidth is the width PDF first pages are rendered at
+	PreviewWidth = 1000
+
+	ThumbnailMimeType      = "image/jpeg"
+	ThumbnailAlphaMimeType = "image/png"
+	PreviewMimeType        = "image/png"
+
+	thumbnailQuality = 82
+	// larger images are refused rather than decoded, a 50 megapixel bitmap is already 200MB
+	maxSourcePixels = 50_000_000
+)
+
+var (
+	ErrUnsupported = errors.New("unsupported file type for preview")
+	ErrUnavailable = errors.New("pdf renderer not installed")
+)
+
+// Thumbnail decodes a JPEG, PNG, GIF or WebP image, scales it to fit within ThumbnailSize, applies
+// its EXIF orientation and re-encodes it, returning the thumbnail and its MIME type. Scaling comes
+// first so orienting only ever touches the small copy. Re-encoding drops EXIF and every other
+// metadata block, so location and camera details never reach reviewers. Images with transparency
+// become PNG thumbnails that keep it; the rest become JPEG, flattened onto white. The standard
+// library has no WebP encoder, so WebP is never produced.
+func Thumbnail(data []byte) ([]byte, string, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", ErrUnsupported
+	}
+	if config.Width*config.Height > maxSourcePixels {
+		return nil, "", fmt.Errorf("image is %dx%d, too large to thumbnail", config.Width, config.Height)
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("error decoding image: %w", err)
+	}
+	orientation := jpegOrientation(data)
+
+	// fit the upright size, then scale along the stored image's axes
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if orientation >= 5 {
+		width, height = height, width
+	}
+	width, height = fit(width, height, ThumbnailSize)
+	if orientation >= 5 {
+		width, height = height, width
+	}
+
+	var out bytes.Buffer
+	if hasAlpha(src) {
+		dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+		draw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Src, nil)
+		if err := png.Encode(&out, orient(dst, orientation)); err != nil {
+			return nil, "", fmt.Errorf("error encoding thumbnail: %w", err)
+		}
+		return out.Bytes(), ThumbnailAlphaMimeType, nil
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Over, nil)
+	if err := jpeg.Encode(&out, orient(dst, orientation), &jpeg.Options{Quality: thumbnailQuality}); err != nil {
+		return nil, "", fmt.Errorf("error encoding thumbnail: %w", err)
+	}
+	return out.Bytes(), ThumbnailMimeType, nil
+}
+
+// hasAlpha reports whether any pixel of img is not fully opaque
+func hasAlpha(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return !o.Opaque()
+	}
+	return false
+}
+
+// fit scales width x height down so the longest side is at most size, never up
+func fit(width, height, size int) (int, int) {
+	if width <= size && height <= size {
+		return width, height
+	}
+	if width >= height {
+		return size, max(1, height*size/width)
+	}
+	return max(1, width*size/height), size
+}
+
+// PDFFirstPage renders the first page of a PDF to a PNG PreviewWidth pixels wide. Rendering is
+// done by poppler's pdftoppm, or the command in PDF_PREVIEW_COMMAND; ErrUnavailable is returned
+// when it isn't installed.
+func PDFFirstPage(ctx context.Context, data []byte) ([]byte, error) {
+	command := os.Getenv("PDF_PREVIEW_COMMAND")
+	if command == "" {
+		command = "pdftoppm"
+	}
+	path, err := exec.LookPath(command)
+	if err != nil {
+		return nil, ErrUnavailable
+	}
+
+	dir, err := os.MkdirTemp("", "preview-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	input := filepath.Join(dir, "input.pdf")
+	if err := os.WriteFile(input, data, 0o600); err != nil {
+		return nil, err
+	}
+
+	output := filepath.Join(dir, "page")
+	cmd := exec.CommandContext(ctx, path,
+		"-png", "-f", "1", "-l", "1", "-singlefile",
+		"-scale-to-x", fmt.Sprint(PreviewWidth), "-scale-to-y", "-1",
+		input, output)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("error rendering pdf: %v: %s", err, bytes.TrimSpace(out))
+	}
+	return os.ReadFile(output + ".png")
+}
@@ -0,0 +1,87 @@
+// Package ranking turns rated applicants into a ranked table.
+package ranking
+
+import (
+	"math"
+	"sort"
+
+	"backend/models"
+)
+
+// MinGames is the number of comparisons below which an applicant's rating is too uncertain to rank on
+const MinGames = 5
+
+// Entry is one applicant's place in a ranking
+type Entry struct {
+	Applicant *models.Applicant
+	// applicants with equal ratings share the best rank among them ("1, 2, 2, 4")
+	Rank int
+	Tied bool
+	// percentile rank in the ranking: share of applicants rated below, counting ties as half
+	Percentile float64
+	Games      int
+	// share of games won, 0 when none have been played
+	WinRate float64
+	// half-width of the 95% confidence interval on the rating, +Inf with no games played
+	Margin float64
+	// fewer than MinGames comparisons
+	LowConfidence bool
+}
+
+// Rank orders applicants by rating, wins breaking ties in the listing order only, and numbers them from 1
+func Rank(applicants []models.Applicant) []Entry {
+	sorted := make([]*models.Applicant, len(applicants))
+	for i := range applicants {
+		sorted[i] = &applicants[i]
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Elo != sorted[j].Elo {
+			return sorted[i].Elo > sorted[j].Elo
+		}
+		return sorted[i].Wins > sorted[j].Wins
+	})
+
+	n := len(sorted)
+	entries := make([]Entry, n)
+	for start := 0; start < n; {
+		end := start
+		for end < n && sorted[end].Elo == sorted[start].Elo {
+			end++
+		}
+		tied := end - start
+		percentile := 100 * (float64(n-end) + float64(tied)/2) / float64(n)
+		for i := start; i < end; i++ {
+			a := sorted[i]
+			games := a.Wins + a.Losses
+			entries[i] = Entry{
+				Applicant:     a,
+				Rank:          start + 1,
+				Tied:          tied > 1,
+				Percentile:    math.Round(percentile*10) / 10,
+				Games:         games,
+				WinRate:       winRate(a.Wins, games),
+				Margin:        Margin(games),
+				LowConfidence: games < MinGames,
+			}
+		}
+		start = end
+	}
+	return entries
+}
+
+func winRate(wins, games int) float64 {
+	if games == 0 {
+		return 0
+	}
+	return float64(wins) / float64(games)
+}
+
+// Margin approximates the 95% confidence margin of an Elo rating after games comparisons.
+// Each game carries at most p(1-p) = 1/4 of Fisher information about the rating on the
+// logistic scale, so the standard error is at least 400 / (ln 10 * sqrt(games / 4)).
+func Margin(games int) float64 {
+	if games == 0 {
+		return math.Inf(1)
+	}
+	return 1.96 * 400 / (math.Ln10 * math.Sqrt(float64(games)/4))
+}
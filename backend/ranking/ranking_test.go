@@ -0,0 +1,109 @@
+package ranking
+
+import (
+	"math"
+	"testing"
+
+	"backend/models"
+)
+
+func TestRank(t *testing.T) {
+	applicants := []models.Applicant{
+		{FirstName: "d", Elo: 1000, Wins: 0, Losses: 2},
+		{FirstName: "b", Elo: 1100, Wins: 3, Losses: 3},
+		{FirstName: "a", Elo: 1200, Wins: 8, Losses: 2},
+		{FirstName: "c", Elo: 1100, Wins: 4, Losses: 0},
+	}
+	entries := Rank(applicants)
+
+	tests := []struct {
+		name       string
+		rank       int
+		tied       bool
+		percentile float64
+		games      int
+		winRate    float64
+		low        bool
+	}{
+		{"a", 1, false, 87.5, 10, 0.8, false},
+		// equal ratings share a rank, more wins list first
+		{"c", 2, true, 50, 4, 1, true},
+		{"b", 2, true, 50, 6, 0.5, false},
+		{"d", 4, false, 12.5, 2, 0, true},
+	}
+	if len(entries) != len(tests) {
+		t.Fatalf("Rank() returned %d entries, want %d", len(entries), len(tests))
+	}
+	for i, tt := range tests {
+		e := entries[i]
+		t.Run(tt.name, func(t *testing.T) {
+			if e.Applicant.FirstName != tt.name {
+				t.Fatalf("entry %d is %q, want %q", i, e.Applicant.FirstName, tt.name)
+			}
+			if e.Rank != tt.rank || e.Tied != tt.tied {
+				t.Errorf("rank %d tied %t, want %d %t", e.Rank, e.Tied, tt.rank, tt.tied)
+			}
+			if e.Percentile != tt.percentile {
+				t.Errorf("percentile %v, want %v", e.Percentile, tt.percentile)
+			}
+			if e.Games != tt.games || e.WinRate != tt.winRate || e.LowConfidence != tt.low {
+				t.Errorf("games %d win rate %v low %t, want %d %v %t", e.Games, e.WinRate, e.LowConfidence, tt.games, tt.winRate, tt.low)
+			}
+			if e.Margin != Margin(tt.games) {
+				t.Errorf("margin %v, want Margin(%d)", e.Margin, tt.games)
+			}
+		})
+	}
+}
+
+func TestRankEdgeCases(t *testing.T) {
+	if entries := Rank(nil); len(entries) != 0 {
+		t.Errorf("Rank(nil) = %v", entries)
+	}
+
+	all := Rank([]models.Applicant{{Elo: 1000}, {Elo: 1000}, {Elo: 1000}})
+	for _, e := range all {
+		if e.Rank != 1 || !e.Tied || e.Percentile != 50 {
+			t.Errorf("all tied: rank %d tied %t percentile %v, want 1 true 50", e.Rank, e.Tied, e.Percentile)
+		}
+	}
+
+	one := Rank([]models.Applicant{{Elo: 1000}})
+	if one[0].Rank != 1 || one[0].Tied || one[0].Percentile != 50 {
+		t.Errorf("single applicant: %+v", one[0])
+	}
+
+	// percentiles are rounded to one decimal
+	three := Rank([]models.Applicant{{Elo: 3}, {Elo: 2}, {Elo: 1}})
+	if three[0].Percentile != 83.3 || three[1].Percentile != 50 || three[2].Percentile != 16.7 {
+		t.Errorf("percentiles = %v %v %v", three[0].Percentile, three[1].Percentile, three[2].Percentile)
+	}
+}
+
+func TestMargin(t *testing.T) {
+	tests := []struct {
+		games int
+		want  float64
+	}{
+		{0, math.Inf(1)},
+		// 1.96 * 400 / ln 10
+		{4, 340.4869},
+		{16, 170.2434},
+		{100, 68.0974},
+	}
+	for _, tt := range tests {
+		got := Margin(tt.games)
+		if math.IsInf(tt.want, 1) {
+			if !math.IsInf(got, 1) {
+				t.Errorf("Margin(%d) = %v, want +Inf", tt.games, got)
+			}
+			continue
+		}
+		if math.Abs(got-tt.want) > 1e-3 {
+			t.Errorf("Margin(%d) = %v, want %v", tt.games, got, tt.want)
+		}
+	}
+	if Margin(MinGames) <= Margin(MinGames+1) {
+		t.Error("Margin does not shrink as games are played")
+	}
+}
@@ -0,0 +1,122 @@
+package ranking
+
+import (
+	"math"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Order lists the applicants in ratings best first, ids breaking ties so two orders compare deterministically
+func Order(ratings map[primitive.ObjectID]int) []primitive.ObjectID {
+	ids := make([]primitive.ObjectID, 0, len(ratings))
+	for id := range ratings {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if ratings[ids[i]] != ratings[ids[j]] {
+			return ratings[ids[i]] > ratings[ids[j]]
+		}
+		return ids[i].Hex() < ids[j].Hex()
+	})
+	return ids
+}
+
+// KendallTau is Kendall's tau-b between two ratings of the same applicants: 1 when they order every
+// pair alike, -1 when they order every pair the other way. It is 0 when either rates everyone equally.
+func KendallTau(x, y []int) float64 {
+	var concordant, discordant, tiesX, tiesY float64
+	for i := range x {
+		for j := i + 1; j < len(x); j++ {
+			dx, dy := sign(x[i]-x[j]), sign(y[i]-y[j])
+			switch {
+			case dx == 0 && dy == 0:
+			case dx == 0:
+				tiesX++
+			case dy == 0:
+				tiesY++
+			case dx == dy:
+				concordant++
+			default:
+				discordant++
+			}
+		}
+	}
+	denominator := math.Sqrt((concordant + discordant + tiesX) * (concordant + discordant + tiesY))
+	if denominator == 0 {
+		return 0
+	}
+	return (concordant - discordant) / denominator
+}
+
+// Spearman is Spearman's rank correlation between two ratings of the same applicants, ties taking
+// their average rank. It is 0 when either rates everyone equally.
+func Spearman(x, y []int) float64 {
+	rx, ry := averageRanks(x), averageRanks(y)
+	n := float64(len(x))
+	var meanX, meanY float64
+	for i := range rx {
+		meanX += rx[i] / n
+		meanY += ry[i] / n
+	}
+	var covariance, varX, varY float64
+	for i := range rx {
+		covariance += (rx[i] - meanX) * (ry[i] - meanY)
+		varX += (rx[i] - meanX) * (rx[i] - meanX)
+		varY += (ry[i] - meanY) * (ry[i] - meanY)
+	}
+	if varX == 0 || varY == 0 {
+		return 0
+	}
+	return covariance / math.Sqrt(varX*varY)
+}
+
+// TopKChurn is the share of the top k in after who were not in the top k of before
+func TopKChurn(before, after []primitive.ObjectID, k int) float64 {
+	k = min(k, len(before), len(after))
+	if k == 0 {
+		return 0
+	}
+	previous := map[primitive.ObjectID]bool{}
+	for _, id := range before[:k] {
+		previous[id] = true
+	}
+	changed := 0
+	for _, id := range after[:k] {
+		if !previous[id] {
+			changed++
+		}
+	}
+	return float64(changed) / float64(k)
+}
+
+func averageRanks(values []int) []float64 {
+	order := make([]int, len(values))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool { return values[order[i]] > values[order[j]] })
+
+	ranks := make([]float64, len(values))
+	for start := 0; start < len(order); {
+		end := start
+		for end < len(order) && values[order[end]] == values[order[start]] {
+			end++
+		}
+		for _, i := range order[start:end] {
+			ranks[i] = float64(start+end+1) / 2
+		}
+		start = end
+	}
+	return ranks
+}
+
+func sign(v int) int {
+	switch {
+	case v > 0:
+		return 1
+	case v < 0:
+		return -1
+	}
+	return 0
+}
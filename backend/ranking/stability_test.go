@@ -0,0 +1,107 @@
+package ranking
+
+import (
+	"math"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestKendallTau(t *testing.T) {
+	tests := []struct {
+		name string
+		x, y []int
+		want float64
+	}{
+		{"identical", []int{4, 3, 2, 1}, []int{40, 30, 20, 10}, 1},
+		{"reversed", []int{4, 3, 2, 1}, []int{1, 2, 3, 4}, -1},
+		{"one swap", []int{4, 3, 2, 1}, []int{4, 3, 1, 2}, 4.0 / 6},
+		// scipy.stats.kendalltau([12, 2, 1, 12, 2], [1, 4, 7, 1, 0])
+		{"ties on both sides", []int{12, 2, 1, 12, 2}, []int{1, 4, 7, 1, 0}, -0.47140452079103173},
+		{"everyone rated equally", []int{5, 5, 5}, []int{1, 2, 3}, 0},
+		{"single applicant", []int{1}, []int{1}, 0},
+		{"empty", nil, nil, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := KendallTau(tt.x, tt.y); math.Abs(got-tt.want) > 1e-12 {
+				t.Errorf("KendallTau() = %v, want %v", got, tt.want)
+			}
+			if got := KendallTau(tt.y, tt.x); math.Abs(got-tt.want) > 1e-12 {
+				t.Errorf("KendallTau() with arguments swapped = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSpearman(t *testing.T) {
+	tests := []struct {
+		name string
+		x, y []int
+		want float64
+	}{
+		{"identical order", []int{1, 2, 3, 4, 5}, []int{10, 20, 30, 40, 50}, 1},
+		{"reversed", []int{1, 2, 3, 4, 5}, []int{5, 4, 3, 2, 1}, -1},
+		// scipy.stats.spearmanr([1, 2, 3, 4, 5], [5, 6, 7, 8, 7])
+		{"tie", []int{1, 2, 3, 4, 5}, []int{5, 6, 7, 8, 7}, 0.8207826816681233},
+		// 1 - 6 * sum(d^2) / (n (n^2 - 1)) without ties: d = 0, 0, 1, 1
+		{"one swap", []int{4, 3, 2, 1}, []int{4, 3, 1, 2}, 0.8},
+		{"everyone rated equally", []int{7, 7, 7}, []int{1, 2, 3}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Spearman(tt.x, tt.y); math.Abs(got-tt.want) > 1e-12 {
+				t.Errorf("Spearman() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAverageRanks(t *testing.T) {
+	got := averageRanks([]int{10, 30, 20, 30})
+	want := []float64{4, 1.5, 3, 1.5}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("averageRanks() = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestOrderAndTopKChurn(t *testing.T) {
+	ids := make([]primitive.ObjectID, 4)
+	for i := range ids {
+		ids[i] = primitive.NewObjectID()
+	}
+	a, b, c, d := ids[0], ids[1], ids[2], ids[3]
+
+	order := Order(map[primitive.ObjectID]int{a: 1000, b: 1200, c: 1100, d: 1100})
+	// equal ratings fall back to id order, and c was created before d
+	want := []primitive.ObjectID{b, c, d, a}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("Order() = %v, want %v", order, want)
+		}
+	}
+
+	tests := []struct {
+		name          string
+		before, after []primitive.ObjectID
+		k             int
+		want          float64
+	}{
+		{"unchanged", []primitive.ObjectID{a, b, c, d}, []primitive.ObjectID{a, b, c, d}, 2, 0},
+		{"reordered inside top k", []primitive.ObjectID{a, b, c, d}, []primitive.ObjectID{b, a, c, d}, 2, 0},
+		{"one replaced", []primitive.ObjectID{a, b, c, d}, []primitive.ObjectID{a, c, b, d}, 2, 0.5},
+		{"all replaced", []primitive.ObjectID{a, b, c, d}, []primitive.ObjectID{c, d, a, b}, 2, 1},
+		{"k larger than the ranking", []primitive.ObjectID{a, b}, []primitive.ObjectID{b, c}, 10, 0.5},
+		{"k zero", []primitive.ObjectID{a}, []primitive.ObjectID{b}, 0, 0},
+		{"empty", nil, nil, 3, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TopKChurn(tt.before, tt.after, tt.k); got != tt.want {
+				t.Errorf("TopKChurn() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,11 +1,10 @@
 package routes
 
 import (
-	"encoding/json"
-	"net/http"
 	"os"
 
 	"backend/controllers"
+	"backend/middleware"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/cors"
@@ -21,8 +20,9 @@ func SetupRoutes(router chi.Router) {
 			}
 			return url
 		}()},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Content-Type", "Authorization"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Accept", "Content-Type", "Authorization", "Range", "If-None-Match"},
+		ExposedHeaders:   []string{"Content-Length", "Content-Range", "Accept-Ranges", "ETag", "Content-Disposition"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
@@ -31,34 +31,80 @@ func SetupRoutes(router chi.Router) {
 	projectController := controllers.NewProjectController()
 	applicantController := controllers.NewApplicantController()
 	formResponseController := controllers.NewFormResponseController()
+	fileController := controllers.NewFileController()
+	webhookController := controllers.NewWebhookController()
 	// dataController := controllers.NewDataController()
 
 	router.Route("/api", func(r chi.Router) {
-		// Project routes
-		r.Get("/projects", projectController.GetAll)
-		// r.Get("/data", dataController.GetAll) // TODO // when clicking "ADD NEW PROJECT" I want this to display all new projects, NOT NECESSARY FOR NOW. FOCUS ON MAKING ONE WORK
-		r.Post("/projects", projectController.Create)
-
-		// r.Get("/applicants", applicantController.GetAll) // TODO
-		r.Get("/applicants", applicantController.GetById)
+		// signed form webhooks carry their own credentials; everything else needs a signed-in user
+		r.Post("/formResponseListener", formResponseController.HandleFormResponse)
+		r.Post("/formResponseListener/typeform", formResponseController.HandleTypeform)
+		r.Post("/formResponseListener/google-forms", formResponseController.HandleGoogleForms)
+		r.Post("/formResponseListener/multipart", formResponseController.HandleMultipart)
 
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.AuthMiddleware)
 
-		r.Get("/getTwoForComparison", applicantController.GetTwoForComparison)
-		r.Post("/updateElo", applicantController.UpdateElo)
-		r.Get("/rankings", applicantController.GetRankings)
-		// Additional routes from server.go
-		r.Get("/background-check", aiBackgroundCheck())
-		r.Post("/formResponseListener", formResponseController.HandleFormResponse)
-	})
-}
+			// signed file URLs only open for the reviewer they were issued to
+			r.Get("/files/{fileId}", fileController.Serve)
 
-func aiBackgroundCheck() http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		name := r.URL.Query().Get("name")
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(map[string]string{"message": "Hello, " + name})
-	}
-}
+			// Project routes
+			r.Get("/projects", projectController.GetAll)
+			// r.Get("/data", dataController.GetAll) // TODO // when clicking "ADD NEW PROJECT" I want this to display all new projects, NOT NECESSARY FOR NOW. FOCUS ON MAKING ONE WORK
+			r.Post("/projects", projectController.Create)
+			r.Patch("/projects/{id}", projectController.Update)
+			r.Get("/projects/{id}/events", projectController.Events)
+			r.Post("/projects/{id}/webhook-secret", projectController.RotateWebhookSecret)
+			r.Post("/projects/{id}/stages/cut", applicantController.Cut)
+			r.Post("/projects/{id}/reminders", applicantController.SendReminders)
+			r.Get("/projects/{id}/email-templates", projectController.EmailTemplates)
+			r.Put("/projects/{id}/email-templates/{template}", projectController.SetEmailTemplate)
+			r.Delete("/projects/{id}/email-templates/{template}", projectController.ResetEmailTemplate)
+			r.Get("/projects/{id}/rankings", applicantController.GetRankings)
+			r.Get("/projects/{id}/rankings/export", applicantController.ExportRankings)
+			r.Get("/projects/{id}/convergence", applicantController.Convergence)
+			r.Get("/projects/{id}/snapshots", applicantController.ListSnapshots)
+			r.Post("/projects/{id}/snapshots", applicantController.CreateSnapshot)
+			r.Get("/projects/{id}/snapshots/diff", applicantController.DiffSnapshots)
+			r.Get("/projects/{id}/comparisons", applicantController.ListComparisons)
+			r.Post("/projects/{id}/live-session", applicantController.StartLiveSession)
+			r.Get("/projects/{id}/live-session", applicantController.GetLiveSession)
+			r.Delete("/projects/{id}/live-session", applicantController.EndLiveSession)
+			r.Get("/projects/{id}/live-session/ws", applicantController.JoinLiveSession)
+			r.Route("/projects/{id}/webhooks", func(r chi.Router) {
+				r.Get("/", webhookController.List)
+				r.Post("/", webhookController.Create)
+				r.Patch("/{webhookId}", webhookController.Update)
+				r.Delete("/{webhookId}", webhookController.Delete)
+				r.Post("/{webhookId}/test", webhookController.Ping)
+				r.Get("/{webhookId}/deliveries", webhookController.Deliveries)
+				r.Post("/{webhookId}/deliveries/{deliveryId}/retry", webhookController.Redeliver)
+			})
+			r.Route("/projects/{id}/applicants", func(r chi.Router) {
+				r.Get("/", applicantController.GetAll)
+				r.Post("/", applicantController.Create)
+				r.Get("/search", applicantController.Search)
+				r.Get("/{applicantId}", applicantController.GetById)
+				r.Patch("/{applicantId}", applicantController.Update)
+				r.Delete("/{applicantId}", applicantController.Delete)
+				r.Post("/{applicantId}/status", applicantController.SetStatus)
+				r.Get("/{applicantId}/background-check", applicantController.BackgroundCheck)
+				r.Post("/{applicantId}/background-check", applicantController.RunBackgroundCheck)
+			})
 
+			// kept for older clients, prefer /projects/{id}/applicants/{applicantId}
+			r.Get("/applicants", applicantController.GetById)
+			r.Post("/applicants/{id}/merge", applicantController.Merge)
+			r.Get("/applicants/{id}/history", applicantController.History)
 
+			r.Get("/getTwoForComparison", applicantController.GetTwoForComparison)
+			r.Post("/updateElo", applicantController.UpdateElo)
+			r.Get("/rankings", applicantController.GetRankings)
+			// Additional routes from server.go
+			r.Get("/background-check", applicantController.BackgroundCheck)
+			r.Get("/quarantine", formResponseController.ListQuarantine)
+			r.Post("/quarantine/{id}/assign", formResponseController.AssignQuarantined)
+			r.Delete("/quarantine/{id}", formResponseController.DeleteQuarantined)
+		})
+	})
+}
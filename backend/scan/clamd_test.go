@@ -0,0 +1,155 @@
+package scan
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseReply(t *testing.T) {
+	tests := []struct {
+		reply     string
+		status    string
+		signature string
+		wantErr   bool
+	}{
+		{"stream: OK", StatusClean, "", false},
+		{"OK", StatusClean, "", false},
+		{"stream: Eicar-Test-Signature FOUND", StatusInfected, "Eicar-Test-Signature", false},
+		{"stream: Win.Trojan.Agent-123 FOUND", StatusInfected, "Win.Trojan.Agent-123", false},
+		{"INSTREAM size limit exceeded. ERROR", "", "", true},
+		{"stream: Can't allocate memory ERROR", "", "", true},
+		{"", "", "", true},
+		{"UNKNOWN COMMAND", "", "", true},
+		{"stream: OKAY", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.reply, func(t *testing.T) {
+			result, err := parseReply(tt.reply)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseReply(%q) error = %v, wantErr %v", tt.reply, err, tt.wantErr)
+			}
+			if result.Status != tt.status || result.Signature != tt.signature {
+				t.Errorf("parseReply(%q) = %+v, want status %q signature %q", tt.reply, result, tt.status, tt.signature)
+			}
+		})
+	}
+}
+
+func TestReadReply(t *testing.T) {
+	tests := []struct {
+		name    string
+		sent    string
+		want    string
+		wantErr bool
+	}{
+		{"null terminated", "stream: OK\x00", "stream: OK", false},
+		{"trailing newline", "PONG\n\x00", "PONG", false},
+		{"closed without terminator", "stream: OK", "stream: OK", false},
+		{"closed without reply", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, server := net.Pipe()
+			go func() {
+				server.Write([]byte(tt.sent))
+				server.Close()
+			}()
+			defer client.Close()
+
+			got, err := readReply(client)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("readReply() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("readReply() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// fakeClamd answers one INSTREAM command with reply(streamed bytes)
+func fakeClamd(t *testing.T, reply func(data []byte) string) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		if command, err := r.ReadString(0); err != nil || command != "zINSTREAM\x00" {
+			conn.Write([]byte("UNKNOWN COMMAND\x00"))
+			return
+		}
+		var data []byte
+		for {
+			var size uint32
+			if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+				return
+			}
+			if size == 0 {
+				break
+			}
+			chunk := make([]byte, size)
+			if _, err := io.ReadFull(r, chunk); err != nil {
+				return
+			}
+			data = append(data, chunk...)
+		}
+		conn.Write([]byte(reply(data) + "\x00"))
+	}()
+	return listener.Addr().String()
+}
+
+func TestClamdScan(t *testing.T) {
+	eicar := `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+	reply := func(data []byte) string {
+		if strings.Contains(string(data), "EICAR-STANDARD-ANTIVIRUS-TEST-FILE") {
+			return "stream: Eicar-Test-Signature FOUND"
+		}
+		return "stream: OK"
+	}
+
+	tests := []struct {
+		name   string
+		body   string
+		status string
+	}{
+		{"clean", "plain resume text", StatusClean},
+		{"empty", "", StatusClean},
+		{"spans several chunks", strings.Repeat("a", 3*clamdChunkSize+17), StatusClean},
+		{"infected", eicar, StatusInfected},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var received int
+			addr := fakeClamd(t, func(data []byte) string {
+				received = len(data)
+				return reply(data)
+			})
+			clamd := NewClamd("tcp", addr, 5*time.Second)
+
+			result, err := clamd.Scan(context.Background(), strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("Scan() error = %v", err)
+			}
+			if result.Status != tt.status {
+				t.Errorf("Scan() status = %q, want %q", result.Status, tt.status)
+			}
+			if received != len(tt.body) {
+				t.Errorf("clamd received %d bytes, want %d", received, len(tt.body))
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+// Package scan checks uploaded files for malware before anything parses or serves them.
+package scan
+
+import (
+	"context"
+	"io"
+	"log"
+	"os"
+	"time"
+)
+
+// Scan statuses recorded on models.FileInfo
+const (
+	StatusClean    = "clean"
+	StatusInfected = "infected"
+	// not scanned yet: no scanner is configured or the scanner could not be reached
+	StatusPending = "pending"
+)
+
+// Result is the verdict on one file. Signature names what was found in an infected file.
+type Result struct {
+	Status    string
+	Signature string
+}
+
+// Scanner inspects a file's bytes. An error means no verdict was reached, not that the file is bad.
+type Scanner interface {
+	Name() string
+	Scan(ctx context.Context, r io.Reader) (Result, error)
+}
+
+var defaultScanner Scanner
+
+// Setup configures the scanner from the environment: CLAMD_SOCKET for clamd on a local unix socket,
+// or CLAMD_ADDRESS for clamd over TCP. Without either, files are stored as pending.
+func Setup() {
+	timeout, err := time.ParseDuration(os.Getenv("CLAMD_TIMEOUT"))
+	if err != nil || timeout <= 0 {
+		timeout = DefaultClamdTimeout
+	}
+
+	switch {
+	case os.Getenv("CLAMD_SOCKET") != "":
+		defaultScanner = NewClamd("unix", os.Getenv("CLAMD_SOCKET"), timeout)
+	case os.Getenv("CLAMD_ADDRESS") != "":
+		defaultScanner = NewClamd("tcp", os.Getenv("CLAMD_ADDRESS"), timeout)
+	default:
+		log.Println("Warning: no malware scanner configured, uploads will be stored unscanned")
+		return
+	}
+	log.Println("Scanning uploads with", defaultScanner.Name())
+}
+
+// Default returns the configured scanner, or nil when there is none
+func Default() Scanner {
+	return defaultScanner
+}
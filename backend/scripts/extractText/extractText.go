@@ -0,0 +1,130 @@
+//go:build ignore
+
+package main
+
+// extracts resume and cover letter text for applicants that arrived before text extraction existed
+// and makes sure the search index is in place
+// go run scripts/extractText/extractText.go [-project <id>] [-all]
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"io"
+	"log"
+	"os"
+
+	"backend/db"
+	"backend/extract"
+	"backend/models"
+	"backend/storage"
+
+	"github.com/joho/godotenv"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func main() {
+	project := flag.String("project", "", "only process applicants of this project")
+	all := flag.Bool("all", false, "re-extract applicants that already have text")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("Warning: .env failed to load")
+	}
+	uri := os.Getenv("MONGODB_URI")
+	if uri == "" {
+		log.Fatal("MONGODB_URI not set in .env file")
+	}
+	db.ConnectMongoDB(uri)
+	db.EnsureIndexes()
+
+	if err := storage.Setup(db.GetDatabase()); err != nil {
+		log.Fatal(err)
+	}
+
+	filter := bson.M{}
+	if *project != "" {
+		projectID, err := primitive.ObjectIDFromHex(*project)
+		if err != nil {
+			log.Fatal("Invalid project id: ", err)
+		}
+		filter["project_id"] = projectID
+	}
+	if !*all {
+		filter["resumeText"] = bson.M{"$exists": false}
+		filter["coverLetterText"] = bson.M{"$exists": false}
+	}
+
+	ctx := context.Background()
+	collection := db.GetCollection("applicants")
+
+	cursor, err := collection.Find(ctx, filter)
+	if err != nil {
+		log.Fatal("Failed to fetch applicants: ", err)
+	}
+	defer cursor.Close(ctx)
+
+	updated, failed := 0, 0
+	for cursor.Next(ctx) {
+		var applicant models.Applicant
+		if err := cursor.Decode(&applicant); err != nil {
+			log.Println("Error decoding applicant:", err)
+			failed++
+			continue
+		}
+
+		set := bson.M{}
+		for field, file := range map[string]*models.FileInfo{
+			"resumeText":      applicant.Resume,
+			"coverLetterText": applicant.CoverLetter,
+		} {
+			if file == nil {
+				continue
+			}
+			text, err := fileText(ctx, file)
+			if err != nil {
+				log.Printf("Error extracting %s for %s: %v", field, applicant.ID.Hex(), err)
+				failed++
+				continue
+			}
+			if text != "" {
+				set[field] = text
+			}
+		}
+		if len(set) == 0 {
+			continue
+		}
+
+		if _, err := collection.UpdateOne(ctx, bson.M{"_id": applicant.ID}, bson.M{"$set": set}); err != nil {
+			log.Printf("Error updating %s: %v", applicant.ID.Hex(), err)
+			failed++
+			continue
+		}
+		updated++
+	}
+
+	log.Printf("Extracted text for %d applicants, %d failures", updated, failed)
+}
+
+func fileText(ctx context.Context, file *models.FileInfo) (string, error) {
+	store, err := storage.Get(file.Storage)
+	if err != nil {
+		return "", err
+	}
+	blob, err := store.Open(ctx, file.FileID)
+	if err != nil {
+		return "", err
+	}
+	defer blob.Close()
+
+	data, err := io.ReadAll(blob)
+	if err != nil {
+		return "", err
+	}
+	text, err := extract.Text(file.MimeType, data)
+	if errors.Is(err, extract.ErrUnsupported) {
+		return "", nil
+	}
+	return text, err
+}
@@ -0,0 +1,171 @@
+//go:build ignore
+
+package main
+
+// generates headshot thumbnails and PDF first-page previews for applicants uploaded before
+// previews existed. PDF previews need poppler's pdftoppm (or PDF_PREVIEW_COMMAND) on the PATH.
+// go run scripts/generatePreviews/generatePreviews.go [-project <id>]
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"backend/db"
+	"backend/models"
+	"backend/preview"
+	"backend/storage"
+
+	"github.com/joho/godotenv"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func main() {
+	project := flag.String("project", "", "only process applicants of this project")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("Warning: .env failed to load")
+	}
+	uri := os.Getenv("MONGODB_URI")
+	if uri == "" {
+		log.Fatal("MONGODB_URI not set in .env file")
+	}
+	db.ConnectMongoDB(uri)
+
+	if err := storage.Setup(db.GetDatabase()); err != nil {
+		log.Fatal(err)
+	}
+
+	filter := bson.M{}
+	if *project != "" {
+		projectID, err := primitive.ObjectIDFromHex(*project)
+		if err != nil {
+			log.Fatal("Invalid project id: ", err)
+		}
+		filter["project_id"] = projectID
+	}
+
+	ctx := context.Background()
+	collection := db.GetCollection("applicants")
+
+	cursor, err := collection.Find(ctx, filter)
+	if err != nil {
+		log.Fatal("Failed to fetch applicants: ", err)
+	}
+	defer cursor.Close(ctx)
+
+	generated, failed := 0, 0
+	for cursor.Next(ctx) {
+		var applicant models.Applicant
+		if err := cursor.Decode(&applicant); err != nil {
+			log.Println("Error decoding applicant:", err)
+			failed++
+			continue
+		}
+
+		for field, file := range map[string]*models.FileInfo{
+			"resume":      applicant.Resume,
+			"coverLetter": applicant.CoverLetter,
+			"image":       applicant.Image,
+		} {
+			if file == nil || file.Thumbnail != nil || file.Preview != nil {
+				continue
+			}
+
+			key, derived, err := derive(ctx, file)
+			if err != nil {
+				if !errors.Is(err, preview.ErrUnsupported) {
+					log.Printf("Error generating %s preview for %s: %v", field, applicant.ID.Hex(), err)
+					failed++
+				}
+				continue
+			}
+			if derived == nil {
+				continue
+			}
+
+			// only link the copy if the document still points at the file it was made from
+			result, err := collection.UpdateOne(ctx,
+				bson.M{"_id": applicant.ID, field + ".fileId": file.FileID},
+				bson.M{"$set": bson.M{field + "." + key: derived}})
+			if err != nil || result.MatchedCount == 0 {
+				log.Printf("Error updating %s of applicant %s, removing preview: %v", field, applicant.ID.Hex(), err)
+				if store, err := storage.Get(derived.Storage); err == nil {
+					store.Delete(ctx, derived.FileID)
+				}
+				failed++
+				continue
+			}
+			generated++
+		}
+	}
+
+	log.Printf("Generated %d previews, %d failures", generated, failed)
+}
+
+// derive renders and stores the thumbnail or preview of file, returning the field it belongs in
+func derive(ctx context.Context, file *models.FileInfo) (string, *models.FileInfo, error) {
+	var key, suffix, mimeType string
+	switch {
+	case strings.HasPrefix(file.MimeType, "image/"):
+		key = "thumbnail"
+	case file.MimeType == "application/pdf":
+		key, suffix, mimeType = "preview", "preview.png", preview.PreviewMimeType
+	default:
+		return "", nil, nil
+	}
+
+	store, err := storage.Get(file.Storage)
+	if err != nil {
+		return "", nil, err
+	}
+	blob, err := store.Open(ctx, file.FileID)
+	if err != nil {
+		return "", nil, err
+	}
+	data, err := io.ReadAll(blob)
+	blob.Close()
+	if err != nil {
+		return "", nil, err
+	}
+
+	var rendered []byte
+	if key == "thumbnail" {
+		rendered, mimeType, err = preview.Thumbnail(data)
+		suffix = "thumb.jpg"
+		if mimeType == preview.ThumbnailAlphaMimeType {
+			suffix = "thumb.png"
+		}
+	} else {
+		rendered, err = preview.PDFFirstPage(ctx, data)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	base := strings.TrimSuffix(file.FileName, filepath.Ext(file.FileName))
+	uniqueName := fmt.Sprintf("%d_%s.%s", time.Now().Unix(), base, suffix)
+	fileID, err := store.Put(ctx, uniqueName, bytes.NewReader(rendered))
+	if err != nil {
+		return "", nil, err
+	}
+
+	return key, &models.FileInfo{
+		FileID:     fileID,
+		FileName:   base + "." + suffix,
+		MimeType:   mimeType,
+		UniqueName: uniqueName,
+		UploadedAt: time.Now(),
+		Storage:    store.Name(),
+	}, nil
+}
@@ -0,0 +1,148 @@
+//go:build ignore
+
+package main
+
+// copies applicant files from one storage backend to another and rewrites FileInfo.FileID
+// the target backend must be configured in .env (STORAGE_LOCAL_DIR or the S3_* variables)
+// go run scripts/migrateStorage/migrateStorage.go -from gridfs -to s3 [-delete] [-dry-run]
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"backend/db"
+	"backend/models"
+	"backend/storage"
+
+	"github.com/joho/godotenv"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var fileFields = []string{"resume", "coverLetter", "image", "resume.preview", "coverLetter.preview", "image.thumbnail"}
+
+func main() {
+	from := flag.String("from", storage.GridFS, "backend to move files out of")
+	to := flag.String("to", "", "backend to move files into")
+	deleteSource := flag.Bool("delete", false, "delete each file from the source backend once it has been copied")
+	dryRun := flag.Bool("dry-run", false, "list the files that would be moved without moving them")
+	flag.Parse()
+
+	if *to == "" || *to == *from {
+		log.Fatal("Please provide a -to backend different from -from")
+	}
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("Warning: .env failed to load")
+	}
+	uri := os.Getenv("MONGODB_URI")
+	if uri == "" {
+		log.Fatal("MONGODB_URI not set in .env file")
+	}
+	db.ConnectMongoDB(uri)
+
+	if err := storage.Setup(db.GetDatabase()); err != nil {
+		log.Fatal(err)
+	}
+	source, err := storage.Get(*from)
+	if err != nil {
+		log.Fatal(err)
+	}
+	target, err := storage.Get(*to)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx := context.Background()
+	collection := db.GetCollection("applicants")
+
+	cursor, err := collection.Find(ctx, bson.M{})
+	if err != nil {
+		log.Fatal("Failed to fetch applicants: ", err)
+	}
+	defer cursor.Close(ctx)
+
+	moved, failed := 0, 0
+	for cursor.Next(ctx) {
+		var applicant models.Applicant
+		if err := cursor.Decode(&applicant); err != nil {
+			log.Println("Error decoding applicant:", err)
+			failed++
+			continue
+		}
+
+		files := map[string]*models.FileInfo{
+			"resume":      applicant.Resume,
+			"coverLetter": applicant.CoverLetter,
+			"image":       applicant.Image,
+		}
+		for _, field := range []string{"resume", "coverLetter", "image"} {
+			if file := files[field]; file != nil {
+				files[field+".preview"] = file.Preview
+				files[field+".thumbnail"] = file.Thumbnail
+			}
+		}
+		for _, field := range fileFields {
+			file := files[field]
+			if file == nil || storageName(file.Storage) != *from {
+				continue
+			}
+
+			if *dryRun {
+				log.Printf("Would move %s %s of applicant %s", field, file.FileID, applicant.ID.Hex())
+				continue
+			}
+
+			newID, err := copyBlob(ctx, source, target, file)
+			if err != nil {
+				log.Printf("Error moving %s %s of applicant %s: %v", field, file.FileID, applicant.ID.Hex(), err)
+				failed++
+				continue
+			}
+
+			// only rewrite the document if it still points at the file we copied
+			filter := bson.M{"_id": applicant.ID, field + ".fileId": file.FileID}
+			update := bson.M{"$set": bson.M{field + ".fileId": newID, field + ".storage": target.Name()}}
+			result, err := collection.UpdateOne(ctx, filter, update)
+			if err != nil || result.MatchedCount == 0 {
+				log.Printf("Error updating %s of applicant %s, removing copy: %v", field, applicant.ID.Hex(), err)
+				target.Delete(ctx, newID)
+				failed++
+				continue
+			}
+
+			if *deleteSource {
+				if err := source.Delete(ctx, file.FileID); err != nil {
+					log.Printf("Error deleting %s from %s: %v", file.FileID, source.Name(), err)
+				}
+			}
+
+			log.Printf("Moved %s of applicant %s: %s/%s -> %s/%s", field, applicant.ID.Hex(), source.Name(), file.FileID, target.Name(), newID)
+			moved++
+		}
+	}
+
+	log.Printf("Moved %d files, %d failures", moved, failed)
+}
+
+func storageName(name string) string {
+	if name == "" {
+		return storage.GridFS
+	}
+	return name
+}
+
+func copyBlob(ctx context.Context, source, target storage.BlobStore, file *models.FileInfo) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	blob, err := source.Open(ctx, file.FileID)
+	if err != nil {
+		return "", err
+	}
+	defer blob.Close()
+
+	return target.Put(ctx, file.UniqueName, blob)
+}
@@ -0,0 +1,117 @@
+//go:build ignore
+
+package main
+
+// scans applicant files that are still pending, e.g. uploads from before scanning was set up or
+// from while clamd was down. -all rescans every file, worth doing after a signature update.
+// go run scripts/scanFiles/scanFiles.go [-all]
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"backend/db"
+	"backend/models"
+	"backend/scan"
+	"backend/storage"
+
+	"github.com/joho/godotenv"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func main() {
+	all := flag.Bool("all", false, "rescan files that already have a verdict")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("Warning: .env failed to load")
+	}
+	uri := os.Getenv("MONGODB_URI")
+	if uri == "" {
+		log.Fatal("MONGODB_URI not set in .env file")
+	}
+	db.ConnectMongoDB(uri)
+
+	if err := storage.Setup(db.GetDatabase()); err != nil {
+		log.Fatal(err)
+	}
+	scan.Setup()
+	scanner := scan.Default()
+	if scanner == nil {
+		log.Fatal("Set CLAMD_SOCKET or CLAMD_ADDRESS to scan files")
+	}
+
+	ctx := context.Background()
+	collection := db.GetCollection("applicants")
+
+	cursor, err := collection.Find(ctx, bson.M{})
+	if err != nil {
+		log.Fatal("Failed to fetch applicants: ", err)
+	}
+	defer cursor.Close(ctx)
+
+	scanned, infected, failed := 0, 0, 0
+	for cursor.Next(ctx) {
+		var applicant models.Applicant
+		if err := cursor.Decode(&applicant); err != nil {
+			log.Println("Error decoding applicant:", err)
+			failed++
+			continue
+		}
+
+		for field, file := range map[string]*models.FileInfo{
+			"resume":      applicant.Resume,
+			"coverLetter": applicant.CoverLetter,
+			"image":       applicant.Image,
+		} {
+			if file == nil || (!*all && file.ScanStatus != "" && file.ScanStatus != scan.StatusPending) {
+				continue
+			}
+
+			result, err := scanFile(ctx, scanner, file)
+			if err != nil {
+				log.Printf("Error scanning %s of applicant %s: %v", field, applicant.ID.Hex(), err)
+				failed++
+				continue
+			}
+
+			_, err = collection.UpdateOne(ctx,
+				bson.M{"_id": applicant.ID, field + ".fileId": file.FileID},
+				bson.M{"$set": bson.M{
+					field + ".scanStatus":    result.Status,
+					field + ".scanSignature": result.Signature,
+					field + ".scannedAt":     time.Now(),
+				}})
+			if err != nil {
+				log.Printf("Error updating %s of applicant %s: %v", field, applicant.ID.Hex(), err)
+				failed++
+				continue
+			}
+
+			scanned++
+			if result.Status == scan.StatusInfected {
+				infected++
+				log.Printf("Malware found in %s of applicant %s: %s", field, applicant.ID.Hex(), result.Signature)
+			}
+		}
+	}
+
+	log.Printf("Scanned %d files, %d infected, %d failures", scanned, infected, failed)
+}
+
+func scanFile(ctx context.Context, scanner scan.Scanner, file *models.FileInfo) (scan.Result, error) {
+	store, err := storage.Get(file.Storage)
+	if err != nil {
+		return scan.Result{}, err
+	}
+	blob, err := store.Open(ctx, file.FileID)
+	if err != nil {
+		return scan.Result{}, err
+	}
+	defer blob.Close()
+
+	return scanner.Scan(ctx, blob)
+}
@@ -4,7 +4,7 @@ package main
 
 // uploads airtable csv for applicants to mongo DB for testing
 // cd backend
-// go run scripts/uploadTestDataScript/uploadApplicantsFromCsv.go
+// FORM_WEBHOOK_SECRET=<project webhook secret> go run scripts/uploadTestDataScript/uploadApplicantsFromCsv.go
 
 import (
 	"bytes"
@@ -20,11 +20,17 @@ import (
 	"time"
 
 	"backend/models"
+	"backend/webhook"
 )
 
 const formId = "67c66785b4db64228e988097"
 
 func UploadApplicants() {
+	secret := os.Getenv("FORM_WEBHOOK_SECRET")
+	if secret == "" {
+		fmt.Println("FORM_WEBHOOK_SECRET not set, the server will reject unsigned submissions")
+	}
+
 	// Open CSV file
 	file, err := os.Open("scripts/uploadTestDataScript/Fall 23 Rush App Responses.csv")
 	if err != nil {
@@ -148,8 +154,16 @@ func UploadApplicants() {
 			continue
 		}
 
-		// Send POST request to endpoint
-		resp, err := http.Post("http://localhost:8080/api/formResponseListener", "application/json", bytes.NewBuffer(jsonData))
+		// Send signed POST request to endpoint
+		req, err := http.NewRequest(http.MethodPost, "http://localhost:8080/api/formResponseListener", bytes.NewBuffer(jsonData))
+		if err != nil {
+			fmt.Printf("Error building request: %v\n", err)
+			continue
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(webhook.SignatureHeader, webhook.Sign(secret, time.Now(), jsonData))
+
+		resp, err := http.DefaultClient.Do(req)
 		if err != nil {
 			fmt.Printf("Error sending request: %v\n", err)
 			continue
@@ -0,0 +1,162 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+)
+
+// GridFSStore keeps files in the database's default fs bucket
+type GridFSStore struct {
+	database *mongo.Database
+}
+
+func NewGridFSStore(database *mongo.Database) *GridFSStore {
+	return &GridFSStore{database: database}
+}
+
+func (s *GridFSStore) Name() string {
+	return GridFS
+}
+
+// a Bucket shares its read buffer between uploads, so every operation gets its own
+func (s *GridFSStore) bucket() (*gridfs.Bucket, error) {
+	return gridfs.NewBucket(s.database)
+}
+
+func (s *GridFSStore) Put(ctx context.Context, name string, r io.Reader) (string, error) {
+	bucket, err := s.bucket()
+	if err != nil {
+		return "", err
+	}
+	id, err := bucket.UploadFromStream(name, r)
+	if err != nil {
+		return "", err
+	}
+	return id.Hex(), nil
+}
+
+func (s *GridFSStore) Open(ctx context.Context, id string) (Blob, error) {
+	fileID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	bucket, err := s.bucket()
+	if err != nil {
+		return nil, err
+	}
+
+	stream, err := bucket.OpenDownloadStream(fileID)
+	if err != nil {
+		if errors.Is(err, gridfs.ErrFileNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &gridfsBlob{
+		bucket: bucket,
+		id:     fileID,
+		size:   stream.GetFile().Length,
+		stream: stream,
+	}, nil
+}
+
+func (s *GridFSStore) Delete(ctx context.Context, id string) error {
+	fileID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrNotFound
+	}
+	bucket, err := s.bucket()
+	if err != nil {
+		return err
+	}
+	if err := bucket.DeleteContext(ctx, fileID); err != nil {
+		if errors.Is(err, gridfs.ErrFileNotFound) {
+			return ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// gridfsBlob adapts a GridFS download stream, which can only skip forwards, to the
+// io.ReadSeeker http.ServeContent needs. Seeking reopens the stream at the new position.
+type gridfsBlob struct {
+	bucket *gridfs.Bucket
+	id     primitive.ObjectID
+	size   int64
+	pos    int64
+	stream *gridfs.DownloadStream
+}
+
+func (b *gridfsBlob) Size() int64 {
+	return b.size
+}
+
+func (b *gridfsBlob) Read(p []byte) (int, error) {
+	if b.pos >= b.size {
+		return 0, io.EOF
+	}
+	if b.stream == nil {
+		stream, err := b.bucket.OpenDownloadStream(b.id)
+		if err != nil {
+			return 0, err
+		}
+		if b.pos > 0 {
+			if _, err := stream.Skip(b.pos); err != nil {
+				stream.Close()
+				return 0, err
+			}
+		}
+		b.stream = stream
+	}
+
+	n, err := b.stream.Read(p)
+	b.pos += int64(n)
+	return n, err
+}
+
+func (b *gridfsBlob) Seek(offset int64, whence int) (int64, error) {
+	target, err := seekTarget(b.pos, b.size, offset, whence)
+	if err != nil {
+		return 0, err
+	}
+
+	// ServeContent seeks to the end to learn the size before reading anything, so moving is
+	// kept lazy and the stream is only reopened by the next Read
+	if target != b.pos && b.stream != nil {
+		b.stream.Close()
+		b.stream = nil
+	}
+	b.pos = target
+	return target, nil
+}
+
+func (b *gridfsBlob) Close() error {
+	if b.stream == nil {
+		return nil
+	}
+	return b.stream.Close()
+}
+
+func seekTarget(pos, size, offset int64, whence int) (int64, error) {
+	var target int64
+	switch whence {
+	case io.SeekStart:
+		target = offset
+	case io.SeekCurrent:
+		target = pos + offset
+	case io.SeekEnd:
+		target = size + offset
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if target < 0 {
+		return 0, errors.New("negative position")
+	}
+	return target, nil
+}
@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalStoreRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewLocalStore(filepath.Join(t.TempDir(), "files"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content := "%PDF-1.4 resume contents"
+	id, err := store.Put(ctx, "resume.pdf", strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if len(id) != 32 {
+		t.Fatalf("Put() id = %q, want 32 hex characters", id)
+	}
+
+	blob, err := store.Open(ctx, id)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if blob.Size() != int64(len(content)) {
+		t.Errorf("Size() = %d, want %d", blob.Size(), len(content))
+	}
+	data, err := io.ReadAll(blob)
+	if err != nil || string(data) != content {
+		t.Fatalf("read back %q, %v", data, err)
+	}
+	if _, err := blob.Seek(9, io.SeekStart); err != nil {
+		t.Fatalf("Seek() error = %v", err)
+	}
+	rest, _ := io.ReadAll(blob)
+	if string(rest) != "resume contents" {
+		t.Errorf("read after Seek = %q", rest)
+	}
+	blob.Close()
+
+	if err := store.Delete(ctx, id); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := store.Open(ctx, id); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Open() after Delete error = %v, want ErrNotFound", err)
+	}
+	if err := store.Delete(ctx, id); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second Delete() error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestLocalStoreFailedPutLeavesNothing(t *testing.T) {
+	root := t.TempDir()
+	store, err := NewLocalStore(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tooLarge := errors.New("too large")
+	if _, err := store.Put(context.Background(), "big.pdf", LimitReader(strings.NewReader("0123456789"), 4, tooLarge)); !errors.Is(err, tooLarge) {
+		t.Fatalf("Put() error = %v, want the limit error", err)
+	}
+	err = filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			t.Errorf("partial upload left behind: %s", path)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLocalStoreRejectsForeignIDs(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"", "../../etc/passwd", "not-hex-not-hex-not-hex-not-hex!", strings.Repeat("a", 31), strings.Repeat("a", 33)} {
+		if _, err := store.Open(context.Background(), id); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Open(%q) error = %v, want ErrNotFound", id, err)
+		}
+		if err := store.Delete(context.Background(), id); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Delete(%q) error = %v, want ErrNotFound", id, err)
+		}
+	}
+}
@@ -0,0 +1,303 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// S3Config points at any S3-compatible object store (AWS S3, MinIO, R2, ...)
+type S3Config struct {
+	// Endpoint is the base URL, e.g. https://s3.us-west-1.amazonaws.com or http://localhost:9000
+	Endpoint        string
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	// PathStyle addresses objects as endpoint/bucket/key instead of bucket.endpoint/key (MinIO needs this)
+	PathStyle bool
+}
+
+func S3ConfigFromEnv() S3Config {
+	region := os.Getenv("S3_REGION")
+	if region == "" {
+		region = "us-east-1"
+	}
+	pathStyle, _ := strconv.ParseBool(os.Getenv("S3_USE_PATH_STYLE"))
+	return S3Config{
+		Endpoint:        strings.TrimRight(os.Getenv("S3_ENDPOINT"), "/"),
+		Region:          region,
+		Bucket:          os.Getenv("S3_BUCKET"),
+		AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+		PathStyle:       pathStyle,
+	}
+}
+
+// S3Store talks to the S3 REST API directly, signing requests with AWS Signature Version 4
+type S3Store struct {
+	config S3Config
+	client *http.Client
+}
+
+func NewS3Store(config S3Config) *S3Store {
+	if config.Endpoint == "" {
+		config.Endpoint = "https://s3." + config.Region + ".amazonaws.com"
+	}
+	return &S3Store{
+		config: config,
+		client: &http.Client{Timeout: 5 * time.Minute},
+	}
+}
+
+func (s *S3Store) Name() string {
+	return S3
+}
+
+const objectPrefix = "files/"
+
+func (s *S3Store) objectURL(id string) (*url.URL, error) {
+	base, err := url.Parse(s.config.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid S3 endpoint: %v", err)
+	}
+	if s.config.PathStyle {
+		base.Path = "/" + s.config.Bucket + "/" + objectPrefix + id
+	} else {
+		base.Host = s.config.Bucket + "." + base.Host
+		base.Path = "/" + objectPrefix + id
+	}
+	return base, nil
+}
+
+func validObjectID(id string) bool {
+	if len(id) != 32 {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
+
+// Put spools the upload to a temp file first: S3 needs the length and the payload hash up front
+func (s *S3Store) Put(ctx context.Context, name string, r io.Reader) (string, error) {
+	tmp, err := os.CreateTemp("", "s3-upload-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hash), r)
+	if err != nil {
+		return "", err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	id := hex.EncodeToString(buf)
+
+	target, err := s.objectURL(id)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, target.String(), tmp)
+	if err != nil {
+		return "", err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/octet-stream")
+	s.sign(req, hex.EncodeToString(hash.Sum(nil)), time.Now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error uploading to S3: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", s3Error("uploading", resp)
+	}
+	return id, nil
+}
+
+func (s *S3Store) Open(ctx context.Context, id string) (Blob, error) {
+	if !validObjectID(id) {
+		return nil, ErrNotFound
+	}
+	resp, err := s.do(ctx, http.MethodHead, id, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, s3Error("opening", resp)
+	}
+	return &s3Blob{store: s, ctx: ctx, id: id, size: resp.ContentLength}, nil
+}
+
+func (s *S3Store) Delete(ctx context.Context, id string) error {
+	if !validObjectID(id) {
+		return ErrNotFound
+	}
+	resp, err := s.do(ctx, http.MethodDelete, id, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return s3Error("deleting", resp)
+	}
+	return nil
+}
+
+// sha256 of an empty body, used for every request without a payload
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+func (s *S3Store) do(ctx context.Context, method, id string, header http.Header) (*http.Response, error) {
+	target, err := s.objectURL(id)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	s.sign(req, emptyPayloadHash, time.Now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error calling S3: %v", err)
+	}
+	return resp, nil
+}
+
+// sign adds an AWS Signature Version 4 Authorization header covering host and the x-amz headers
+func (s *S3Store) sign(req *http.Request, payloadHash string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalHeaders := "host:" + req.URL.Host + "\n" +
+		"x-amz-content-sha256:" + payloadHash + "\n" +
+		"x-amz-date:" + amzDate + "\n"
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL),
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.config.Region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.config.SecretAccessKey), date)
+	key = hmacSHA256(key, s.config.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.config.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// canonicalQuery sorts the query parameters and encodes them the way SigV4 expects: every key
+// has an "=" and spaces are %20 rather than +
+func canonicalQuery(u *url.URL) string {
+	return strings.ReplaceAll(u.Query().Encode(), "+", "%20")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func s3Error(action string, resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("error %s S3 object: %s: %s", action, resp.Status, strings.TrimSpace(string(body)))
+}
+
+// s3Blob reads an object with ranged GETs, starting a new request whenever it is seeked
+type s3Blob struct {
+	store *S3Store
+	ctx   context.Context
+	id    string
+	size  int64
+	pos   int64
+	body  io.ReadCloser
+}
+
+func (b *s3Blob) Size() int64 {
+	return b.size
+}
+
+func (b *s3Blob) Read(p []byte) (int, error) {
+	if b.pos >= b.size {
+		return 0, io.EOF
+	}
+	if b.body == nil {
+		header := http.Header{}
+		header.Set("Range", fmt.Sprintf("bytes=%d-", b.pos))
+		resp, err := b.store.do(b.ctx, http.MethodGet, b.id, header)
+		if err != nil {
+			return 0, err
+		}
+		if resp.StatusCode != http.StatusPartialContent && resp.StatusCode != http.StatusOK {
+			defer resp.Body.Close()
+			return 0, s3Error("reading", resp)
+		}
+		b.body = resp.Body
+	}
+
+	n, err := b.body.Read(p)
+	b.pos += int64(n)
+	return n, err
+}
+
+func (b *s3Blob) Seek(offset int64, whence int) (int64, error) {
+	target, err := seekTarget(b.pos, b.size, offset, whence)
+	if err != nil {
+		return 0, err
+	}
+	if target != b.pos && b.body != nil {
+		b.body.Close()
+		b.body = nil
+	}
+	b.pos = target
+	return target, nil
+}
+
+func (b *s3Blob) Close() error {
+	if b.body == nil {
+		return nil
+	}
+	return b.body.Close()
+}
@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Names of the supported backends, as stored on models.FileInfo.Storage and set in STORAGE_BACKEND
+const (
+	GridFS = "gridfs"
+	Local  = "local"
+	S3     = "s3"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+// BlobStore stores applicant files. Ids are opaque strings chosen by the store.
+type BlobStore interface {
+	// Name is the backend name recorded on files written by this store
+	Name() string
+	// Put stores everything read from r and returns the new blob's id. If r returns an
+	// error the partial blob is discarded and the error is returned unchanged.
+	Put(ctx context.Context, name string, r io.Reader) (string, error)
+	// Open returns a seekable reader over the blob, suitable for http.ServeContent
+	Open(ctx context.Context, id string) (Blob, error)
+	Delete(ctx context.Context, id string) error
+}
+
+type Blob interface {
+	io.ReadSeekCloser
+	Size() int64
+}
+
+var (
+	stores       = map[string]BlobStore{}
+	defaultStore BlobStore
+)
+
+// Setup builds every backend that is configured in the environment and selects
+// STORAGE_BACKEND (gridfs when unset) as the store new files are written to.
+// GridFS is always available so files uploaded before a migration can still be read.
+func Setup(database *mongo.Database) error {
+	stores[GridFS] = NewGridFSStore(database)
+
+	if dir := os.Getenv("STORAGE_LOCAL_DIR"); dir != "" {
+		local, err := NewLocalStore(dir)
+		if err != nil {
+			return err
+		}
+		stores[Local] = local
+	}
+
+	if os.Getenv("S3_BUCKET") != "" {
+		stores[S3] = NewS3Store(S3ConfigFromEnv())
+	}
+
+	name := os.Getenv("STORAGE_BACKEND")
+	if name == "" {
+		name = GridFS
+	}
+	store, ok := stores[name]
+	if !ok {
+		return fmt.Errorf("storage backend %q is not configured", name)
+	}
+	defaultStore = store
+	return nil
+}
+
+// Default is the store new uploads are written to
+func Default() BlobStore {
+	return defaultStore
+}
+
+// Get returns the configured store with the given backend name. Files written before
+// backends were recorded on FileInfo have an empty name and live in GridFS.
+func Get(name string) (BlobStore, error) {
+	if name == "" {
+		name = GridFS
+	}
+	store, ok := stores[name]
+	if !ok {
+		return nil, fmt.Errorf("storage backend %q is not configured", name)
+	}
+	return store, nil
+}
+
+type limitedReader struct {
+	r         io.Reader
+	remaining int64
+	err       error
+}
+
+// LimitReader reads from r but fails with err once more than n bytes have been read,
+// so a BlobStore aborts the upload instead of silently truncating it
+func LimitReader(r io.Reader, n int64, err error) io.Reader {
+	return &limitedReader{r: r, remaining: n, err: err}
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.remaining < 0 {
+		return 0, l.err
+	}
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return n, l.err
+	}
+	return n, err
+}
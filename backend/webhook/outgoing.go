@@ -0,0 +1,128 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Headers on outgoing webhooks, alongside SignatureHeader
+const (
+	EventHeader    = "X-Swiperank-Event"
+	DeliveryHeader = "X-Swiperank-Delivery"
+)
+
+// Retry schedule for outgoing webhooks: the first retry after BackoffBase, doubling up to BackoffMax,
+// giving up after MaxAttempts
+const (
+	BackoffBase = 30 * time.Second
+	BackoffMax  = 6 * time.Hour
+	MaxAttempts = 10
+)
+
+// Backoff is how long to wait before retrying after the given number of failed attempts,
+// with ±20% jitter so failed deliveries don't retry in lockstep
+func Backoff(attempts int) time.Duration {
+	delay := BackoffMax
+	if attempts < 20 {
+		delay = min(BackoffBase<<max(attempts-1, 0), BackoffMax)
+	}
+	jitter := 0.8 + 0.4*rand.Float64()
+	return time.Duration(float64(delay) * jitter)
+}
+
+// ErrPrivateAddress is returned for webhook URLs that point into the server's own network
+var ErrPrivateAddress = errors.New("webhook URL is not a public address")
+
+// publicIP reports whether ip may be sent webhooks: not loopback, link-local, private or unspecified
+func publicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsPrivate() || ip.IsUnspecified() || ip.IsMulticast())
+}
+
+// dialPublic refuses connections to addresses that aren't public. It runs on the address actually
+// being dialled, after DNS, so a name that resolves or rebinds to an internal address is caught too.
+func dialPublic(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !publicIP(ip) {
+		return ErrPrivateAddress
+	}
+	return nil
+}
+
+// client only connects to public addresses, redirects included, and ignores proxy settings so
+// the check applies to the endpoint itself
+var client = &http.Client{
+	Timeout: 10 * time.Second,
+	Transport: &http.Transport{
+		DialContext:         (&net.Dialer{Timeout: 5 * time.Second, Control: dialPublic}).DialContext,
+		TLSHandshakeTimeout: 5 * time.Second,
+		MaxIdleConns:        10,
+		IdleConnTimeout:     90 * time.Second,
+	},
+}
+
+// ValidateURL checks that raw is an http or https URL of a public host. Hosts given as an IP
+// address or as localhost are checked here; names are checked again each time Send connects.
+func ValidateURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return errors.New("not an http or https URL")
+	}
+	if u.User != nil {
+		return errors.New("URL contains credentials")
+	}
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return ErrPrivateAddress
+	}
+	if ip := net.ParseIP(host); ip != nil && !publicIP(ip) {
+		return ErrPrivateAddress
+	}
+	return nil
+}
+
+// Send POSTs body to url, signed with secret in SignatureHeader when there is one. Any 2xx
+// response is a delivery; the status code is returned whenever the endpoint answered. URLs that
+// don't pass ValidateURL, or whose host resolves to an address that isn't public, fail with an
+// error wrapping ErrPrivateAddress.
+func Send(ctx context.Context, url, secret, eventType, deliveryID string, body []byte) (int, error) {
+	if err := ValidateURL(url); err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Swiperank-Webhooks/1.0")
+	req.Header.Set(EventHeader, eventType)
+	req.Header.Set(DeliveryHeader, deliveryID)
+	if secret != "" {
+		req.Header.Set(SignatureHeader, Sign(secret, time.Now(), body))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint answered %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
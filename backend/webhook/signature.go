@@ -0,0 +1,176 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Header carrying the signature of an incoming form webhook.
+// Format: "t=<unix seconds>,v1=<hex hmac-sha256 of "<t>.<body>">"
+const SignatureHeader = "X-Swiperank-Signature"
+
+// Header Typeform sets on its own webhooks: "sha256=<base64 hmac-sha256 of body>"
+const TypeformSignatureHeader = "Typeform-Signature"
+
+// How far a signed timestamp may drift from the server clock before the request is treated as stale
+const DefaultTolerance = 5 * time.Minute
+
+var (
+	ErrMissingSignature   = errors.New("missing signature header")
+	ErrMalformedSignature = errors.New("malformed signature header")
+	ErrInvalidSignature   = errors.New("signature does not match")
+	ErrStaleTimestamp     = errors.New("signature timestamp outside replay window")
+	ErrReplayed           = errors.New("delivery already received")
+)
+
+// GenerateSecret returns a random hex-encoded secret for signing webhooks
+func GenerateSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("error generating webhook secret: %v", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// Sign builds the signature header value for body at the given time
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	t := strconv.FormatInt(timestamp.Unix(), 10)
+	return fmt.Sprintf("t=%s,v1=%s", t, computeMAC(secret, t, body))
+}
+
+// Verify checks header against body and rejects signatures older or newer than tolerance
+func Verify(secret, header string, body []byte, now time.Time, tolerance time.Duration) error {
+	_, err := VerifyReplayKey(secret, header, body, now, tolerance)
+	return err
+}
+
+// VerifyReplayKey is Verify, also returning the key the delivery should be remembered by to catch
+// replays. Callers must dedupe on this key, never the raw header, which can be rewritten without
+// invalidating the signature.
+func VerifyReplayKey(secret, header string, body []byte, now time.Time, tolerance time.Duration) (string, error) {
+	v, err := NewStreamVerifier(secret, header, now, tolerance)
+	if err != nil {
+		return "", err
+	}
+	v.Write(body)
+	if err := v.Verify(); err != nil {
+		return "", err
+	}
+	return v.ReplayKey(), nil
+}
+
+// StreamVerifier checks a signature over a body that is too large to buffer. The header and
+// timestamp are checked up front, the body is written through it as it is consumed, and Verify
+// is called once the whole body has been read.
+type StreamVerifier struct {
+	mac        hash.Hash
+	timestamp  int64
+	signatures []string
+	matched    string
+}
+
+func NewStreamVerifier(secret, header string, now time.Time, tolerance time.Duration) (*StreamVerifier, error) {
+	if header == "" {
+		return nil, ErrMissingSignature
+	}
+
+	t, signatures, err := parseHeader(header)
+	if err != nil {
+		return nil, err
+	}
+
+	unix, err := strconv.ParseInt(t, 10, 64)
+	if err != nil {
+		return nil, ErrMalformedSignature
+	}
+	drift := now.Sub(time.Unix(unix, 0))
+	if drift > tolerance || drift < -tolerance {
+		return nil, ErrStaleTimestamp
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t))
+	mac.Write([]byte("."))
+	return &StreamVerifier{mac: mac, timestamp: unix, signatures: signatures}, nil
+}
+
+func (v *StreamVerifier) Write(p []byte) (int, error) {
+	return v.mac.Write(p)
+}
+
+func (v *StreamVerifier) Verify() error {
+	expected := hex.EncodeToString(v.mac.Sum(nil))
+	for _, sig := range v.signatures {
+		if hmac.Equal([]byte(expected), []byte(sig)) {
+			v.matched = expected
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+// ReplayKey identifies the verified request by its timestamp and the signature that matched, so
+// the same delivery is recognised however its header was spelled. It is empty until Verify succeeds.
+func (v *StreamVerifier) ReplayKey() string {
+	if v.matched == "" {
+		return ""
+	}
+	return strconv.FormatInt(v.timestamp, 10) + "." + v.matched
+}
+
+// VerifyTypeform checks a Typeform-Signature header, which signs only the body
+func VerifyTypeform(secret, header string, body []byte) error {
+	if header == "" {
+		return ErrMissingSignature
+	}
+	sig, ok := strings.CutPrefix(header, "sha256=")
+	if !ok {
+		return ErrMalformedSignature
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	expected := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(sig)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func parseHeader(header string) (string, []string, error) {
+	var t string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return "", nil, ErrMalformedSignature
+		}
+		switch key {
+		case "t":
+			t = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	if t == "" || len(signatures) == 0 {
+		return "", nil, ErrMalformedSignature
+	}
+	return t, signatures, nil
+}
+
+func computeMAC(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
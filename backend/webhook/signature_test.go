@@ -0,0 +1,169 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReplayKeyRewrittenHeaders(t *testing.T) {
+	const secret = "s3cr3t"
+	body := []byte(`{"formId":"f1"}`)
+	now := time.Unix(1700000000, 0)
+	header := Sign(secret, now, body)
+	ts, sig, _ := strings.Cut(header, ",")
+
+	want, err := VerifyReplayKey(secret, header, body, now, DefaultTolerance)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every variant still carries the one valid signature, so it must map to the same delivery
+	variants := []struct {
+		name   string
+		header string
+	}{
+		{"whitespace", ts + ", " + sig},
+		{"reordered", sig + "," + ts},
+		{"extra v1", header + ",v1=deadbeef"},
+		{"extra v1 first", ts + ",v1=deadbeef," + sig},
+	}
+	for _, v := range variants {
+		t.Run(v.name, func(t *testing.T) {
+			key, err := VerifyReplayKey(secret, v.header, body, now, DefaultTolerance)
+			if err != nil {
+				t.Fatalf("VerifyReplayKey() error = %v", err)
+			}
+			if key != want {
+				t.Fatalf("VerifyReplayKey() = %q, want %q", key, want)
+			}
+		})
+	}
+
+	// a later delivery of the same body is signed at a different time, so it is not a replay
+	later, _ := VerifyReplayKey(secret, Sign(secret, now.Add(time.Second), body), body, now, DefaultTolerance)
+	if later == want {
+		t.Fatal("a delivery signed at another time has the same replay key")
+	}
+}
+
+func TestReplayKeyBeforeVerify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	v, err := NewStreamVerifier("s", Sign("s", now, nil), now, DefaultTolerance)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key := v.ReplayKey(); key != "" {
+		t.Fatalf("ReplayKey() before Verify = %q, want empty", key)
+	}
+}
+
+func TestSignVerify(t *testing.T) {
+	const secret = "s3cr3t"
+	body := []byte(`{"formId":"f1","answers":[]}`)
+	signedAt := time.Unix(1700000000, 0)
+	header := Sign(secret, signedAt, body)
+
+	tests := []struct {
+		name   string
+		secret string
+		header string
+		body   []byte
+		now    time.Time
+		want   error
+	}{
+		{"valid", secret, header, body, signedAt, nil},
+		{"valid at edge of tolerance", secret, header, body, signedAt.Add(DefaultTolerance), nil},
+		{"clock behind sender", secret, header, body, signedAt.Add(-time.Minute), nil},
+		{"second signature matches", secret, "t=1700000000,v1=00," + strings.Split(header, ",")[1], body, signedAt, nil},
+		{"missing header", secret, "", body, signedAt, ErrMissingSignature},
+		{"no timestamp", secret, "v1=abc", body, signedAt, ErrMalformedSignature},
+		{"no signature", secret, "t=1700000000", body, signedAt, ErrMalformedSignature},
+		{"not key=value", secret, "t=1700000000,garbage", body, signedAt, ErrMalformedSignature},
+		{"non-numeric timestamp", secret, "t=yesterday,v1=abc", body, signedAt, ErrMalformedSignature},
+		{"stale", secret, header, body, signedAt.Add(DefaultTolerance + time.Second), ErrStaleTimestamp},
+		{"from the future", secret, header, body, signedAt.Add(-DefaultTolerance - time.Second), ErrStaleTimestamp},
+		{"tampered body", secret, header, []byte(`{"formId":"f2","answers":[]}`), signedAt, ErrInvalidSignature},
+		{"wrong secret", "other", header, body, signedAt, ErrInvalidSignature},
+		{"timestamp swapped", secret, "t=1700000001," + strings.Split(header, ",")[1], body, signedAt, ErrInvalidSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Verify(tt.secret, tt.header, tt.body, tt.now, DefaultTolerance); err != tt.want {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSignFormat(t *testing.T) {
+	got := Sign("key", time.Unix(1700000000, 0), []byte("body"))
+	// HMAC-SHA256("key", "1700000000.body")
+	mac := hmac.New(sha256.New, []byte("key"))
+	mac.Write([]byte("1700000000.body"))
+	want := "t=1700000000,v1=" + hex.EncodeToString(mac.Sum(nil))
+	if got != want {
+		t.Fatalf("Sign() = %q, want %q", got, want)
+	}
+}
+
+func TestStreamVerifierMatchesVerify(t *testing.T) {
+	const secret = "s3cr3t"
+	now := time.Unix(1700000000, 0)
+	body := []byte(strings.Repeat("chunk-", 1000))
+	header := Sign(secret, now, body)
+
+	v, err := NewStreamVerifier(secret, header, now, DefaultTolerance)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < len(body); i += 700 {
+		v.Write(body[i:min(i+700, len(body))])
+	}
+	if err := v.Verify(); err != nil {
+		t.Fatalf("Verify() over chunks error = %v", err)
+	}
+}
+
+func TestVerifyTypeform(t *testing.T) {
+	const secret = "typeform-secret"
+	body := []byte(`{"event_id":"e1"}`)
+	// base64(HMAC-SHA256(secret, body)), as Typeform documents it
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	valid := "sha256=" + base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+	tests := []struct {
+		name   string
+		header string
+		body   []byte
+		want   error
+	}{
+		{"valid", valid, body, nil},
+		{"missing", "", body, ErrMissingSignature},
+		{"no prefix", strings.TrimPrefix(valid, "sha256="), body, ErrMalformedSignature},
+		{"tampered body", valid, []byte(`{"event_id":"e2"}`), ErrInvalidSignature},
+		{"hex instead of base64", "sha256=" + computeMAC(secret, "", body), body, ErrInvalidSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := VerifyTypeform(secret, tt.header, tt.body); err != tt.want {
+				t.Fatalf("VerifyTypeform() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	a, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := GenerateSecret()
+	if len(a) != 64 || a == b {
+		t.Fatalf("GenerateSecret() = %q, %q; want distinct 64-char hex", a, b)
+	}
+}
@@ -1,6 +1,7 @@
 "use client";
 
 import { useParams } from "next/navigation";
+import { useAuth } from "@clerk/nextjs";
 import { useState, useEffect } from "react";
 import { Card, CardHeader, CardTitle, CardContent } from "@/components/ui/card";
 import { Separator } from "@/components/ui/separator";
@@ -28,6 +29,7 @@ interface Applicant {
 export default function ApplicantPage() {
   const params = useParams();
   const applicantId = params?.id as string;
+  const { getToken } = useAuth();
 
   const [applicant, setApplicant] = useState<Applicant | null>(null);
   const [loading, setLoading] = useState(true);
@@ -38,7 +40,8 @@ export default function ApplicantPage() {
       try {
         console.log("Fetching applicant:", applicantId);
         const response = await fetch(
-          `http://localhost:8080/api/applicants?id=${applicantId}`
+          `http://localhost:8080/api/applicants?id=${applicantId}`,
+          { headers: { Authorization: `Bearer ${await getToken()}` } }
         );
         
         if (!response.ok) {
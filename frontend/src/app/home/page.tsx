@@ -3,6 +3,7 @@ import { Button } from "@/components/ui/button";
 import { Progress } from "@/components/ui/progress";
 import Link from "next/link";
 import { Plus } from "lucide-react";
+import { auth } from "@clerk/nextjs/server";
 
 interface Project {
   id: string;
@@ -14,8 +15,10 @@ interface Project {
 
 async function getProjects(): Promise<Project[]> {
   try {
+    const { getToken } = await auth();
     const res = await fetch("http://localhost:8080/api/projects", {
       cache: "no-store",
+      headers: { Authorization: `Bearer ${await getToken()}` },
     });
 
     if (!res.ok) {
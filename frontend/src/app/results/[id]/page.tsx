@@ -1,6 +1,7 @@
 "use client";
 
 import { useParams } from "next/navigation";
+import { useAuth } from "@clerk/nextjs";
 import { useState, useEffect } from "react";
 import { Card, CardHeader, CardTitle, CardContent } from "@/components/ui/card";
 import { Crown, Medal } from "lucide-react";
@@ -8,18 +9,36 @@ import Link from "next/link";
 
 interface ApplicantRanking {
   id: string;
-  first_name: string;
-  last_name: string;
+  firstName: string;
+  lastName: string;
   elo: number;
   wins: number;
   losses: number;
+  status?: string;
+  rank?: number;
+  tied?: boolean;
+  percentile?: number;
+  games: number;
+  winRate: number;
+  confidence: { low: number; high: number; margin: number } | null;
+  lowConfidence: boolean;
 }
 
+const rankLabel = (applicant: ApplicantRanking) =>
+  `${applicant.tied ? "T" : "#"}${applicant.rank}`;
+
+const ratingLabel = (applicant: ApplicantRanking) =>
+  applicant.confidence
+    ? `Elo: ${applicant.elo} ± ${applicant.confidence.margin}`
+    : `Elo: ${applicant.elo}`;
+
 export default function ResultsPage() {
   const params = useParams();
   const projectId = params?.id as string;
+  const { getToken } = useAuth();
 
   const [rankings, setRankings] = useState<ApplicantRanking[]>([]);
+  const [inactive, setInactive] = useState<ApplicantRanking[]>([]);
   const [loading, setLoading] = useState(true);
   const [error, setError] = useState<string | null>(null);
 
@@ -27,13 +46,15 @@ export default function ResultsPage() {
     const fetchRankings = async () => {
       try {
         const response = await fetch(
-          `http://localhost:8080/api/rankings?project_id=${projectId}`
+          `http://localhost:8080/api/rankings?project_id=${projectId}`,
+          { headers: { Authorization: `Bearer ${await getToken()}` } }
         );
         if (!response.ok) {
           throw new Error("Failed to fetch rankings");
         }
         const data = await response.json();
-        setRankings(data);
+        setRankings(data.rankings);
+        setInactive(data.inactive);
       } catch (err: any) {
         setError(err.message);
       } finally {
@@ -42,7 +63,18 @@ export default function ResultsPage() {
     };
 
     fetchRankings();
-  }, [projectId]);
+
+    // refetch whenever the backend reports the ranking may have moved; EventSource can't set
+    // headers, so it authenticates with Clerk's session cookie
+    const events = new EventSource(
+      `http://localhost:8080/api/projects/${projectId}/events`,
+      { withCredentials: true }
+    );
+    for (const type of ["ranking-changed", "applicant-added", "project-status"]) {
+      events.addEventListener(type, fetchRankings);
+    }
+    return () => events.close();
+  }, [projectId, getToken]);
 
   if (loading) return <div className="text-center">Loading rankings...</div>;
   if (error) return <div className="text-center text-red-500">{error}</div>;
@@ -63,11 +95,11 @@ export default function ResultsPage() {
               <CardHeader>
                 <Medal className="h-8 w-8 text-gray-500 mx-auto" />
                 <CardTitle className="text-center">
-                  {topThree[1].first_name} {topThree[1].last_name}
+                  {topThree[1].firstName} {topThree[1].lastName}
                 </CardTitle>
               </CardHeader>
               <CardContent className="text-center">
-                <p className="text-gray-600">Elo: {topThree[1].elo}</p>
+                <p className="text-gray-600">{ratingLabel(topThree[1])}</p>
                 <p className="text-sm">
                   W: {topThree[1].wins} - L: {topThree[1].losses}
                 </p>
@@ -86,11 +118,11 @@ export default function ResultsPage() {
                 <CardHeader>
                   <Medal className="h-8 w-8 text-yellow-500 mx-auto" />
                   <CardTitle className="text-center">
-                    {topThree[0].first_name} {topThree[0].last_name}
+                    {topThree[0].firstName} {topThree[0].lastName}
                   </CardTitle>
                 </CardHeader>
                 <CardContent className="text-center">
-                  <p className="text-gray-600">Elo: {topThree[0].elo}</p>
+                  <p className="text-gray-600">{ratingLabel(topThree[0])}</p>
                   <p className="text-sm">
                     W: {topThree[0].wins} - L: {topThree[0].losses}
                   </p>
@@ -108,11 +140,11 @@ export default function ResultsPage() {
               <CardHeader>
                 <Medal className="h-8 w-8 text-orange-500 mx-auto" />
                 <CardTitle className="text-center">
-                  {topThree[2].first_name} {topThree[2].last_name}
+                  {topThree[2].firstName} {topThree[2].lastName}
                 </CardTitle>
               </CardHeader>
               <CardContent className="text-center">
-                <p className="text-gray-600">Elo: {topThree[2].elo}</p>
+                <p className="text-gray-600">{ratingLabel(topThree[2])}</p>
                 <p className="text-sm">
                   W: {topThree[2].wins} - L: {topThree[2].losses}
                 </p>
@@ -131,10 +163,10 @@ export default function ResultsPage() {
               <CardHeader>
                 <CardTitle className="flex justify-between items-center">
                   <span>
-                    #{index + 4} - {applicant.first_name} {applicant.last_name}
+                    {rankLabel(applicant)} - {applicant.firstName} {applicant.lastName}
                   </span>
                   <span className="text-sm text-gray-500">
-                    Elo: {applicant.elo}
+                    {ratingLabel(applicant)}
                   </span>
                 </CardTitle>
               </CardHeader>
@@ -142,12 +174,38 @@ export default function ResultsPage() {
                 <div className="flex justify-between text-sm text-gray-600">
                   <span>Wins: {applicant.wins}</span>
                   <span>Losses: {applicant.losses}</span>
+                  <span>Win rate: {Math.round(applicant.winRate * 100)}%</span>
+                  <span>Top {Math.max(1, Math.round(100 - (applicant.percentile ?? 0)))}%</span>
                 </div>
+                {applicant.lowConfidence && (
+                  <p className="mt-2 text-xs text-amber-600">
+                    Only {applicant.games} comparisons so far, rank not yet reliable
+                  </p>
+                )}
               </CardContent>
             </Card>
           </Link>
         ))}
       </div>
+
+      {inactive.length > 0 && (
+        <div className="mt-12">
+          <h2 className="text-xl font-semibold mb-4">No longer in consideration</h2>
+          <div className="space-y-2">
+            {inactive.map((applicant) => (
+              <div
+                key={applicant.id}
+                className="flex justify-between text-sm text-gray-600 border rounded-lg px-4 py-2"
+              >
+                <span>
+                  {applicant.firstName} {applicant.lastName}
+                </span>
+                <span className="capitalize">{applicant.status}</span>
+              </div>
+            ))}
+          </div>
+        </div>
+      )}
     </div>
   );
 }
\ No newline at end of file
@@ -1,16 +1,21 @@
 "use client";
 import { useParams, useRouter } from "next/navigation";
+import { useAuth } from "@clerk/nextjs";
 import React, { useState, useEffect } from "react";
 import { Card, CardHeader, CardTitle, CardContent } from "@/components/ui/card";
 import { Separator } from "@/components/ui/separator";
 
 interface FileInfo {
-  fileID: string;
+  fileId: string;
   fileName: string;
-  fileType: string;
-  data?: string;
+  mimeType: string;
+  url?: string;
+  thumbnail?: FileInfo;
+  preview?: FileInfo;
 }
 
+const apiUrl = process.env.NEXT_PUBLIC_API_URL || "http://localhost:8080";
+
 interface Applicant {
   id: string;
   name: string;
@@ -26,6 +31,7 @@ interface Applicant {
 
 const CandidatesPage = () => {
   const router = useRouter();
+  const { getToken } = useAuth();
   const params = useParams();
   const projectId = params?.id as string;
 
@@ -36,9 +42,10 @@ const CandidatesPage = () => {
   const fetchApplicants = async () => {
     try {
       console.log("Starting fetch...");
-      const apiUrl = process.env.NEXT_PUBLIC_API_URL || "http://localhost:8080";
       console.log(apiUrl);
-      const response = await fetch(`${apiUrl}/api/getTwoForComparison`);
+      const response = await fetch(`${apiUrl}/api/getTwoForComparison?project_id=${projectId}`, {
+        headers: { Authorization: `Bearer ${await getToken()}` },
+      });
 
       console.log("Content-Type:", response.headers.get("content-type"));
       if (response.status === 409) {
@@ -79,6 +86,7 @@ const CandidatesPage = () => {
         method: "POST",
         headers: {
           "Content-Type": "application/json",
+          Authorization: `Bearer ${await getToken()}`,
         },
         body: JSON.stringify(payload),
       });
@@ -100,39 +108,14 @@ const CandidatesPage = () => {
     fileInfo: FileInfo | null,
     preview: boolean = false
   ) => {
-    if (!fileInfo?.data) return;
+    if (!fileInfo?.url) return;
 
     if (preview) {
-      try {
-        // Create data URL directly for PDF preview
-        const dataUrl = `data:application/pdf;base64,${fileInfo.data}`;
-
-        // Open PDF in new window/tab
-        const newWindow = window.open("", "_blank");
-        if (newWindow) {
-          newWindow.document.write(`
-            <html>
-              <head>
-                <title>${fileInfo.fileName}</title>
-              </head>
-              <body style="margin:0;padding:0;">
-                <embed 
-                  width="100%" 
-                  height="100%" 
-                  src="${dataUrl}" 
-                  type="application/pdf"
-                />
-              </body>
-            </html>
-          `);
-        }
-      } catch (error) {
-        console.error("Error processing PDF:", error);
-        alert("Error opening PDF. Please try downloading instead.");
-      }
+      // The browser renders the PDF itself and fetches pages with range requests
+      window.open(`${apiUrl}${fileInfo.url}`, "_blank");
     } else {
       const linkElement = document.createElement("a");
-      linkElement.href = `data:${fileInfo.fileType};base64,${fileInfo.data}`;
+      linkElement.href = `${apiUrl}${fileInfo.url}&download=1`;
       linkElement.download = fileInfo.fileName;
       document.body.appendChild(linkElement);
       linkElement.click();
@@ -189,7 +172,7 @@ const CandidatesPage = () => {
                     {applicant.image && (
                       <div>
                         <img
-                          src={`data:${applicant.image.fileType};base64,${applicant.image.data}`}
+                          src={`${apiUrl}${(applicant.image.thumbnail ?? applicant.image).url}`}
                           alt={applicant.name}
                           className="w-full h-48 object-cover rounded-lg"
                         />